@@ -0,0 +1,249 @@
+// Package backtest replays historical candles and trades through a strategy, simulating its orders with the paper
+// trader, and reports the resulting equity curve, trade log and summary statistics.
+package backtest
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+// Strategy reacts to market data by placing and cancelling orders through the trader. The trader is a paper trader
+// with simulated balances, so strategies written against private.Trader run unchanged in backtests and live.
+type Strategy interface {
+	OnCandle(trader private.Trader, candle public.Candle) error
+}
+
+// TradeStrategy is implemented by strategies that want individual trades when replaying trade history; strategies
+// without it receive each trade as a candle whose prices all equal the trade price.
+type TradeStrategy interface {
+	OnTrade(trader private.Trader, trade Trade) error
+}
+
+// Trade is one market trade of the replayed symbol
+type Trade struct {
+	Time   time.Time
+	Price  float64
+	Amount float64
+	Side   string
+}
+
+// Config describes the simulated account and market of a backtest
+type Config struct {
+	Symbol string
+	// BaseCurrency and QuoteCurrency default to the symbol split before its quote currency, e.g. BTC and USD for btcusd
+	BaseCurrency  string
+	QuoteCurrency string
+	// InitialBalances are the starting balances by currency, e.g. {"USD": 10000}
+	InitialBalances map[string]float64
+	// MakerFee and TakerFee are fee rates, e.g. 0.002 for 0.2%
+	MakerFee float64
+	TakerFee float64
+	// Slippage worsens taker fills by the given fraction of the price
+	Slippage float64
+	// PeriodsPerYear annualizes the Sharpe ratio; by default it is derived from the spacing of the replayed data
+	PeriodsPerYear float64
+}
+
+// quoteCurrencies are matched, longest first, against the end of a symbol to split it into base and quote currency
+var quoteCurrencies = []string{"gusd", "usdt", "usdc", "usd", "eur", "gbp", "sgd", "dai", "btc", "eth", "bch"}
+
+func splitSymbol(symbol string) (string, string, error) {
+	lower := strings.ToLower(symbol)
+	for _, quote := range quoteCurrencies {
+		if strings.HasSuffix(lower, quote) && len(lower) > len(quote) {
+			return strings.ToUpper(strings.TrimSuffix(lower, quote)), strings.ToUpper(quote), nil
+		}
+	}
+	return "", "", errors.New("backtest: cannot determine the currencies of symbol " + symbol + ", set BaseCurrency and QuoteCurrency")
+}
+
+// replayMarket serves the current replayed price as a book of unlimited size on both sides
+type replayMarket struct {
+	base  string
+	quote string
+	price float64
+	now   time.Time
+}
+
+func (m *replayMarket) Book(symbol string) (*paper.Book, error) {
+	level := []paper.Level{{Price: m.price, Amount: math.Inf(1)}}
+	return &paper.Book{Bids: level, Asks: level}, nil
+}
+
+func (m *replayMarket) Currencies(symbol string) (string, string, error) {
+	return m.base, m.quote, nil
+}
+
+type backtest struct {
+	config Config
+	market *replayMarket
+	trader *paper.Trader
+	equity []EquityPoint
+	first  float64
+}
+
+func newBacktest(config Config) (*backtest, error) {
+	if config.Symbol == "" {
+		return nil, errors.New("backtest: no symbol configured")
+	}
+	if config.BaseCurrency == "" || config.QuoteCurrency == "" {
+		base, quote, err := splitSymbol(config.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		config.BaseCurrency, config.QuoteCurrency = base, quote
+	}
+	config.BaseCurrency, config.QuoteCurrency = strings.ToUpper(config.BaseCurrency), strings.ToUpper(config.QuoteCurrency)
+	market := &replayMarket{base: config.BaseCurrency, quote: config.QuoteCurrency}
+	trader := paper.NewTrader(market, config.InitialBalances,
+		paper.WithFees(config.MakerFee, config.TakerFee),
+		paper.WithSlippage(config.Slippage),
+		paper.WithClock(func() time.Time { return market.now }))
+	return &backtest{config: config, market: market, trader: trader}, nil
+}
+
+// moveTo sets the market price, filling resting orders the new price reaches
+func (b *backtest) moveTo(at time.Time, price float64) error {
+	if b.first == 0 {
+		b.first = price
+	}
+	b.market.now = at
+	b.market.price = price
+	return b.trader.Refresh()
+}
+
+// mark appends the account value at the current price to the equity curve
+func (b *backtest) mark(at time.Time) error {
+	balances, err := b.trader.GetAvailableBalances()
+	if err != nil {
+		return err
+	}
+	equity := 0.0
+	for _, balance := range balances {
+		amount := parseFloat(balance.Amount)
+		switch balance.Currency {
+		case b.config.BaseCurrency:
+			equity += amount * b.market.price
+		case b.config.QuoteCurrency:
+			equity += amount
+		}
+	}
+	b.equity = append(b.equity, EquityPoint{Time: at, Equity: equity})
+	return nil
+}
+
+// pricePath is the order in which prices are visited within a candle: open, then the extreme nearer the close
+// last, then the close
+func pricePath(candle public.Candle) []float64 {
+	if candle.Close >= candle.Open {
+		return []float64{candle.Open, candle.Low, candle.High, candle.Close}
+	}
+	return []float64{candle.Open, candle.High, candle.Low, candle.Close}
+}
+
+func Run(config Config, candles []public.Candle, strategy Strategy) (*Result, error) {
+	/*
+		Replay candles, oldest first, through a strategy
+
+		Within each candle the price moves from the open to the low and high (in the order implied by the candle's
+		direction) to the close, filling resting and stop orders it reaches. The strategy is then called with the
+		candle and the account is marked at the close. Orders the strategy places that cross the close fill
+		immediately as taker orders.
+
+		Args:
+		config (Config): simulated account and market
+		candles ([]public.Candle): e.g. from public.GetCandleSeries or LoadCandlesCSV
+		strategy (Strategy): strategy under test
+
+		Returns the Result, or the first error returned by the strategy or the simulation
+	*/
+	b, err := newBacktest(config)
+	if err != nil {
+		return nil, err
+	}
+	candles = append([]public.Candle(nil), candles...)
+	sort.SliceStable(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	for _, candle := range candles {
+		for _, price := range pricePath(candle) {
+			if err := b.moveTo(candle.Time, price); err != nil {
+				return nil, err
+			}
+		}
+		if err := strategy.OnCandle(b.trader, candle); err != nil {
+			return nil, err
+		}
+		if err := b.mark(candle.Time); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
+
+func RunTrades(config Config, trades []Trade, strategy Strategy) (*Result, error) {
+	/*
+		Replay trade history, oldest first, through a strategy
+
+		Each trade moves the price, filling resting and stop orders it reaches, before the strategy sees it: through
+		OnTrade if the strategy implements TradeStrategy, otherwise as a candle at the trade price.
+
+		Returns the Result, or the first error returned by the strategy or the simulation
+	*/
+	b, err := newBacktest(config)
+	if err != nil {
+		return nil, err
+	}
+	trades = append([]Trade(nil), trades...)
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time.Before(trades[j].Time) })
+	tradeStrategy, onTrade := strategy.(TradeStrategy)
+	for _, trade := range trades {
+		if err := b.moveTo(trade.Time, trade.Price); err != nil {
+			return nil, err
+		}
+		if onTrade {
+			err = tradeStrategy.OnTrade(b.trader, trade)
+		} else {
+			err = strategy.OnCandle(b.trader, public.Candle{
+				Time: trade.Time, Open: trade.Price, High: trade.Price, Low: trade.Price, Close: trade.Price, Volume: trade.Amount,
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := b.mark(trade.Time); err != nil {
+			return nil, err
+		}
+	}
+	return b.result(), nil
+}
+
+func ParseTrades(history []map[string]interface{}) ([]Trade, error) {
+	/*
+		Convert trade history as returned by public.GetTradeHistory into Trade values, ordered oldest first
+	*/
+	trades := make([]Trade, 0, len(history))
+	for _, entry := range history {
+		timestamp, err := toFloat(entry["timestampms"])
+		if err != nil {
+			return nil, errors.New("error parsing trade timestamp: " + err.Error())
+		}
+		price, err := toFloat(entry["price"])
+		if err != nil {
+			return nil, errors.New("error parsing trade price: " + err.Error())
+		}
+		amount, err := toFloat(entry["amount"])
+		if err != nil {
+			return nil, errors.New("error parsing trade amount: " + err.Error())
+		}
+		side, _ := entry["type"].(string)
+		trades = append(trades, Trade{Time: time.UnixMilli(int64(timestamp)).UTC(), Price: price, Amount: amount, Side: side})
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time.Before(trades[j].Time) })
+	return trades, nil
+}
@@ -0,0 +1,147 @@
+package backtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/papertest"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func candle(hour int, open float64, high float64, low float64, close float64) public.Candle {
+	return public.Candle{Time: start.Add(time.Duration(hour) * time.Hour), Open: open, High: high, Low: low, Close: close, Volume: 1}
+}
+
+// dipBuyer buys one BTC below the market and sells it into a rally
+type dipBuyer struct {
+	bought bool
+	sold   bool
+}
+
+func (s *dipBuyer) OnCandle(trader private.Trader, candle public.Candle) error {
+	if !s.bought {
+		s.bought = true
+		_, err := trader.LimitBuy("btcusd", 1, 95)
+		return err
+	}
+	balances, err := trader.GetAvailableBalances()
+	if err != nil {
+		return err
+	}
+	for _, balance := range balances {
+		if balance.Currency == "BTC" && balance.Available == "1" && !s.sold {
+			s.sold = true
+			_, err = trader.LimitSell("btcusd", 1, 110)
+		}
+	}
+	return err
+}
+
+func TestRunCandles(t *testing.T) {
+	candles := []public.Candle{
+		candle(2, 96, 111, 96, 108),
+		candle(0, 100, 101, 99, 100),
+		candle(1, 100, 100, 94, 96),
+		candle(3, 108, 108, 108, 108),
+	}
+	config := Config{Symbol: "btcusd", InitialBalances: map[string]float64{"USD": 1000}, MakerFee: 0.001, TakerFee: 0.002}
+	result, err := Run(config, candles, &dipBuyer{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(result.Trades) != 2 || result.Trades[0].Price != 95 || result.Trades[1].Price != 110 || !result.Trades[1].Maker {
+		t.Fatalf("trade log %+v", result.Trades)
+	}
+	if !papertest.Near(result.Trades[1].RealizedPnL, 14.795) {
+		t.Errorf("realized PnL %v, want 14.795", result.Trades[1].RealizedPnL)
+	}
+	want := []float64{1000, 1000.905, 1014.795, 1014.795}
+	for i, point := range result.EquityCurve {
+		if !papertest.Near(point.Equity, want[i]) {
+			t.Errorf("equity %d is %v, want %v", i, point.Equity, want[i])
+		}
+	}
+	stats := result.Stats
+	if !papertest.Near(stats.Return, 0.014795) || stats.MaxDrawdown != 0 || stats.WinRate != 1 || stats.Sells != 1 || !papertest.Near(stats.Fees, 0.205) {
+		t.Errorf("stats %+v", stats)
+	}
+	if stats.Sharpe <= 0 {
+		t.Errorf("sharpe of a rising equity curve should be positive: %v", stats.Sharpe)
+	}
+	t.Log(stats)
+}
+
+// stopLoss buys on the first trade and protects the position with a stop-limit sell
+type stopLoss struct {
+	trades int
+}
+
+func (s *stopLoss) OnCandle(trader private.Trader, candle public.Candle) error {
+	panic("OnTrade should be used")
+}
+
+func (s *stopLoss) OnTrade(trader private.Trader, trade Trade) error {
+	s.trades++
+	if s.trades > 1 {
+		return nil
+	}
+	if _, err := trader.LimitBuy("btcusd", 1, 105); err != nil {
+		return err
+	}
+	_, err := trader.StopLimitSell("btcusd", 1, 95, 80)
+	return err
+}
+
+func TestRunTradesWithSlippage(t *testing.T) {
+	trades, err := ParseTrades([]map[string]interface{}{
+		{"timestampms": float64(start.Add(2 * time.Second).UnixMilli()), "price": "90", "amount": "1", "type": "sell"},
+		{"timestampms": float64(start.UnixMilli()), "price": "100", "amount": "1", "type": "buy"},
+		{"timestampms": float64(start.Add(time.Second).UnixMilli()), "price": "100", "amount": "1", "type": "buy"},
+	})
+	if err != nil {
+		t.Fatalf("ParseTrades failed: %v", err)
+	}
+	config := Config{Symbol: "btcusd", InitialBalances: map[string]float64{"USD": 1000}, Slippage: 0.01}
+	result, err := RunTrades(config, trades, &stopLoss{})
+	if err != nil {
+		t.Fatalf("RunTrades failed: %v", err)
+	}
+	if len(result.Trades) != 2 || !papertest.Near(result.Trades[0].Price, 101) || !papertest.Near(result.Trades[1].Price, 89.1) {
+		t.Fatalf("taker fills should include slippage: %+v", result.Trades)
+	}
+	if stats := result.Stats; stats.WinRate != 0 || !papertest.Near(stats.RealizedPnL, -11.9) || !papertest.Near(stats.MaxDrawdown, (999-988.1)/999) {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestCandlesCSV(t *testing.T) {
+	candles := []public.Candle{candle(0, 100, 101, 99, 100.5), candle(1, 100.5, 102, 100, 101)}
+	var buf bytes.Buffer
+	if err := WriteCandlesCSV(&buf, candles); err != nil {
+		t.Fatalf("WriteCandlesCSV failed: %v", err)
+	}
+	read, err := ReadCandlesCSV(&buf)
+	if err != nil {
+		t.Fatalf("ReadCandlesCSV failed: %v", err)
+	}
+	if len(read) != 2 || read[1] != candles[1] {
+		t.Errorf("round trip returned %+v", read)
+	}
+	read, err = ReadCandlesCSV(strings.NewReader("2024-01-01T00:00:00Z,1,2,0.5,1.5,10\n"))
+	if err != nil || len(read) != 1 || !read[0].Time.Equal(start) {
+		t.Errorf("RFC 3339 times should parse: %+v %v", read, err)
+	}
+
+	result, _ := Run(Config{Symbol: "ethbtc", InitialBalances: map[string]float64{"BTC": 1}}, candles, &dipBuyer{bought: true, sold: true})
+	buf.Reset()
+	result.WriteEquityCSV(&buf)
+	if !strings.HasPrefix(buf.String(), "time,equity\n2024-01-01T00:00:00Z,1\n") {
+		t.Errorf("equity CSV %q", buf.String())
+	}
+}
@@ -0,0 +1,79 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+func LoadCandlesCSV(path string) ([]public.Candle, error) {
+	/*
+		Read candles from a CSV file. See ReadCandlesCSV
+	*/
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("error opening candles file: " + err.Error())
+	}
+	defer file.Close()
+	return ReadCandlesCSV(file)
+}
+
+func ReadCandlesCSV(r io.Reader) ([]public.Candle, error) {
+	/*
+		Read candles from CSV with columns time, open, high, low, close and volume. time is either milliseconds since
+		the Unix epoch, as in the API, or RFC 3339. A header row is skipped.
+	*/
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, errors.New("error reading candles CSV: " + err.Error())
+	}
+	var candles []public.Candle
+	for i, record := range records {
+		if len(record) < 6 {
+			return nil, errors.New("candles CSV line " + strconv.Itoa(i+1) + " has fewer than 6 columns")
+		}
+		at, err := parseTime(record[0])
+		if err != nil {
+			if i == 0 {
+				continue // header
+			}
+			return nil, errors.New("candles CSV line " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+		var values [5]float64
+		for j := range values {
+			if values[j], err = strconv.ParseFloat(record[j+1], 64); err != nil {
+				return nil, errors.New("candles CSV line " + strconv.Itoa(i+1) + ": " + err.Error())
+			}
+		}
+		candles = append(candles, public.Candle{Time: at, Open: values[0], High: values[1], Low: values[2], Close: values[3], Volume: values[4]})
+	}
+	return candles, nil
+}
+
+func WriteCandlesCSV(w io.Writer, candles []public.Candle) error {
+	/*
+		Write candles as CSV readable by ReadCandlesCSV, with times in milliseconds since the Unix epoch
+	*/
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "open", "high", "low", "close", "volume"})
+	for _, candle := range candles {
+		writer.Write([]string{
+			strconv.FormatInt(candle.Time.UnixMilli(), 10), formatFloat(candle.Open), formatFloat(candle.High),
+			formatFloat(candle.Low), formatFloat(candle.Close), formatFloat(candle.Volume),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func parseTime(value string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,204 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// EquityPoint is the account value, in the quote currency, after a replayed candle or trade
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+// Execution is one fill of a strategy order. RealizedPnL is set on sells, against the average cost of the position.
+type Execution struct {
+	Time        time.Time
+	OrderID     int
+	Side        string
+	Amount      float64
+	Price       float64
+	Fee         float64
+	Maker       bool
+	RealizedPnL float64
+}
+
+// Stats summarizes a backtest. Return and MaxDrawdown are fractions (0.1 is 10%); WinRate is the fraction of sells
+// with a positive realized profit after fees.
+type Stats struct {
+	InitialEquity float64
+	FinalEquity   float64
+	Return        float64
+	MaxDrawdown   float64
+	Sharpe        float64
+	WinRate       float64
+	Fills         int
+	Sells         int
+	Fees          float64
+	RealizedPnL   float64
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("return %.2f%%, max drawdown %.2f%%, sharpe %.2f, win rate %.2f%% (%d sells, %d fills), fees %.2f, realized PnL %.2f, equity %.2f -> %.2f",
+		s.Return*100, s.MaxDrawdown*100, s.Sharpe, s.WinRate*100, s.Sells, s.Fills, s.Fees, s.RealizedPnL, s.InitialEquity, s.FinalEquity)
+}
+
+// Result is the outcome of a backtest
+type Result struct {
+	EquityCurve []EquityPoint
+	Trades      []Execution
+	Stats       Stats
+}
+
+func (b *backtest) result() *Result {
+	result := &Result{EquityCurve: b.equity}
+
+	// the starting position is valued at the first replayed price
+	position := b.config.InitialBalances[b.config.BaseCurrency]
+	cost := position * b.first
+	for _, fill := range b.trader.Fills() {
+		execution := Execution{
+			Time: fill.Time, OrderID: fill.OrderID, Side: fill.Side, Amount: fill.Amount, Price: fill.Price, Fee: fill.Fee, Maker: fill.Maker,
+		}
+		if fill.Side == "buy" {
+			position += fill.Amount
+			cost += fill.Amount*fill.Price + fill.Fee
+		} else {
+			averageCost := 0.0
+			if position > 0 {
+				averageCost = cost / position
+			}
+			execution.RealizedPnL = fill.Amount*(fill.Price-averageCost) - fill.Fee
+			position -= fill.Amount
+			cost -= fill.Amount * averageCost
+			result.Stats.Sells++
+			result.Stats.RealizedPnL += execution.RealizedPnL
+			if execution.RealizedPnL > 0 {
+				result.Stats.WinRate++
+			}
+		}
+		result.Stats.Fees += fill.Fee
+		result.Trades = append(result.Trades, execution)
+	}
+	result.Stats.Fills = len(result.Trades)
+	if result.Stats.Sells > 0 {
+		result.Stats.WinRate /= float64(result.Stats.Sells)
+	}
+
+	if len(b.equity) > 0 {
+		result.Stats.InitialEquity = b.equity[0].Equity
+		result.Stats.FinalEquity = b.equity[len(b.equity)-1].Equity
+		if result.Stats.InitialEquity != 0 {
+			result.Stats.Return = result.Stats.FinalEquity/result.Stats.InitialEquity - 1
+		}
+	}
+	result.Stats.MaxDrawdown = maxDrawdown(b.equity)
+	result.Stats.Sharpe = sharpe(b.equity, b.config.PeriodsPerYear)
+	return result
+}
+
+func maxDrawdown(equity []EquityPoint) float64 {
+	peak, drawdown := 0.0, 0.0
+	for _, point := range equity {
+		peak = math.Max(peak, point.Equity)
+		if peak > 0 {
+			drawdown = math.Max(drawdown, (peak-point.Equity)/peak)
+		}
+	}
+	return drawdown
+}
+
+// sharpe is the annualized Sharpe ratio of the per-period returns of the equity curve, with a risk free rate of zero
+func sharpe(equity []EquityPoint, periodsPerYear float64) float64 {
+	if len(equity) < 3 {
+		return 0
+	}
+	returns := make([]float64, 0, len(equity)-1)
+	intervals := make([]float64, 0, len(equity)-1)
+	for i := 1; i < len(equity); i++ {
+		if equity[i-1].Equity != 0 {
+			returns = append(returns, equity[i].Equity/equity[i-1].Equity-1)
+		}
+		intervals = append(intervals, equity[i].Time.Sub(equity[i-1].Time).Seconds())
+	}
+	if periodsPerYear == 0 {
+		sort.Float64s(intervals)
+		median := intervals[len(intervals)/2]
+		if median <= 0 {
+			return 0
+		}
+		periodsPerYear = (365 * 24 * time.Hour).Seconds() / median
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(returns)-1))
+	if stddev == 0 {
+		return 0
+	}
+	return mean / stddev * math.Sqrt(periodsPerYear)
+}
+
+func (r *Result) WriteEquityCSV(w io.Writer) error {
+	/*
+		Write the equity curve as CSV with columns time (RFC 3339) and equity
+	*/
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "equity"})
+	for _, point := range r.EquityCurve {
+		writer.Write([]string{point.Time.Format(time.RFC3339), formatFloat(point.Equity)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *Result) WriteTradesCSV(w io.Writer) error {
+	/*
+		Write the trade log as CSV with columns time, order_id, side, amount, price, fee, maker and realized_pnl
+	*/
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "order_id", "side", "amount", "price", "fee", "maker", "realized_pnl"})
+	for _, trade := range r.Trades {
+		writer.Write([]string{
+			trade.Time.Format(time.RFC3339), strconv.Itoa(trade.OrderID), trade.Side, formatFloat(trade.Amount),
+			formatFloat(trade.Price), formatFloat(trade.Fee), strconv.FormatBool(trade.Maker), formatFloat(trade.RealizedPnL),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func parseFloat(value string) float64 {
+	number, _ := strconv.ParseFloat(value, 64)
+	return number
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, errors.New("unexpected value " + fmt.Sprint(value))
+	}
+}
@@ -0,0 +1,364 @@
+// Package bot runs a trading strategy: it polls market data, feeds ticks, closed candles and fills of the strategy's
+// orders to it, and manages its lifecycle, cancelling the strategy's open orders when it stops.
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// State is the lifecycle state of a Bot
+type State int
+
+const (
+	Stopped State = iota
+	Running
+	Paused
+)
+
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Paused:
+		return "paused"
+	default:
+		return "stopped"
+	}
+}
+
+// Bot runs a Strategy against a private.Trader
+type Bot struct {
+	config   Config
+	trader   private.Trader
+	strategy Strategy
+	market   MarketData
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	cancel      context.CancelFunc
+	done        chan struct{}
+	orders      map[int]*trackedOrder
+	lastCandles map[string]time.Time
+}
+
+type trackedOrder struct {
+	symbol   string
+	executed float64
+}
+
+// Option configures optional behavior of a Bot
+type Option func(*Bot)
+
+func WithMarketData(market MarketData) Option {
+	/*
+		Read market data from the given source instead of PollingMarketData
+	*/
+	return func(b *Bot) {
+		b.market = market
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	/*
+		Log through the given logger instead of util.Logger()
+	*/
+	return func(b *Bot) {
+		b.logger = logger
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	/*
+		Decide which candles are closed with the given clock instead of time.Now
+	*/
+	return func(b *Bot) {
+		b.now = now
+	}
+}
+
+func New(config Config, trader private.Trader, strategy Strategy, opts ...Option) (*Bot, error) {
+	/*
+		Create a bot
+
+		Args:
+		config (Config): symbols, polling and candle settings; the strategy and paper settings are used by callers building the trader and strategy
+		trader (private.Trader): a *private.Client to trade live, or a *paper.Trader
+		strategy (Strategy): the strategy to run
+		opts (...Option): optional behavior, e.g. WithMarketData
+
+		Returns a pointer to the Bot, or an error if the configuration is invalid
+	*/
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	b := &Bot{
+		config:      config,
+		trader:      trader,
+		strategy:    strategy,
+		market:      PollingMarketData{},
+		now:         time.Now,
+		orders:      map[int]*trackedOrder{},
+		lastCandles: map[string]time.Time{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.logger == nil {
+		b.logger = util.Logger()
+	}
+	b.logger = b.logger.With("component", "bot")
+	return b, nil
+}
+
+func (b *Bot) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Bot) Run(ctx context.Context) error {
+	/*
+		Run the strategy until the context is cancelled or Stop is called, polling every PollInterval. On the way out,
+		every open order placed by the strategy is cancelled.
+	*/
+	b.mu.Lock()
+	if b.state != Stopped {
+		b.mu.Unlock()
+		return nil
+	}
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.done = make(chan struct{})
+	b.state = Running
+	b.mu.Unlock()
+	b.logger.Info("bot started", "symbols", b.config.Symbols, "poll_interval", b.config.PollInterval.Duration)
+
+	defer func() {
+		b.cancelOpenOrders()
+		b.mu.Lock()
+		b.state = Stopped
+		close(b.done)
+		b.mu.Unlock()
+		b.logger.Info("bot stopped")
+	}()
+
+	ticker := time.NewTicker(b.config.PollInterval.Duration)
+	defer ticker.Stop()
+	for {
+		if b.State() == Running {
+			b.Step()
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Bot) Pause() {
+	/*
+		Stop feeding market data to the strategy. Open orders stay on the book.
+	*/
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Running {
+		b.state = Paused
+		b.logger.Info("bot paused")
+	}
+}
+
+func (b *Bot) Resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Paused {
+		b.state = Running
+		b.logger.Info("bot resumed")
+	}
+}
+
+func (b *Bot) Stop() {
+	/*
+		Stop the bot and wait for Run to cancel the strategy's open orders and return
+	*/
+	b.mu.Lock()
+	cancel, done := b.cancel, b.done
+	b.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (b *Bot) Step() {
+	/*
+		Run one polling cycle: report fills of tracked orders, then deliver each symbol's tick and newly closed
+		candles. Errors are logged and do not stop the bot.
+	*/
+	trader := &trackingTrader{Trader: b.trader, bot: b}
+	b.checkFills(trader)
+	for _, symbol := range b.config.Symbols {
+		tick, err := b.market.Tick(symbol)
+		if err != nil {
+			b.logger.Error("market data failed", "symbol", symbol, "error", err)
+			continue
+		}
+		if err := b.strategy.OnTick(trader, tick); err != nil {
+			b.logger.Error("strategy OnTick failed", "symbol", symbol, "error", err)
+		}
+		if b.config.CandleTimeFrame != "" {
+			b.deliverCandles(trader, symbol)
+		}
+	}
+}
+
+// deliverCandles passes the symbol's closed candles newer than the last delivered one to the strategy; on the
+// first poll only the most recent WarmupCandles+1 are delivered
+func (b *Bot) deliverCandles(trader private.Trader, symbol string) {
+	candles, err := b.market.Candles(symbol, b.config.CandleTimeFrame)
+	if err != nil {
+		b.logger.Error("candle data failed", "symbol", symbol, "error", err)
+		return
+	}
+	frame := timeFrames[b.config.CandleTimeFrame]
+	now := b.now()
+	var closed []public.Candle
+	for _, candle := range candles {
+		if !candle.Time.Add(frame).After(now) {
+			closed = append(closed, candle)
+		}
+	}
+	b.mu.Lock()
+	last, seen := b.lastCandles[symbol]
+	b.mu.Unlock()
+	if !seen && len(closed) > b.config.WarmupCandles+1 {
+		closed = closed[len(closed)-b.config.WarmupCandles-1:]
+	}
+	for _, candle := range closed {
+		if seen && !candle.Time.After(last) {
+			continue
+		}
+		if err := b.strategy.OnCandle(trader, Candle{Symbol: symbol, Candle: candle}); err != nil {
+			b.logger.Error("strategy OnCandle failed", "symbol", symbol, "error", err)
+		}
+		b.mu.Lock()
+		b.lastCandles[symbol] = candle.Time
+		b.mu.Unlock()
+	}
+}
+
+// checkFills reports newly executed amounts of tracked orders and stops tracking orders that are no longer live
+func (b *Bot) checkFills(trader private.Trader) {
+	b.mu.Lock()
+	ids := make([]int, 0, len(b.orders))
+	for id := range b.orders {
+		ids = append(ids, id)
+	}
+	b.mu.Unlock()
+
+	for _, id := range ids {
+		order, err := b.trader.GetOrderStatus(id)
+		if err != nil {
+			b.logger.Error("order status failed", "order_id", id, "error", err)
+			continue
+		}
+		b.mu.Lock()
+		tracked := b.orders[id]
+		executed, _ := strconv.ParseFloat(order.ExecutedAmount, 64)
+		delta := executed - tracked.executed
+		tracked.executed = executed
+		if !order.IsLive {
+			delete(b.orders, id)
+		}
+		b.mu.Unlock()
+		if delta <= 0 {
+			continue
+		}
+		price, _ := strconv.ParseFloat(order.AvgExecutionPrice, 64)
+		fill := Fill{Symbol: tracked.symbol, OrderID: id, Side: order.Side, Amount: delta, Price: price, Order: order}
+		b.logger.Info("order filled", "symbol", fill.Symbol, "order_id", id, "side", fill.Side, "amount", delta, "price", price)
+		if err := b.strategy.OnFill(trader, fill); err != nil {
+			b.logger.Error("strategy OnFill failed", "order_id", id, "error", err)
+		}
+	}
+}
+
+// track starts reporting fills of an order placed by the strategy. Orders already executed when placed are
+// reported on the next poll.
+func (b *Bot) track(order *private.Order, err error) (*private.Order, error) {
+	if err != nil || order == nil {
+		return order, err
+	}
+	id, convErr := strconv.Atoi(order.OrderID)
+	if convErr != nil {
+		b.logger.Warn("untracked order with non-numeric id", "order_id", order.OrderID)
+		return order, nil
+	}
+	b.mu.Lock()
+	b.orders[id] = &trackedOrder{symbol: order.Symbol}
+	b.mu.Unlock()
+	return order, nil
+}
+
+func (b *Bot) OpenOrders() []int {
+	/*
+		Get the ids of the orders placed by the strategy that the bot still tracks as open
+	*/
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]int, 0, len(b.orders))
+	for id := range b.orders {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (b *Bot) cancelOpenOrders() {
+	for _, id := range b.OpenOrders() {
+		order, err := b.trader.CancelOrder(id)
+		if err != nil {
+			b.logger.Error("cancel on stop failed", "order_id", id, "error", err)
+			continue
+		}
+		b.logger.Info("cancelled open order on stop", "order_id", id, "symbol", order.Symbol)
+		b.mu.Lock()
+		delete(b.orders, id)
+		b.mu.Unlock()
+	}
+}
+
+// trackingTrader is the trader handed to strategies; it registers the orders they place with the bot
+type trackingTrader struct {
+	private.Trader
+	bot *Bot
+}
+
+func (t *trackingTrader) LimitBuy(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	return t.bot.track(t.Trader.LimitBuy(symbol, amount, limitPrice, options...))
+}
+
+func (t *trackingTrader) LimitSell(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	return t.bot.track(t.Trader.LimitSell(symbol, amount, limitPrice, options...))
+}
+
+func (t *trackingTrader) LimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options ...string) (*private.Order, error) {
+	return t.bot.track(t.Trader.LimitOrder(side, symbol, amount, limitPrice, clientOrderID, options...))
+}
+
+func (t *trackingTrader) StopLimitBuy(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	return t.bot.track(t.Trader.StopLimitBuy(symbol, amount, stopPrice, limitPrice))
+}
+
+func (t *trackingTrader) StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	return t.bot.track(t.Trader.StopLimitSell(symbol, amount, stopPrice, limitPrice))
+}
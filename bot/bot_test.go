@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/risk"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeMarket serves one price as ticks, paper books and minute candles
+type fakeMarket struct {
+	mu      sync.Mutex
+	price   float64
+	candles []public.Candle
+}
+
+func (m *fakeMarket) setPrice(price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.price = price
+}
+
+func (m *fakeMarket) Tick(symbol string) (Tick, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Tick{Symbol: symbol, Bid: m.price, Ask: m.price, Last: m.price}, nil
+}
+
+func (m *fakeMarket) Candles(symbol string, timeFrame string) ([]public.Candle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.candles, nil
+}
+
+func (m *fakeMarket) Book(symbol string) (*paper.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	level := []paper.Level{{Price: m.price, Amount: math.Inf(1)}}
+	return &paper.Book{Bids: level, Asks: level}, nil
+}
+
+func (m *fakeMarket) Currencies(symbol string) (string, string, error) {
+	return "BTC", "USD", nil
+}
+
+// recorder buys below the market on its first tick and records what it is fed
+type recorder struct {
+	mu      sync.Mutex
+	ticks   int
+	candles []Candle
+	fills   []Fill
+}
+
+func (r *recorder) OnTick(trader private.Trader, tick Tick) error {
+	r.mu.Lock()
+	r.ticks++
+	first := r.ticks == 1
+	r.mu.Unlock()
+	if !first {
+		return nil
+	}
+	if _, err := trader.LimitBuy(tick.Symbol, 1, tick.Bid-5); err != nil {
+		return err
+	}
+	_, err := trader.LimitBuy(tick.Symbol, 1, tick.Bid/2)
+	return err
+}
+
+func (r *recorder) OnCandle(trader private.Trader, candle Candle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.candles = append(r.candles, candle)
+	return nil
+}
+
+func (r *recorder) OnFill(trader private.Trader, fill Fill) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fills = append(r.fills, fill)
+	return nil
+}
+
+func TestBotLifecycle(t *testing.T) {
+	market := &fakeMarket{price: 100}
+	for i := 0; i < 5; i++ {
+		market.candles = append(market.candles, public.Candle{Time: start.Add(time.Duration(i) * time.Minute), Close: float64(100 + i)})
+	}
+	trader := paper.NewTrader(market, map[string]float64{"USD": 1000})
+	strategy := &recorder{}
+	now := start.Add(4*time.Minute + 30*time.Second)
+	config := Config{Symbols: []string{"BTCUSD"}, PollInterval: Duration{time.Millisecond}, CandleTimeFrame: "1m", WarmupCandles: 1}
+	b, err := New(config, trader, strategy, WithMarketData(market), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	b.Step()
+	if len(b.OpenOrders()) != 2 {
+		t.Fatalf("orders placed by the strategy should be tracked: %v", b.OpenOrders())
+	}
+	// the candle starting at 4m is still open; one warmup candle precedes the latest closed one
+	if len(strategy.candles) != 2 || strategy.candles[1].Close != 103 || strategy.candles[0].Symbol != "btcusd" {
+		t.Errorf("candles delivered %+v", strategy.candles)
+	}
+
+	market.setPrice(94)
+	now = now.Add(time.Minute)
+	b.Step()
+	if len(strategy.fills) != 1 || strategy.fills[0].Price != 95 || strategy.fills[0].Amount != 1 {
+		t.Errorf("fills delivered %+v", strategy.fills)
+	}
+	if len(strategy.candles) != 3 || strategy.candles[2].Close != 104 {
+		t.Errorf("a newly closed candle should be delivered once: %+v", strategy.candles)
+	}
+
+	done := make(chan error)
+	go func() { done <- b.Run(context.Background()) }()
+	for b.State() != Running {
+		time.Sleep(time.Millisecond)
+	}
+	b.Pause()
+	if b.State() != Paused {
+		t.Errorf("state %v, want paused", b.State())
+	}
+	b.Resume()
+	b.Stop()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+	if b.State() != Stopped || len(b.OpenOrders()) != 0 {
+		t.Errorf("stop should cancel open orders: state %v, open %v", b.State(), b.OpenOrders())
+	}
+	history, _ := trader.GetClosedOrdersHistory()
+	if len(history) != 2 || !history[0].IsCancelled {
+		t.Errorf("resting order should be cancelled on stop: %+v", history)
+	}
+	if usd, _ := strconv.ParseFloat(mustBalance(t, trader, "USD").Available, 64); usd != mustAmount(t, trader, "USD") {
+		t.Errorf("cancelled order should release held USD")
+	}
+}
+
+func mustBalance(t *testing.T, trader private.Trader, currency string) private.AvailableBalance {
+	t.Helper()
+	balances, err := trader.GetAvailableBalances()
+	if err != nil {
+		t.Fatalf("GetAvailableBalances failed: %v", err)
+	}
+	for _, balance := range balances {
+		if balance.Currency == currency {
+			return balance
+		}
+	}
+	t.Fatalf("no %s balance", currency)
+	return private.AvailableBalance{}
+}
+
+func mustAmount(t *testing.T, trader private.Trader, currency string) float64 {
+	amount, _ := strconv.ParseFloat(mustBalance(t, trader, currency).Amount, 64)
+	return amount
+}
+
+func TestLoadConfigAndRegistry(t *testing.T) {
+	Register("test-recorder", func(config json.RawMessage) (Strategy, error) {
+		return &recorder{}, nil
+	})
+	path := filepath.Join(t.TempDir(), "bot.json")
+	os.WriteFile(path, []byte(`{
+		"symbols": ["ETHUSD"],
+		"poll_interval": "250ms",
+		"paper": true,
+		"paper_balances": {"USD": 500},
+		"strategy": "test-recorder",
+		"strategy_config": {"anything": true}
+	}`), 0644)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.PollInterval.Duration != 250*time.Millisecond || config.Symbols[0] != "ethusd" {
+		t.Errorf("config %+v", config)
+	}
+	b, err := NewFromConfig(*config)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if _, ok := b.trader.(*paper.Trader); !ok {
+		t.Errorf("paper config should trade with a paper trader, got %T", b.trader)
+	}
+
+	config.Risk = &risk.Config{Symbols: map[string]risk.Limits{"ETHUSD": {MaxNotional: 100}}}
+	if b, err = NewFromConfig(*config); err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if _, ok := b.trader.(*risk.Trader); !ok {
+		t.Errorf("config with risk limits should trade through a risk trader, got %T", b.trader)
+	}
+	if _, ok := config.Risk.Symbols["ethusd"]; !ok {
+		t.Errorf("risk symbols should be lowercased: %+v", config.Risk.Symbols)
+	}
+
+	config.Strategy = "missing"
+	if _, err := NewFromConfig(*config); err == nil {
+		t.Errorf("unknown strategies should be rejected")
+	}
+	if err := (&Config{Symbols: []string{"btcusd"}, CandleTimeFrame: "2m"}).Validate(); err == nil {
+		t.Errorf("unknown time frames should be rejected")
+	}
+	if err := (&Config{}).Validate(); err == nil {
+		t.Errorf("configs without symbols should be rejected")
+	}
+}
@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/risk"
+)
+
+// Config configures a bot. It is read from JSON, e.g.
+//
+//	{
+//	  "symbols": ["btcusd"],
+//	  "poll_interval": "10s",
+//	  "candle_time_frame": "1m",
+//	  "paper": true,
+//	  "paper_balances": {"USD": 10000},
+//	  "strategy": "dca",
+//	  "strategy_config": {...},
+//	  "risk": {"default": {"max_notional": 1000}}
+//	}
+type Config struct {
+	Symbols      []string `json:"symbols"`
+	PollInterval Duration `json:"poll_interval"`
+	// CandleTimeFrame is a time frame of the candles endpoint, e.g. "1m"; no candles are delivered if empty
+	CandleTimeFrame string `json:"candle_time_frame"`
+	// WarmupCandles is the number of historical closed candles delivered before the first new one
+	WarmupCandles int `json:"warmup_candles"`
+	// Paper trades with virtual PaperBalances against live market data instead of the account
+	Paper         bool               `json:"paper"`
+	PaperBalances map[string]float64 `json:"paper_balances"`
+	PaperMakerFee float64            `json:"paper_maker_fee"`
+	PaperTakerFee float64            `json:"paper_taker_fee"`
+	// Strategy is the name of a registered strategy, created with StrategyConfig
+	Strategy       string          `json:"strategy"`
+	StrategyConfig json.RawMessage `json:"strategy_config"`
+	// Risk, if set, checks every order of the bot against its limits before it is placed
+	Risk *risk.Config `json:"risk"`
+}
+
+// DefaultPollInterval is used when a Config does not set a poll interval
+const DefaultPollInterval = 10 * time.Second
+
+// Duration is a time.Duration read from JSON as a string such as "10s"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("durations must be strings such as \"10s\": " + err.Error())
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func LoadConfig(path string) (*Config, error) {
+	/*
+		Read and validate a JSON bot configuration file
+	*/
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("error reading bot config: " + err.Error())
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, errors.New("error parsing bot config: " + err.Error())
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Config) Validate() error {
+	/*
+		Check the configuration, filling in defaults
+	*/
+	if len(c.Symbols) == 0 {
+		return errors.New("bot config: no symbols")
+	}
+	for i, symbol := range c.Symbols {
+		c.Symbols[i] = strings.ToLower(symbol)
+	}
+	if c.PollInterval.Duration == 0 {
+		c.PollInterval.Duration = DefaultPollInterval
+	}
+	if c.PollInterval.Duration < 0 {
+		return errors.New("bot config: negative poll interval")
+	}
+	if _, ok := timeFrames[c.CandleTimeFrame]; c.CandleTimeFrame != "" && !ok {
+		return errors.New("bot config: unknown candle time frame " + c.CandleTimeFrame)
+	}
+	if c.Risk != nil && len(c.Risk.Symbols) > 0 {
+		symbols := make(map[string]risk.Limits, len(c.Risk.Symbols))
+		for symbol, limits := range c.Risk.Symbols {
+			symbols[strings.ToLower(symbol)] = limits
+		}
+		c.Risk.Symbols = symbols
+	}
+	return nil
+}
+
+func NewFromConfig(config Config, opts ...Option) (*Bot, error) {
+	/*
+		Create a bot with the registered strategy named by the configuration, trading either with virtual balances
+		through a paper trader or live through private.DefaultClient(), behind a risk.Trader if the configuration has
+		risk limits
+	*/
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	strategy, err := NewStrategy(config.Strategy, config.StrategyConfig)
+	if err != nil {
+		return nil, err
+	}
+	var trader private.Trader = private.DefaultClient()
+	if config.Paper {
+		var paperOpts []paper.Option
+		if config.PaperMakerFee != 0 || config.PaperTakerFee != 0 {
+			paperOpts = append(paperOpts, paper.WithFees(config.PaperMakerFee, config.PaperTakerFee))
+		}
+		trader = paper.NewTrader(&paper.LiveMarketData{}, config.PaperBalances, paperOpts...)
+	}
+	if config.Risk != nil {
+		trader = risk.NewTrader(trader, *config.Risk)
+	}
+	return New(config, trader, strategy, opts...)
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+// MarketData supplies the ticks and candles the bot feeds to its strategy. PollingMarketData reads the REST API; a
+// streaming feed can be plugged in by implementing this interface over the market data WebSocket.
+type MarketData interface {
+	Tick(symbol string) (Tick, error)
+	Candles(symbol string, timeFrame string) ([]public.Candle, error)
+}
+
+// PollingMarketData reads ticks from the v2 ticker and candles from the candles endpoint on every poll
+type PollingMarketData struct{}
+
+func (PollingMarketData) Tick(symbol string) (Tick, error) {
+	var ticker public.TickerV2
+	if err := public.GetPublicEndpoint("/v2/ticker/"+symbol, &ticker); err != nil {
+		return Tick{}, err
+	}
+	tick := Tick{Symbol: symbol, Time: time.Now()}
+	var err error
+	for _, field := range []struct {
+		value  string
+		target *float64
+	}{{ticker.Bid, &tick.Bid}, {ticker.Ask, &tick.Ask}, {ticker.Close, &tick.Last}} {
+		if *field.target, err = strconv.ParseFloat(field.value, 64); err != nil {
+			return Tick{}, errors.New("error parsing ticker of " + symbol + ": " + err.Error())
+		}
+	}
+	return tick, nil
+}
+
+func (PollingMarketData) Candles(symbol string, timeFrame string) ([]public.Candle, error) {
+	var raw [][]interface{}
+	if err := public.GetPublicEndpoint("/v2/candles/"+symbol+"/"+timeFrame, &raw); err != nil {
+		return nil, err
+	}
+	return public.ParseCandles(raw)
+}
+
+// timeFrames are the durations of the candle time frames of the API
+var timeFrames = map[string]time.Duration{
+	"1m":   time.Minute,
+	"5m":   5 * time.Minute,
+	"15m":  15 * time.Minute,
+	"30m":  30 * time.Minute,
+	"1hr":  time.Hour,
+	"6hr":  6 * time.Hour,
+	"1day": 24 * time.Hour,
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+// Tick is a top of book snapshot of a symbol
+type Tick struct {
+	Symbol string
+	Time   time.Time
+	Bid    float64
+	Ask    float64
+	Last   float64
+}
+
+// Candle is a closed candle of a symbol
+type Candle struct {
+	Symbol string
+	public.Candle
+}
+
+// Fill reports that an order placed by the strategy was (partially) executed. Amount is the newly executed amount;
+// Order is the order's state after the fill.
+type Fill struct {
+	Symbol  string
+	OrderID int
+	Side    string
+	Amount  float64
+	Price   float64
+	Order   *private.Order
+}
+
+// Strategy reacts to market data and fills by placing and cancelling orders through the trader. Orders placed through
+// the trader are tracked by the bot, which reports their fills and cancels them when it stops.
+type Strategy interface {
+	OnTick(trader private.Trader, tick Tick) error
+	OnCandle(trader private.Trader, candle Candle) error
+	OnFill(trader private.Trader, fill Fill) error
+}
+
+// StrategyFactory creates a strategy from its JSON configuration
+type StrategyFactory func(config json.RawMessage) (Strategy, error)
+
+var (
+	strategies     = map[string]StrategyFactory{}
+	strategiesLock sync.Mutex
+)
+
+func Register(name string, factory StrategyFactory) {
+	/*
+		Make a strategy available to configuration files under the given name. Strategy packages call it from init.
+	*/
+	strategiesLock.Lock()
+	defer strategiesLock.Unlock()
+	if _, exists := strategies[name]; exists {
+		panic("bot: strategy " + name + " registered twice")
+	}
+	strategies[name] = factory
+}
+
+func NewStrategy(name string, config json.RawMessage) (Strategy, error) {
+	/*
+		Create a registered strategy from its JSON configuration
+	*/
+	strategiesLock.Lock()
+	factory, ok := strategies[name]
+	strategiesLock.Unlock()
+	if !ok {
+		return nil, errors.New("bot: unknown strategy " + name)
+	}
+	return factory(config)
+}
+
+func Strategies() []string {
+	/*
+		Get the names of the registered strategies, sorted
+	*/
+	strategiesLock.Lock()
+	defer strategiesLock.Unlock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
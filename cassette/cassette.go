@@ -0,0 +1,120 @@
+// Package cassette records HTTP interactions with the Gemini API to files and replays them in tests.
+//
+// Recorded requests keep only their method, path, query and signed payload; the API key and signature headers are
+// dropped and the nonce is removed from the payload, so cassettes contain no credentials and replay matches
+// requests regardless of when they were signed.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Cassette is the file format of recorded interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a scrubbed request: no host, no credential headers and a payload without its nonce
+type Request struct {
+	Method  string          `json:"method"`
+	URL     string          `json:"url"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// ScrubbedPayloadFields are removed from signed payloads before they are recorded or matched
+var ScrubbedPayloadFields = []string{"nonce"}
+
+func Load(path string) (*Cassette, error) {
+	/*
+		Read a cassette file
+	*/
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("error reading cassette: " + err.Error())
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, errors.New("error parsing cassette: " + err.Error())
+	}
+	return &c, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	/*
+		Write the cassette to a file
+	*/
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.New("error encoding cassette: " + err.Error())
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return errors.New("error writing cassette: " + err.Error())
+	}
+	return nil
+}
+
+func scrubRequest(req *http.Request) (Request, error) {
+	/*
+		Reduce a request to what is recorded and matched: method, path and query, and the decoded payload without scrubbed fields
+	*/
+	scrubbed := Request{Method: req.Method, URL: req.URL.RequestURI()}
+	encoded := req.Header.Get("X-GEMINI-PAYLOAD")
+	if encoded == "" {
+		return scrubbed, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return scrubbed, errors.New("error decoding payload: " + err.Error())
+	}
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(decoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return scrubbed, errors.New("error parsing payload: " + err.Error())
+	}
+	for _, field := range ScrubbedPayloadFields {
+		delete(payload, field)
+	}
+	// maps marshal with sorted keys, so equal payloads have equal encodings
+	scrubbed.Payload, _ = json.Marshal(payload)
+	return scrubbed, nil
+}
+
+// Matcher decides whether a recorded request answers an actual one
+type Matcher func(recorded Request, actual Request) bool
+
+func DefaultMatcher(recorded Request, actual Request) bool {
+	/*
+		Match on method, path and query, and payload (nonce excluded). Signature and API key headers are never compared.
+	*/
+	return recorded.Method == actual.Method &&
+		recorded.URL == actual.URL &&
+		bytes.Equal(compact(recorded.Payload), compact(actual.Payload))
+}
+
+func compact(raw json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return []byte(strings.TrimSpace(string(raw)))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,120 @@
+package cassette_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/austinjhunt/go-gemini/cassette"
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+func postBalances(t *testing.T, client *private.Client) []map[string]interface{} {
+	t.Helper()
+	payload, _ := json.Marshal(map[string]interface{}{
+		"request": "/v1/balances",
+		"nonce":   util.GenerateNonceString(),
+	})
+	var balances []map[string]interface{}
+	if err := client.PostPrivateEndpoint(payload, &balances); err != nil {
+		t.Fatalf("PostPrivateEndpoint failed: %v", err)
+	}
+	return balances
+}
+
+func TestRecordAndReplayPrivate(t *testing.T) {
+	server := geminitest.NewServer()
+	path := filepath.Join(t.TempDir(), "balances.json")
+
+	recorder := cassette.NewRecorder(nil, server.APIKey, server.APISecret)
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret},
+		private.WithBaseURL(server.URL), private.WithHTTPClient(&http.Client{Transport: recorder}))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	recorded := postBalances(t, client)
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	server.Close()
+
+	data, _ := os.ReadFile(path)
+	for _, leaked := range []string{server.APIKey, server.APISecret, "nonce", "X-Gemini-Signature"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("cassette contains %q: %s", leaked, data)
+		}
+	}
+
+	// replayed with different credentials and a new nonce, against a server that no longer exists
+	replayer, err := cassette.LoadReplayer(path)
+	if err != nil {
+		t.Fatalf("LoadReplayer failed: %v", err)
+	}
+	client, _ = private.NewClient(private.StaticCredentials{APIKey: "other-key", APISecret: "other-secret"},
+		private.WithBaseURL(server.URL), private.WithHTTPClient(&http.Client{Transport: replayer}))
+	replayed := postBalances(t, client)
+	if len(replayed) == 0 || len(replayed) != len(recorded) {
+		t.Errorf("replayed %d balances, recorded %d", len(replayed), len(recorded))
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("interactions left unused: %+v", unused)
+	}
+
+	// each interaction answers once unless repeats are allowed
+	payload, _ := json.Marshal(map[string]interface{}{"request": "/v1/balances", "nonce": util.GenerateNonceString()})
+	var balances []map[string]interface{}
+	if err := client.PostPrivateEndpoint(payload, &balances); !errors.Is(err, cassette.ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction, got %v", err)
+	}
+	replayer.AllowRepeats = true
+	postBalances(t, client)
+}
+
+func TestRecordAndReplayPublic(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	t.Setenv("GEMINI_EXCHANGE_API_BASE_URL", server.URL)
+	previous := util.HTTPClient()
+	defer util.SetHTTPClient(previous)
+
+	recorder := cassette.NewRecorder(nil)
+	util.SetHTTPClient(&http.Client{Transport: recorder})
+	recorded := public.GetTickerV2("btcusd")
+
+	replayer := cassette.NewReplayer(recorder.Cassette())
+	util.SetHTTPClient(&http.Client{Transport: replayer})
+	replayed := public.GetTickerV2("btcusd")
+	if replayed == nil || replayed.Close != recorded.Close {
+		t.Errorf("replayed ticker %+v, recorded %+v", replayed, recorded)
+	}
+
+	replayer.AllowRepeats = true
+	res, err := util.HTTPClient().Get(server.URL + "/v2/ticker/btcusd")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.Status != "200 OK" {
+		t.Errorf("replayed status %q", res.Status)
+	}
+}
+
+func TestDefaultMatcher(t *testing.T) {
+	recorded := cassette.Request{Method: "POST", URL: "/v1/order/status", Payload: json.RawMessage(`{"order_id": 1, "request": "/v1/order/status"}`)}
+	if !cassette.DefaultMatcher(recorded, cassette.Request{Method: "POST", URL: "/v1/order/status", Payload: json.RawMessage(`{"order_id":1,"request":"/v1/order/status"}`)}) {
+		t.Errorf("payloads differing only in whitespace should match")
+	}
+	if cassette.DefaultMatcher(recorded, cassette.Request{Method: "POST", URL: "/v1/order/status", Payload: json.RawMessage(`{"order_id":2,"request":"/v1/order/status"}`)}) {
+		t.Errorf("payloads with different fields should not match")
+	}
+	if _, err := cassette.Load(filepath.Join(t.TempDir(), "missing.json")); err == nil || errors.Is(err, cassette.ErrNoInteraction) {
+		t.Errorf("expected a read error, got %v", err)
+	}
+}
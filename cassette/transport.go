@@ -0,0 +1,173 @@
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// ErrNoInteraction is returned by a Replayer for a request that matches no unused recorded interaction
+var ErrNoInteraction = errors.New("cassette: no recorded interaction matches request")
+
+// Recorder is an http.RoundTripper that sends requests through Transport and records them
+type Recorder struct {
+	Transport http.RoundTripper
+	// Redactor masks sensitive values in recorded response bodies and headers
+	Redactor *util.Redactor
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func NewRecorder(transport http.RoundTripper, secrets ...string) *Recorder {
+	/*
+		Create a recorder sending requests through transport (http.DefaultTransport if nil). Every literal occurrence
+		of the given secrets (e.g. the API key) is masked in recorded responses.
+	*/
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{Transport: transport, Redactor: util.NewRedactor().WithSecrets(secrets...)}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	scrubbed, err := scrubRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: scrubbed,
+		Response: Response{
+			Status: res.StatusCode,
+			Header: r.Redactor.RedactHeader(res.Header),
+			Body:   r.Redactor.RedactString(string(body)),
+		},
+	})
+	return res, nil
+}
+
+func (r *Recorder) Cassette() *Cassette {
+	/*
+		Get a copy of the interactions recorded so far
+	*/
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+func (r *Recorder) Save(path string) error {
+	/*
+		Write the interactions recorded so far to a cassette file
+	*/
+	return r.Cassette().Save(path)
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette without sending them
+type Replayer struct {
+	// Matcher decides which recorded interaction answers a request; DefaultMatcher if nil
+	Matcher Matcher
+	// AllowRepeats lets an interaction answer more than one request once every match has been used
+	AllowRepeats bool
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{interactions: c.Interactions, used: make([]bool, len(c.Interactions))}
+}
+
+func LoadReplayer(path string) (*Replayer, error) {
+	/*
+		Create a replayer from a cassette file
+	*/
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(c), nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	/*
+		Answer with the first unused recorded interaction matching the request, in recording order
+	*/
+	actual, err := scrubRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	matcher := r.Matcher
+	if matcher == nil {
+		matcher = DefaultMatcher
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	repeat := -1
+	for i, interaction := range r.interactions {
+		if !matcher(interaction.Request, actual) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return interaction.Response.toHTTP(req), nil
+		}
+		repeat = i
+	}
+	if r.AllowRepeats && repeat >= 0 {
+		return r.interactions[repeat].Response.toHTTP(req), nil
+	}
+	return nil, fmt.Errorf("%w: %s %s %s", ErrNoInteraction, actual.Method, actual.URL, actual.Payload)
+}
+
+func (r *Replayer) Unused() []Interaction {
+	/*
+		Get the recorded interactions that have not answered a request yet
+	*/
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+func (res Response) toHTTP(req *http.Request) *http.Response {
+	header := res.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", res.Status, http.StatusText(res.Status)),
+		StatusCode:    res.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(res.Body))),
+		ContentLength: int64(len(res.Body)),
+		Request:       req,
+	}
+}
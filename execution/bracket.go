@@ -0,0 +1,392 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// Bracket states
+const (
+	// BracketPendingEntry: the entry buy is resting
+	BracketPendingEntry = "pending_entry"
+	// BracketOpen: the position is held and protected by the resting stop; StopOrderID is 0 while it is (re)placed
+	BracketOpen = "open"
+	// BracketTakeProfit and BracketStopped: the rest of the position was sold by the take-profit or by the stop
+	BracketTakeProfit = "take_profit"
+	BracketStopped    = "stopped"
+	// BracketCancelled: cancelled by Cancel, or the entry or the stop was cancelled outside the manager
+	BracketCancelled = "cancelled"
+	// BracketFailed: the stop could not be placed maxStopAttempts times in a row; the position is unprotected
+	BracketFailed = "failed"
+)
+
+// maxStopAttempts is how many times in a row placing the stop of a bracket may fail before it is given up
+const maxStopAttempts = 3
+
+// Bracket links an optional entry buy with a take-profit price and a protective stop-limit sell (an OCO pair).
+// It is persisted by its Manager; order ids are 0 until the order is placed.
+type Bracket struct {
+	ID     string  `json:"id"`
+	Symbol string  `json:"symbol"`
+	Amount float64 `json:"amount"`
+	// EntryPrice is 0 for a plain OCO on an existing position
+	EntryPrice      float64   `json:"entry_price,omitempty"`
+	TakeProfitPrice float64   `json:"take_profit_price"`
+	StopPrice       float64   `json:"stop_price"`
+	StopLimitPrice  float64   `json:"stop_limit_price"`
+	EntryOrderID    int       `json:"entry_order_id,omitempty"`
+	TakeProfitID    int       `json:"take_profit_order_id,omitempty"`
+	StopOrderID     int       `json:"stop_order_id,omitempty"`
+	State           string    `json:"state"`
+	ExitPrice       string    `json:"exit_price,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	// Sold is the amount sold so far by the stop and the take-profit
+	Sold float64 `json:"sold,omitempty"`
+	// StopFailures counts the failures to place the stop since it was last placed
+	StopFailures int `json:"stop_failures,omitempty"`
+}
+
+// Manager places and supervises OCO and bracket orders. The exchange holds funds for each resting order separately, so
+// only the protective stop-limit sell rests and the take-profit is watched client-side: when Poll (or Run) sees the
+// bid reach it, the stop is cancelled and the rest of the position sold with an immediate-or-cancel limit sell at the
+// take-profit price, and whatever doesn't fill gets a new stop. When the entry of a bracket fills, its stop is placed
+// for the executed amount. Brackets are persisted to a JSON file after every change, so a restarted manager picks up
+// where the previous one stopped.
+type Manager struct {
+	trader  private.Trader
+	path    string
+	options options
+
+	mu       sync.Mutex
+	brackets map[string]*Bracket
+}
+
+func NewManager(trader private.Trader, path string, opts ...Option) (*Manager, error) {
+	/*
+		Create a bracket manager, loading the brackets persisted at path if the file exists
+
+		Args:
+		trader (private.Trader): trader the entries, stops and take-profits are placed with
+		path (string): JSON file brackets are persisted to; empty keeps them in memory only
+		opts (...Option): optional behavior, e.g. WithLogger
+
+		Returns a pointer to the Manager, or an error if the file cannot be read
+	*/
+	m := &Manager{trader: trader, path: path, options: newOptions("bracket", opts), brackets: map[string]*Bracket{}}
+	if path == "" {
+		return m, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, errors.New("error reading brackets: " + err.Error())
+	}
+	var brackets []*Bracket
+	if err := json.Unmarshal(data, &brackets); err != nil {
+		return nil, errors.New("error parsing brackets: " + err.Error())
+	}
+	for _, bracket := range brackets {
+		m.brackets[bracket.ID] = bracket
+	}
+	return m, nil
+}
+
+func (m *Manager) PlaceOCO(symbol string, amount float64, takeProfitPrice float64, stopPrice float64, stopLimitPrice float64) (*Bracket, error) {
+	/*
+		Protect an existing position with a stop-limit sell, and sell it at takeProfitPrice instead once the bid reaches it
+
+		Args:
+		symbol (string): trading pair symbol, e.g. "btcusd"
+		amount (float64): base amount of the position
+		takeProfitPrice (float64): limit price of the take-profit sell, above the market
+		stopPrice, stopLimitPrice (float64): stop and limit prices of the protective sell, below the market; stopPrice > stopLimitPrice
+
+		Returns a copy of the Bracket, or an error if the stop cannot be placed
+	*/
+	bracket, err := m.newBracket(symbol, amount, 0, takeProfitPrice, stopPrice, stopLimitPrice)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.protect(bracket); err != nil {
+		return nil, err
+	}
+	m.brackets[bracket.ID] = bracket
+	copy := *bracket
+	return &copy, m.save()
+}
+
+func (m *Manager) PlaceBracket(symbol string, amount float64, entryPrice float64, takeProfitPrice float64, stopPrice float64, stopLimitPrice float64) (*Bracket, error) {
+	/*
+		Enter a position with a limit buy at entryPrice; once it fills, protect the executed amount as PlaceOCO does
+
+		Once the entry is placed, the bracket is returned and saved even with an error, e.g. if the entry filled at once
+		and its stop failed; Poll retries it
+	*/
+	bracket, err := m.newBracket(symbol, amount, entryPrice, takeProfitPrice, stopPrice, stopLimitPrice)
+	if err != nil {
+		return nil, err
+	}
+	if entryPrice <= stopPrice || entryPrice >= takeProfitPrice {
+		return nil, errors.New("bracket: entry price must be between the stop and take-profit prices")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, err := m.trader.LimitBuy(symbol, amount, entryPrice)
+	if err != nil {
+		return nil, fmt.Errorf("bracket entry failed: %w", err)
+	}
+	if bracket.EntryOrderID, err = orderID(order); err != nil {
+		return nil, errors.New("bracket entry has non-numeric id " + order.OrderID)
+	}
+	bracket.State = BracketPendingEntry
+	m.brackets[bracket.ID] = bracket
+	m.options.logger.Info("bracket entry placed", "bracket_id", bracket.ID, "order_id", bracket.EntryOrderID, "price", entryPrice)
+	// the entry is live, so the bracket is saved even if its stop can't be placed yet; Poll retries it
+	err = m.advance(bracket)
+	copy := *bracket
+	return &copy, errors.Join(err, m.save())
+}
+
+func (m *Manager) newBracket(symbol string, amount float64, entryPrice float64, takeProfitPrice float64, stopPrice float64, stopLimitPrice float64) (*Bracket, error) {
+	if symbol == "" || amount <= 0 {
+		return nil, errors.New("bracket: symbol and a positive amount are required")
+	}
+	if stopLimitPrice <= 0 || stopPrice <= stopLimitPrice || takeProfitPrice <= stopPrice {
+		return nil, errors.New("bracket: prices must satisfy 0 < stop limit < stop < take profit")
+	}
+	now := m.options.now()
+	return &Bracket{
+		ID: util.GenerateUUID(), Symbol: symbol, Amount: amount, EntryPrice: entryPrice,
+		TakeProfitPrice: takeProfitPrice, StopPrice: stopPrice, StopLimitPrice: stopLimitPrice,
+		CreatedAt: now, UpdatedAt: now,
+	}, nil
+}
+
+// protect places the stop for the rest of the position, giving the bracket up once it failed maxStopAttempts times
+// in a row
+func (m *Manager) protect(bracket *Bracket) error {
+	bracket.UpdatedAt = m.options.now()
+	stop, err := m.trader.StopLimitSell(bracket.Symbol, bracket.remaining(), bracket.StopPrice, bracket.StopLimitPrice)
+	if err == nil {
+		bracket.StopOrderID, err = orderID(stop)
+	}
+	if err != nil {
+		bracket.StopFailures++
+		if bracket.StopFailures >= maxStopAttempts {
+			bracket.State = BracketFailed
+			m.options.logger.Error("bracket stop failed, giving up; the position is unprotected", "bracket_id", bracket.ID, "attempts", bracket.StopFailures, "error", err)
+		}
+		return fmt.Errorf("bracket stop failed: %w", err)
+	}
+	bracket.StopFailures = 0
+	bracket.State = BracketOpen
+	m.options.logger.Info("bracket stop placed", "bracket_id", bracket.ID, "stop_order_id", bracket.StopOrderID, "amount", bracket.remaining())
+	return nil
+}
+
+// remaining is the amount of the position not sold yet
+func (b *Bracket) remaining() float64 {
+	return b.Amount - b.Sold
+}
+
+func (m *Manager) Poll() error {
+	/*
+		Check every active bracket once: protect filled entries, take profit once the bid reaches the take-profit price and
+		follow fills of the stop
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for _, bracket := range m.brackets {
+		if err := m.advance(bracket); err != nil {
+			errs = append(errs, fmt.Errorf("bracket %s: %w", bracket.ID, err))
+		}
+	}
+	if err := m.save(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) advance(bracket *Bracket) error {
+	switch bracket.State {
+	case BracketPendingEntry:
+		entry, err := m.trader.GetOrderStatus(bracket.EntryOrderID)
+		if err != nil {
+			return err
+		}
+		if entry.IsLive {
+			return nil
+		}
+		executed := executedAmount(entry)
+		if executed == 0 {
+			bracket.State = BracketCancelled
+			bracket.UpdatedAt = m.options.now()
+			m.options.logger.Warn("bracket entry cancelled", "bracket_id", bracket.ID, "order_id", bracket.EntryOrderID)
+			return nil
+		}
+		bracket.Amount, bracket.State = executed, BracketOpen
+		return m.protect(bracket)
+
+	case BracketOpen:
+		if bracket.StopOrderID == 0 {
+			return m.protect(bracket)
+		}
+		stop, err := m.trader.GetOrderStatus(bracket.StopOrderID)
+		if err != nil {
+			return err
+		}
+		if !stop.IsLive {
+			executed := executedAmount(stop)
+			bracket.Sold += executed
+			bracket.StopOrderID = 0
+			switch {
+			case bracket.remaining() <= 1e-9:
+				m.finish(bracket, BracketStopped, stop.AvgExecutionPrice)
+				return nil
+			case executed == 0:
+				bracket.State = BracketCancelled
+				bracket.UpdatedAt = m.options.now()
+				m.options.logger.Warn("bracket stop cancelled", "bracket_id", bracket.ID, "order_id", stop.OrderID)
+				return nil
+			}
+			m.options.logger.Warn("bracket stop partially filled and closed, protecting the rest", "bracket_id", bracket.ID, "sold", bracket.Sold)
+			return m.protect(bracket)
+		}
+		bid, _, err := m.options.quote(bracket.Symbol)
+		if err != nil {
+			return err
+		}
+		if bid >= bracket.TakeProfitPrice {
+			return m.takeProfit(bracket)
+		}
+	}
+	return nil
+}
+
+// takeProfit cancels the stop and sells the rest of the position at the take-profit price, immediate-or-cancel;
+// whatever doesn't fill is protected by a new stop
+func (m *Manager) takeProfit(bracket *Bracket) error {
+	stop, err := m.trader.CancelOrder(bracket.StopOrderID)
+	if err != nil {
+		return fmt.Errorf("cancelling the stop failed: %w", err)
+	}
+	bracket.Sold += executedAmount(stop)
+	bracket.StopOrderID = 0
+	if bracket.remaining() <= 1e-9 {
+		m.finish(bracket, BracketStopped, stop.AvgExecutionPrice)
+		return nil
+	}
+	order, err := m.trader.LimitSell(bracket.Symbol, bracket.remaining(), bracket.TakeProfitPrice, "immediate-or-cancel")
+	if err != nil {
+		return errors.Join(fmt.Errorf("bracket take-profit failed: %w", err), m.protect(bracket))
+	}
+	bracket.TakeProfitID, _ = orderID(order)
+	bracket.Sold += executedAmount(order)
+	if bracket.remaining() <= 1e-9 {
+		m.finish(bracket, BracketTakeProfit, order.AvgExecutionPrice)
+		return nil
+	}
+	m.options.logger.Info("bracket take-profit partially filled, protecting the rest", "bracket_id", bracket.ID, "sold", bracket.Sold)
+	return m.protect(bracket)
+}
+
+// finish closes a bracket whose position has been sold
+func (m *Manager) finish(bracket *Bracket, state string, exitPrice string) {
+	bracket.State = state
+	bracket.ExitPrice = exitPrice
+	bracket.UpdatedAt = m.options.now()
+	m.options.logger.Info("bracket closed", "bracket_id", bracket.ID, "state", state, "exit_price", exitPrice)
+}
+
+func (m *Manager) Cancel(id string) error {
+	/*
+		Cancel every resting order of a bracket
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bracket, ok := m.brackets[id]
+	if !ok {
+		return errors.New("bracket " + id + " not found")
+	}
+	var ids []int
+	switch bracket.State {
+	case BracketPendingEntry:
+		ids = []int{bracket.EntryOrderID}
+	case BracketOpen:
+		ids = []int{bracket.StopOrderID}
+	default:
+		return nil
+	}
+	for _, id := range ids {
+		if id == 0 {
+			continue
+		}
+		if _, err := m.trader.CancelOrder(id); err != nil {
+			return err
+		}
+	}
+	bracket.State = BracketCancelled
+	bracket.UpdatedAt = m.options.now()
+	return m.save()
+}
+
+func (m *Manager) Brackets() []Bracket {
+	/*
+		Get copies of all brackets, oldest first
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sorted()
+}
+
+func (m *Manager) sorted() []Bracket {
+	brackets := make([]Bracket, 0, len(m.brackets))
+	for _, bracket := range m.brackets {
+		brackets = append(brackets, *bracket)
+	}
+	sort.Slice(brackets, func(i, j int) bool { return brackets[i].CreatedAt.Before(brackets[j].CreatedAt) })
+	return brackets
+}
+
+func (m *Manager) Run(ctx context.Context, interval time.Duration) error {
+	/*
+		Poll every interval until the context is done
+	*/
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Poll(); err != nil {
+			m.options.logger.Error("bracket poll failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) save() error {
+	if m.path == "" {
+		return nil
+	}
+	if err := saveJSON(m.path, m.sorted()); err != nil {
+		return errors.New("error writing brackets: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package execution
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/papertest"
+)
+
+func TestBracketTakeProfit(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	// only the stop rests, so the entry fill is all the bracket needs
+	trader := paper.NewTrader(market, map[string]float64{"USD": 1000}, paper.WithFees(0, 0))
+	path := filepath.Join(t.TempDir(), "brackets.json")
+	manager, err := NewManager(trader, path, WithQuotes(quotes(market)))
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if _, err := manager.PlaceBracket("btcusd", 1, 101, 99, 95, 94); err == nil {
+		t.Errorf("an entry outside the stop and take-profit prices should be rejected")
+	}
+	bracket, err := manager.PlaceBracket("btcusd", 1, 99, 110, 95, 94)
+	if err != nil {
+		t.Fatalf("PlaceBracket failed: %v", err)
+	}
+	if bracket.State != BracketPendingEntry {
+		t.Fatalf("entry below the ask should rest: %+v", bracket)
+	}
+
+	// the entry fills, then the stop is placed for the executed amount
+	market.Set(98.5, 98.9)
+	trader.Refresh()
+	if err := manager.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	bracket = &manager.Brackets()[0]
+	if bracket.State != BracketOpen || bracket.Amount != 1 || bracket.StopOrderID == 0 {
+		t.Fatalf("the stop should be placed after the entry filled: %+v", bracket)
+	}
+	if btc, available := balance(t, trader, "BTC"); btc != 1 || available != 0 {
+		t.Errorf("BTC %v (available %v) should be held by the stop", btc, available)
+	}
+
+	// a restarted manager resumes from the file; the bid reaches the take-profit, which cancels the stop and sells
+	manager, err = NewManager(trader, path, WithQuotes(quotes(market)))
+	if err != nil {
+		t.Fatalf("reloading failed: %v", err)
+	}
+	market.Set(110, 110.5)
+	trader.Refresh()
+	if err := manager.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	bracket = &manager.Brackets()[0]
+	if bracket.State != BracketTakeProfit || bracket.ExitPrice != "110" || bracket.TakeProfitID == 0 {
+		t.Errorf("take-profit should have closed the bracket: %+v", bracket)
+	}
+	if stop, _ := trader.GetOrderStatus(bracket.StopOrderID); stop != nil && !stop.IsCancelled {
+		t.Errorf("stop should be cancelled: %+v", stop)
+	}
+	if btc, _ := balance(t, trader, "BTC"); btc != 0 {
+		t.Errorf("BTC %v after take-profit", btc)
+	}
+}
+
+func TestOCOStop(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	trader := paper.NewTrader(market, map[string]float64{"BTC": 2}, paper.WithFees(0, 0))
+	manager, _ := NewManager(trader, "", WithQuotes(quotes(market)))
+	if _, err := manager.PlaceOCO("btcusd", 1, 110, 95, 94); err != nil {
+		t.Fatalf("PlaceOCO failed: %v", err)
+	}
+	market.Set(94.5, 95)
+	trader.Refresh()
+	if err := manager.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	bracket := manager.Brackets()[0]
+	if bracket.State != BracketStopped || bracket.ExitPrice != "94.5" || bracket.TakeProfitID != 0 {
+		t.Errorf("stop should have closed the OCO: %+v", bracket)
+	}
+
+	// a position that cannot be funded is not protected
+	market.Set(100, 100.5)
+	trader.Refresh()
+	if _, err := manager.PlaceOCO("btcusd", 1.5, 110, 95, 94); err == nil {
+		t.Errorf("the stop should fail without funds")
+	}
+	other, _ := manager.PlaceOCO("btcusd", 1, 110, 95, 94)
+	if _, available := balance(t, trader, "BTC"); available != 0 {
+		t.Errorf("the stop should hold the position, %v BTC available", available)
+	}
+	if err := manager.Cancel(other.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if _, available := balance(t, trader, "BTC"); available != 1 {
+		t.Errorf("cancel should release the stop, %v BTC available", available)
+	}
+	if _, err := manager.PlaceOCO("btcusd", 1, 90, 95, 94); err == nil {
+		t.Errorf("a take-profit below the stop should be rejected")
+	}
+}
+
+func TestOCOPartialTakeProfit(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	trader := &faultyTrader{Trader: paper.NewTrader(market, map[string]float64{"BTC": 1}, paper.WithFees(0, 0)), sellDepth: 0.4}
+	manager, _ := NewManager(trader, "", WithQuotes(quotes(market)))
+	oco, err := manager.PlaceOCO("btcusd", 1, 110, 95, 94)
+	if err != nil {
+		t.Fatalf("PlaceOCO failed: %v", err)
+	}
+
+	// only part of the take-profit fills, so the rest gets a new stop
+	market.Set(110, 110.5)
+	if err := manager.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	bracket := manager.Brackets()[0]
+	if bracket.State != BracketOpen || !papertest.Near(bracket.Sold, 0.4) || bracket.StopOrderID == 0 || bracket.StopOrderID == oco.StopOrderID {
+		t.Fatalf("the rest should be protected after a partial take-profit: %+v", bracket)
+	}
+	if stop, _ := trader.GetOrderStatus(bracket.StopOrderID); stop.OriginalAmount != "0.6" || !stop.IsLive {
+		t.Errorf("stop for the rest %+v", stop)
+	}
+
+	// the next poll sells the rest
+	trader.sellDepth = 0
+	if err := manager.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if bracket := manager.Brackets()[0]; bracket.State != BracketTakeProfit || !papertest.Near(bracket.Sold, 1) {
+		t.Errorf("the take-profit should have sold the rest: %+v", bracket)
+	}
+	if btc, available := balance(t, trader, "BTC"); btc > 1e-9 || available > 1e-9 {
+		t.Errorf("BTC %v (available %v) after take-profit", btc, available)
+	}
+}
+
+func TestBracketStopFailures(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	trader := &faultyTrader{Trader: paper.NewTrader(market, map[string]float64{"USD": 1000}, paper.WithFees(0, 0)), failStops: true}
+	path := filepath.Join(t.TempDir(), "brackets.json")
+	manager, err := NewManager(trader, path, WithQuotes(quotes(market)))
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	// the entry crosses and fills at once, but the stop fails
+	bracket, err := manager.PlaceBracket("btcusd", 1, 101, 110, 95, 94)
+	if err == nil || bracket == nil || bracket.State != BracketOpen || bracket.StopOrderID != 0 || bracket.EntryOrderID == 0 {
+		t.Fatalf("PlaceBracket returned %+v, %v", bracket, err)
+	}
+
+	// a restarted manager still knows the entry and places the stop once it can
+	manager, err = NewManager(trader, path, WithQuotes(quotes(market)))
+	if err != nil {
+		t.Fatalf("reloading failed: %v", err)
+	}
+	if brackets := manager.Brackets(); len(brackets) != 1 || brackets[0].EntryOrderID != bracket.EntryOrderID {
+		t.Fatalf("bracket was not saved: %+v", brackets)
+	}
+	trader.failStops = false
+	if err := manager.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if bracket := manager.Brackets()[0]; bracket.State != BracketOpen || bracket.StopOrderID == 0 || bracket.StopFailures != 0 {
+		t.Errorf("the stop should be placed after the restart: %+v", bracket)
+	}
+
+	// a stop that keeps failing is given up instead of retried forever
+	trader.failStops = true
+	other, _ := manager.PlaceBracket("btcusd", 1, 101, 110, 95, 94)
+	for i := 1; i < maxStopAttempts; i++ {
+		if err := manager.Poll(); err == nil {
+			t.Fatal("expected the stop failure to be reported")
+		}
+	}
+	attempts := trader.stopAttempts
+	if err := manager.Poll(); err != nil {
+		t.Errorf("a failed bracket should not be retried: %v", err)
+	}
+	for _, bracket := range manager.Brackets() {
+		if bracket.ID == other.ID && (bracket.State != BracketFailed || bracket.StopFailures != maxStopAttempts) {
+			t.Errorf("bracket should have failed: %+v", bracket)
+		}
+	}
+	if trader.stopAttempts != attempts {
+		t.Errorf("the stop was retried after the bracket failed")
+	}
+}
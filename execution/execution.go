@@ -0,0 +1,101 @@
+// Package execution manages orders client-side on top of a private.Trader: trailing stops, one-cancels-other and
+// bracket orders, and TWAP and iceberg order slicing, none of which the exchange offers natively.
+package execution
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// Option configures optional behavior of an order manager
+type Option func(*options)
+
+type options struct {
+	now    func() time.Time
+	logger *slog.Logger
+	quote  QuoteFunc
+}
+
+// QuoteFunc returns the best bid and ask of a symbol
+type QuoteFunc func(symbol string) (bid float64, ask float64, err error)
+
+func WithClock(now func() time.Time) Option {
+	/*
+		Use the given clock instead of time.Now
+	*/
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	/*
+		Log through the given logger instead of util.Logger()
+	*/
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func WithQuotes(quote QuoteFunc) Option {
+	/*
+		Read the best bid and ask from quote instead of polling the v2 ticker
+	*/
+	return func(o *options) {
+		o.quote = quote
+	}
+}
+
+func newOptions(name string, opts []Option) options {
+	o := options{now: time.Now, quote: tickerQuote}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = util.Logger()
+	}
+	o.logger = o.logger.With("execution", name)
+	return o
+}
+
+// tickerQuote reads the best bid and ask from the symbol's v2 ticker
+func tickerQuote(symbol string) (float64, float64, error) {
+	var ticker public.TickerV2
+	if err := public.GetPublicEndpoint("/v2/ticker/"+symbol, &ticker); err != nil {
+		return 0, 0, err
+	}
+	bid, err := strconv.ParseFloat(ticker.Bid, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid bid in ticker: " + ticker.Bid)
+	}
+	ask, err := strconv.ParseFloat(ticker.Ask, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid ask in ticker: " + ticker.Ask)
+	}
+	return bid, ask, nil
+}
+
+func orderID(order *private.Order) (int, error) {
+	return strconv.Atoi(order.OrderID)
+}
+
+func executedAmount(order *private.Order) float64 {
+	executed, _ := strconv.ParseFloat(order.ExecutedAmount, 64)
+	return executed
+}
+
+// saveJSON writes value to path atomically, as indented JSON
+func saveJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, data, 0o600)
+}
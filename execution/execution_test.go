@@ -0,0 +1,229 @@
+package execution
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/papertest"
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+// clock is a manually advanced clock
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func balance(t *testing.T, trader private.Trader, currency string) (float64, float64) {
+	t.Helper()
+	balances, err := trader.GetAvailableBalances()
+	if err != nil {
+		t.Fatalf("GetAvailableBalances failed: %v", err)
+	}
+	for _, b := range balances {
+		if b.Currency == currency {
+			amount, _ := strconv.ParseFloat(b.Amount, 64)
+			available, _ := strconv.ParseFloat(b.Available, 64)
+			return amount, available
+		}
+	}
+	return 0, 0
+}
+
+func TestTrailingStop(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	trader := paper.NewTrader(market, map[string]float64{"BTC": 1}, paper.WithFees(0, 0))
+	c := newClock()
+	stop, err := NewTrailingStop(trader, TrailingStopConfig{
+		Symbol: "btcusd", Amount: 1, TrailPercent: 0.05, LimitOffset: 0.005, MinRepriceInterval: 30 * time.Second,
+	}, WithClock(c.Now))
+	if err != nil {
+		t.Fatalf("NewTrailingStop failed: %v", err)
+	}
+	update := func(bid float64) {
+		t.Helper()
+		market.Set(bid, bid+0.5)
+		if err := stop.Update(bid); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	update(100)
+	status := stop.Status()
+	if status.StopPrice != 95 || status.LimitPrice != 94.52 || status.Order.Type != "exchange stop limit" {
+		t.Fatalf("initial stop %+v", status)
+	}
+
+	// the high-water mark rises at once, the order only after the minimum re-price interval
+	c.Advance(10 * time.Second)
+	update(110)
+	if status := stop.Status(); status.HighWaterMark != 110 || status.StopPrice != 95 {
+		t.Errorf("stop should not be re-priced within the interval: %+v", status)
+	}
+	first := status.OrderID
+	c.Advance(30 * time.Second)
+	update(108)
+	status = stop.Status()
+	if status.StopPrice != 104.5 || status.OrderID == first {
+		t.Errorf("stop should trail the high-water mark of 110: %+v", status)
+	}
+	if cancelled, _ := trader.GetOrderStatus(first); !cancelled.IsCancelled {
+		t.Errorf("previous stop order should be cancelled: %+v", cancelled)
+	}
+
+	// the stop never moves down, and fills when the price falls through it
+	c.Advance(time.Minute)
+	update(106)
+	if stop.Status().StopPrice != 104.5 {
+		t.Errorf("stop moved down: %+v", stop.Status())
+	}
+	update(104)
+	update(104)
+	status = stop.Status()
+	if status.State != TrailingFilled || status.Order.AvgExecutionPrice != "104" {
+		t.Errorf("stop should have filled into the bid: %+v", status)
+	}
+	if usd, _ := balance(t, trader, "USD"); usd != 104 {
+		t.Errorf("USD balance %v after stop", usd)
+	}
+}
+
+// faultyTrader fails stop-limit placements, reports partial fills of cancelled orders and fills immediate-or-cancel
+// sells partially on demand
+type faultyTrader struct {
+	private.Trader
+	failStops      bool
+	stopAttempts   int
+	cancelExecuted string
+	// statusExecuted is reported once as the executed amount of the next order whose status is read
+	statusExecuted string
+	// sellDepth caps the amount an immediate-or-cancel sell fills when positive
+	sellDepth float64
+}
+
+func (f *faultyTrader) StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	f.stopAttempts++
+	if f.failStops {
+		return nil, errors.New("503 Service Unavailable")
+	}
+	return f.Trader.StopLimitSell(symbol, amount, stopPrice, limitPrice)
+}
+
+func (f *faultyTrader) LimitSell(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	if f.sellDepth <= 0 || amount <= f.sellDepth {
+		return f.Trader.LimitSell(symbol, amount, limitPrice, options...)
+	}
+	order, err := f.Trader.LimitSell(symbol, f.sellDepth, limitPrice, options...)
+	if err == nil {
+		order.OriginalAmount, order.IsCancelled = strconv.FormatFloat(amount, 'f', -1, 64), true
+	}
+	return order, err
+}
+
+func (f *faultyTrader) GetOrderStatus(orderID int) (*private.Order, error) {
+	order, err := f.Trader.GetOrderStatus(orderID)
+	if err == nil && f.statusExecuted != "" {
+		order.ExecutedAmount, order.AvgExecutionPrice = f.statusExecuted, order.Price
+		f.statusExecuted = ""
+	}
+	return order, err
+}
+
+func (f *faultyTrader) CancelOrder(orderID int) (*private.Order, error) {
+	order, err := f.Trader.CancelOrder(orderID)
+	if err == nil && f.cancelExecuted != "" {
+		order.ExecutedAmount, order.AvgExecutionPrice = f.cancelExecuted, order.Price
+		f.cancelExecuted = ""
+	}
+	return order, err
+}
+
+func TestTrailingStopReplacement(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	trader := &faultyTrader{Trader: paper.NewTrader(market, map[string]float64{"BTC": 1}, paper.WithFees(0, 0))}
+	c := newClock()
+	stop, err := NewTrailingStop(trader, TrailingStopConfig{Symbol: "btcusd", Amount: 1, TrailPercent: 0.05, LimitOffset: 0.005}, WithClock(c.Now))
+	if err != nil {
+		t.Fatalf("NewTrailingStop failed: %v", err)
+	}
+	if err := stop.Update(100); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// a failed re-placement leaves no order, and the next update places one instead of giving up
+	trader.failStops = true
+	if err := stop.Update(110); err == nil {
+		t.Fatal("expected the failed placement to be reported")
+	}
+	if status := stop.Status(); status.State != TrailingActive || status.OrderID != 0 {
+		t.Fatalf("status after failed placement %+v", status)
+	}
+	trader.failStops = false
+	if err := stop.Update(110); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if status := stop.Status(); status.State != TrailingActive || status.OrderID == 0 || status.StopPrice != 104.5 {
+		t.Fatalf("stop should be placed again: %+v", status)
+	}
+
+	// a stop that partially filled before it was moved is re-placed for the remainder
+	trader.cancelExecuted = "0.4"
+	if err := stop.Update(120); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	status := stop.Status()
+	if status.State != TrailingActive || status.Sold != 0.4 || status.StopPrice != 114 || status.Order.OriginalAmount != "0.6" {
+		t.Errorf("stop should cover the remaining 0.6: %+v %+v", status, status.Order)
+	}
+
+	// a stop cancelled elsewhere after it partially filled is not taken for filled; the rest gets a new stop
+	cancelled := status.OrderID
+	trader.Trader.CancelOrder(cancelled)
+	trader.statusExecuted = "0.2"
+	if err := stop.Update(120); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	status = stop.Status()
+	if amount, _ := strconv.ParseFloat(status.Order.OriginalAmount, 64); status.State != TrailingActive || !papertest.Near(status.Sold, 0.6) || status.OrderID == cancelled || !papertest.Near(amount, 0.4) {
+		t.Errorf("stop should cover the remaining 0.4: %+v %+v", status, status.Order)
+	}
+}
+
+func TestTrailingStopCancel(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	trader := paper.NewTrader(market, map[string]float64{"BTC": 1})
+	stop, _ := NewTrailingStop(trader, TrailingStopConfig{Symbol: "btcusd", Amount: 1, TrailPercent: 0.1, LimitOffset: 0.01})
+	stop.Update(100)
+	if err := stop.Cancel(); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if status := stop.Status(); status.State != TrailingCancelled || !status.Order.IsCancelled {
+		t.Errorf("status after cancel %+v", status)
+	}
+	if _, available := balance(t, trader, "BTC"); available != 1 {
+		t.Errorf("cancel should release the BTC, %v available", available)
+	}
+	if _, err := NewTrailingStop(trader, TrailingStopConfig{Symbol: "btcusd", Amount: 1, TrailPercent: 0.1}); err == nil {
+		t.Errorf("a zero limit offset should be rejected")
+	}
+}
@@ -0,0 +1,341 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// SliceConfig configures a TWAP or iceberg execution of a parent order
+type SliceConfig struct {
+	Symbol string
+	// Side is "buy" or "sell"
+	Side string
+	// Amount is the base currency amount of the parent order
+	Amount float64
+	// LimitPrice is the worst price child orders may be placed at: the highest for buys, the lowest for sells; 0 for none
+	LimitPrice float64
+	// Duration and Slices configure a TWAP: Slices equal child orders, one released every Duration/Slices
+	Duration time.Duration
+	Slices   int
+	// VisibleAmount configures an iceberg: at most this amount rests on the book at a time
+	VisibleAmount float64
+	// CrossSpread places child orders at the far side of the book (buys at the ask) instead of joining the near side
+	CrossSpread bool
+	// RepriceAfter is how long a child order may rest away from the best price before it is re-placed (default 30s)
+	RepriceAfter time.Duration
+	// PriceIncrement and AmountIncrement round child prices and amounts (default 0.01 and 1e-8)
+	PriceIncrement  float64
+	AmountIncrement float64
+	// MinOrderSize is the smallest child order the exchange accepts; a smaller remainder completes the parent
+	MinOrderSize float64
+}
+
+// Slicer states
+const (
+	SlicerRunning   = "running"
+	SlicerPaused    = "paused"
+	SlicerCompleted = "completed"
+	SlicerCancelled = "cancelled"
+)
+
+// SlicerProgress is a snapshot of a parent order's execution
+type SlicerProgress struct {
+	State    string
+	Amount   float64
+	Filled   float64
+	AvgPrice float64
+	// Children counts the child orders placed so far
+	Children int
+	// OpenOrderID is the resting child order, or 0
+	OpenOrderID int
+}
+
+// Slicer executes a parent order as a sequence of child limit orders, one resting at a time. A TWAP releases the
+// parent amount in equal slices over its duration; an iceberg keeps only its visible amount on the book. Each Step
+// re-prices a resting child that has fallen behind the best price for longer than RepriceAfter.
+type Slicer struct {
+	trader  private.Trader
+	config  SliceConfig
+	kind    string
+	options options
+
+	mu        sync.Mutex
+	state     string
+	started   time.Time
+	pausedAt  time.Time
+	paused    time.Duration
+	filled    float64
+	notional  float64
+	children  int
+	child     int
+	childAt   time.Time
+	childSeen float64
+	childAvg  float64
+}
+
+func NewTWAP(trader private.Trader, config SliceConfig, opts ...Option) (*Slicer, error) {
+	/*
+		Create a TWAP execution releasing config.Amount in config.Slices equal child orders over config.Duration.
+		Call Step periodically, or Run, to place and re-price the child orders
+	*/
+	if config.Duration <= 0 || config.Slices <= 0 {
+		return nil, errors.New("twap: a positive duration and number of slices are required")
+	}
+	if config.Duration/time.Duration(config.Slices) <= 0 {
+		return nil, errors.New("twap: duration is too short for " + strconv.Itoa(config.Slices) + " slices")
+	}
+	return newSlicer(trader, config, "twap", opts)
+}
+
+func NewIceberg(trader private.Trader, config SliceConfig, opts ...Option) (*Slicer, error) {
+	/*
+		Create an iceberg execution showing at most config.VisibleAmount of config.Amount on the book at a time.
+		Call Step periodically, or Run, to place and re-price the child orders
+	*/
+	if config.VisibleAmount <= 0 {
+		return nil, errors.New("iceberg: a positive visible amount is required")
+	}
+	return newSlicer(trader, config, "iceberg", opts)
+}
+
+func newSlicer(trader private.Trader, config SliceConfig, name string, opts []Option) (*Slicer, error) {
+	if config.Symbol == "" || config.Amount <= 0 {
+		return nil, errors.New(name + ": symbol and a positive amount are required")
+	}
+	if config.Side != "buy" && config.Side != "sell" {
+		return nil, errors.New(name + ": side must be buy or sell, got " + config.Side)
+	}
+	if config.RepriceAfter <= 0 {
+		config.RepriceAfter = 30 * time.Second
+	}
+	if config.PriceIncrement <= 0 {
+		config.PriceIncrement = 0.01
+	}
+	if config.AmountIncrement <= 0 {
+		config.AmountIncrement = 1e-8
+	}
+	s := &Slicer{trader: trader, config: config, kind: name, options: newOptions(name, opts), state: SlicerRunning}
+	s.started = s.options.now()
+	return s, nil
+}
+
+func (s *Slicer) Progress() SlicerProgress {
+	/*
+		Get a snapshot of the execution
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.progress()
+}
+
+func (s *Slicer) progress() SlicerProgress {
+	// the resting child's fills so far count towards both the filled amount and its average price
+	progress := SlicerProgress{
+		State: s.state, Amount: s.config.Amount, Filled: s.filled + s.childSeen, Children: s.children, OpenOrderID: s.child,
+	}
+	if progress.Filled > 0 {
+		progress.AvgPrice = (s.notional + s.childSeen*s.childAvg) / progress.Filled
+	}
+	return progress
+}
+
+func (s *Slicer) Step() error {
+	/*
+		Check the resting child order, re-price it if the book moved away, and place the next child when one is due
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != SlicerRunning {
+		return nil
+	}
+	bid, ask, err := s.options.quote(s.config.Symbol)
+	if err != nil {
+		return fmt.Errorf("quote failed: %w", err)
+	}
+	price, ok := s.price(bid, ask)
+
+	if s.child != 0 {
+		order, err := s.trader.GetOrderStatus(s.child)
+		if err != nil {
+			return err
+		}
+		if order.IsLive {
+			s.childSeen = executedAmount(order)
+			s.childAvg, _ = strconv.ParseFloat(order.AvgExecutionPrice, 64)
+			current, _ := strconv.ParseFloat(order.Price, 64)
+			if !ok || current == price || s.options.now().Sub(s.childAt) < s.config.RepriceAfter {
+				return nil
+			}
+			if order, err = s.trader.CancelOrder(s.child); err != nil {
+				return fmt.Errorf("cancelling child for re-pricing failed: %w", err)
+			}
+			s.options.logger.Info("re-pricing child order", "order_id", s.child, "price", current, "new_price", price)
+		}
+		s.settle(order)
+	}
+
+	remaining := s.config.Amount - s.filled
+	if remaining < s.config.MinOrderSize || remaining < s.config.AmountIncrement {
+		s.state = SlicerCompleted
+		s.options.logger.Info("parent order completed", "filled", s.filled, "children", s.children)
+		return nil
+	}
+	amount := util.FloorTo(math.Min(s.released()-s.filled, remaining), s.config.AmountIncrement)
+	if amount <= 0 || amount < s.config.MinOrderSize || !ok {
+		return nil
+	}
+	var order *private.Order
+	if s.config.Side == "buy" {
+		order, err = s.trader.LimitBuy(s.config.Symbol, amount, price)
+	} else {
+		order, err = s.trader.LimitSell(s.config.Symbol, amount, price)
+	}
+	if err != nil {
+		return fmt.Errorf("placing child order failed: %w", err)
+	}
+	id, err := orderID(order)
+	if err != nil {
+		return errors.New("child order has non-numeric id " + order.OrderID)
+	}
+	s.children++
+	s.child, s.childAt, s.childSeen, s.childAvg = id, s.options.now(), 0, 0
+	s.options.logger.Info("child order placed", "order_id", id, "side", s.config.Side, "amount", amount, "price", price)
+	if !order.IsLive {
+		s.settle(order)
+	}
+	return nil
+}
+
+// released is the cumulative amount the schedule allows to have been placed by now
+func (s *Slicer) released() float64 {
+	if s.kind == "iceberg" {
+		return math.Min(s.filled+s.config.VisibleAmount, s.config.Amount)
+	}
+	interval := s.config.Duration / time.Duration(s.config.Slices)
+	due := int(s.options.now().Sub(s.started)-s.paused)/int(interval) + 1
+	if due >= s.config.Slices {
+		return s.config.Amount
+	}
+	return s.config.Amount * float64(due) / float64(s.config.Slices)
+}
+
+// price is the child order price for the current book, capped at LimitPrice; false if the book is empty
+func (s *Slicer) price(bid float64, ask float64) (float64, bool) {
+	// buys join the bid and sells the ask, unless crossing the spread
+	price := bid
+	if (s.config.Side == "buy" && s.config.CrossSpread) || (s.config.Side == "sell" && !s.config.CrossSpread) {
+		price = ask
+	}
+	if s.config.Side == "buy" {
+		price = util.FloorTo(price, s.config.PriceIncrement)
+		if s.config.LimitPrice > 0 && price > s.config.LimitPrice {
+			price = s.config.LimitPrice
+		}
+	} else {
+		price = -util.FloorTo(-price, s.config.PriceIncrement)
+		if s.config.LimitPrice > 0 && price < s.config.LimitPrice {
+			price = s.config.LimitPrice
+		}
+	}
+	return price, price > 0
+}
+
+// settle accounts for a child order that is no longer live
+func (s *Slicer) settle(order *private.Order) {
+	executed := executedAmount(order)
+	avg, _ := strconv.ParseFloat(order.AvgExecutionPrice, 64)
+	s.filled += executed
+	s.notional += executed * avg
+	s.child, s.childSeen, s.childAvg = 0, 0, 0
+}
+
+// cancelChild cancels the resting child order, if any
+func (s *Slicer) cancelChild() error {
+	if s.child == 0 {
+		return nil
+	}
+	order, err := s.trader.CancelOrder(s.child)
+	if err != nil {
+		return fmt.Errorf("cancelling child order failed: %w", err)
+	}
+	s.settle(order)
+	return nil
+}
+
+func (s *Slicer) Pause() error {
+	/*
+		Cancel the resting child order and stop placing new ones until Resume. A TWAP schedule is shifted by the pause
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != SlicerRunning {
+		return nil
+	}
+	if err := s.cancelChild(); err != nil {
+		return err
+	}
+	s.state, s.pausedAt = SlicerPaused, s.options.now()
+	return nil
+}
+
+func (s *Slicer) Resume() {
+	/*
+		Resume a paused execution
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == SlicerPaused {
+		s.paused += s.options.now().Sub(s.pausedAt)
+		s.state = SlicerRunning
+	}
+}
+
+func (s *Slicer) Cancel() error {
+	/*
+		Cancel the resting child order and the rest of the parent order
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == SlicerCompleted || s.state == SlicerCancelled {
+		return nil
+	}
+	if err := s.cancelChild(); err != nil {
+		return err
+	}
+	s.state = SlicerCancelled
+	s.options.logger.Info("parent order cancelled", "filled", s.filled, "children", s.children)
+	return nil
+}
+
+func (s *Slicer) Run(ctx context.Context, interval time.Duration) error {
+	/*
+		Step every interval until the parent order completes or is cancelled. When the context is done, the resting
+		child order is cancelled
+	*/
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Step(); err != nil {
+			s.options.logger.Error("slicer step failed", "symbol", s.config.Symbol, "error", err)
+		}
+		if state := s.Progress().State; state == SlicerCompleted || state == SlicerCancelled {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err := s.Cancel(); err != nil {
+				s.options.logger.Error("cancelling slicer failed", "symbol", s.config.Symbol, "error", err)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
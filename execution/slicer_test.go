@@ -0,0 +1,149 @@
+package execution
+
+import (
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/papertest"
+)
+
+// quotes reads the quotes of execution orders from market
+func quotes(market *papertest.Market) QuoteFunc {
+	return func(symbol string) (float64, float64, error) {
+		bid, ask := market.Quote()
+		return bid, ask, nil
+	}
+}
+
+func TestTWAP(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	trader := paper.NewTrader(market, map[string]float64{"USD": 1000}, paper.WithFees(0, 0))
+	c := newClock()
+	twap, err := NewTWAP(trader, SliceConfig{
+		Symbol: "btcusd", Side: "buy", Amount: 4, Duration: 4 * time.Minute, Slices: 4, RepriceAfter: 20 * time.Second,
+	}, WithClock(c.Now), WithQuotes(quotes(market)))
+	if err != nil {
+		t.Fatalf("NewTWAP failed: %v", err)
+	}
+	step := func() {
+		t.Helper()
+		trader.Refresh()
+		if err := twap.Step(); err != nil {
+			t.Fatalf("Step failed: %v", err)
+		}
+	}
+
+	// the first slice joins the bid; the next is not released before its interval
+	step()
+	progress := twap.Progress()
+	first := progress.OpenOrderID
+	if progress.Children != 1 || first == 0 {
+		t.Fatalf("first slice not placed: %+v", progress)
+	}
+	if order, _ := trader.GetOrderStatus(first); order.Price != "100" || order.OriginalAmount != "1" {
+		t.Errorf("first slice %+v", order)
+	}
+
+	// the bid moves up: the child is re-placed only after RepriceAfter
+	market.Set(101, 101.5)
+	c.Advance(10 * time.Second)
+	step()
+	if twap.Progress().OpenOrderID != first {
+		t.Errorf("child re-priced before RepriceAfter")
+	}
+	c.Advance(15 * time.Second)
+	step()
+	second := twap.Progress().OpenOrderID
+	if order, _ := trader.GetOrderStatus(second); second == first || order.Price != "101" {
+		t.Errorf("child should be re-priced at the new bid: %+v", order)
+	}
+
+	// the child fills; two slices are due after the second interval
+	market.Set(100, 100.8)
+	c.Advance(time.Minute)
+	step()
+	step()
+	progress = twap.Progress()
+	if progress.Filled != 1 || progress.AvgPrice != 101 {
+		t.Fatalf("first slice should have filled at 101: %+v", progress)
+	}
+	if order, _ := trader.GetOrderStatus(progress.OpenOrderID); order.OriginalAmount != "1" || order.Price != "100" {
+		t.Errorf("second slice %+v", order)
+	}
+
+	// a partial fill of the resting child shows in both the filled amount and the average price
+	twap.trader = &faultyTrader{Trader: trader, statusExecuted: "0.5"}
+	step()
+	twap.trader = trader
+	if progress := twap.Progress(); progress.Filled != 1.5 || progress.AvgPrice != (101+0.5*100)/1.5 {
+		t.Errorf("progress with a partially filled child %+v", progress)
+	}
+
+	// pausing cancels the child and releases its funds
+	if err := twap.Pause(); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	if _, available := balance(t, trader, "USD"); available != 899 {
+		t.Errorf("pause should release the child's funds, %v USD available", available)
+	}
+	c.Advance(10 * time.Minute)
+	step()
+	if progress := twap.Progress(); progress.State != SlicerPaused || progress.OpenOrderID != 0 {
+		t.Errorf("paused slicer placed an order: %+v", progress)
+	}
+
+	// after resuming, the rest is released and crosses once the price falls through it
+	twap.Resume()
+	c.Advance(5 * time.Minute)
+	step()
+	market.Set(99, 99.5)
+	step()
+	step()
+	progress = twap.Progress()
+	if progress.State != SlicerCompleted || progress.Filled != 4 || progress.AvgPrice != 100.25 {
+		t.Errorf("TWAP should have completed: %+v", progress)
+	}
+}
+
+func TestIceberg(t *testing.T) {
+	market := papertest.NewMarket(100, 100.5)
+	trader := paper.NewTrader(market, map[string]float64{"BTC": 1}, paper.WithFees(0, 0))
+	iceberg, err := NewIceberg(trader, SliceConfig{
+		Symbol: "btcusd", Side: "sell", Amount: 1, VisibleAmount: 0.4, LimitPrice: 100.2,
+	}, WithQuotes(quotes(market)))
+	if err != nil {
+		t.Fatalf("NewIceberg failed: %v", err)
+	}
+	if err := iceberg.Step(); err != nil {
+		t.Fatalf("Step failed: %v", err)
+	}
+	open := iceberg.Progress().OpenOrderID
+	if order, _ := trader.GetOrderStatus(open); order.OriginalAmount != "0.4" || order.Price != "100.5" {
+		t.Errorf("visible child %+v", order)
+	}
+	if _, available := balance(t, trader, "BTC"); available != 0.6 {
+		t.Errorf("only the visible amount should be held, %v BTC available", available)
+	}
+	if err := iceberg.Cancel(); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if order, _ := trader.GetOrderStatus(open); !order.IsCancelled || iceberg.Progress().State != SlicerCancelled {
+		t.Errorf("cancel should clean up the child: %+v", order)
+	}
+	if _, err := NewIceberg(trader, SliceConfig{Symbol: "btcusd", Side: "sell", Amount: 1}); err == nil {
+		t.Errorf("an iceberg without a visible amount should be rejected")
+	}
+
+	// slices don't apply to an iceberg
+	sliced, err := NewIceberg(trader, SliceConfig{Symbol: "btcusd", Side: "sell", Amount: 1, VisibleAmount: 0.4, Slices: 3}, WithQuotes(quotes(market)))
+	if err != nil {
+		t.Fatalf("NewIceberg failed: %v", err)
+	}
+	if err := sliced.Step(); err != nil || sliced.Progress().Children != 1 {
+		t.Errorf("iceberg with slices: %+v, %v", sliced.Progress(), err)
+	}
+	if _, err := NewTWAP(trader, SliceConfig{Symbol: "btcusd", Side: "sell", Amount: 1, Duration: 2, Slices: 5}); err == nil {
+		t.Errorf("a TWAP with a zero slice interval should be rejected")
+	}
+}
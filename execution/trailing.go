@@ -0,0 +1,239 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// TrailingStopConfig configures a trailing stop
+type TrailingStopConfig struct {
+	Symbol string
+	// Amount is the base currency amount of the position to sell when the stop triggers
+	Amount float64
+	// TrailPercent is the distance of the stop below the high-water mark, as a fraction (0.05 is 5%)
+	TrailPercent float64
+	// LimitOffset is the distance of the limit price below the stop price, as a fraction
+	LimitOffset float64
+	// MinRepriceInterval is the minimum time between re-placements of the stop order
+	MinRepriceInterval time.Duration
+	// PriceIncrement rounds stop and limit prices down; the stop is only moved up by at least one increment (default 0.01)
+	PriceIncrement float64
+}
+
+// Trailing stop states
+const (
+	TrailingActive    = "active"
+	TrailingFilled    = "filled"
+	TrailingCancelled = "cancelled"
+)
+
+// TrailingStopStatus is a snapshot of a trailing stop
+type TrailingStopStatus struct {
+	State         string
+	HighWaterMark float64
+	StopPrice     float64
+	LimitPrice    float64
+	OrderID       int
+	Order         *private.Order
+	// Sold is the amount sold by stop orders that partially filled before they were moved or closed; the stop covers the rest
+	Sold float64
+}
+
+// TrailingStop protects a long position with an exchange stop-limit sell that follows the price up. Every price
+// update raises the high-water mark; when the stop it implies has risen by at least one price increment and the
+// minimum re-price interval has passed, the resting stop-limit order is cancelled and placed again higher. The stop
+// never moves down.
+type TrailingStop struct {
+	trader  private.Trader
+	config  TrailingStopConfig
+	options options
+
+	mu          sync.Mutex
+	status      TrailingStopStatus
+	lastReprice time.Time
+}
+
+func NewTrailingStop(trader private.Trader, config TrailingStopConfig, opts ...Option) (*TrailingStop, error) {
+	/*
+		Create a trailing stop. No order is placed until the first price update.
+
+		Args:
+		trader (private.Trader): trader the stop-limit orders are placed with
+		config (TrailingStopConfig): position, trail and re-pricing settings
+		opts (...Option): optional behavior, e.g. WithClock
+
+		Returns a pointer to the TrailingStop, or an error if the configuration is invalid
+	*/
+	if config.Symbol == "" || config.Amount <= 0 {
+		return nil, errors.New("trailing stop: symbol and a positive amount are required")
+	}
+	if config.TrailPercent <= 0 || config.TrailPercent >= 1 {
+		return nil, errors.New("trailing stop: trail percent must be between 0 and 1")
+	}
+	if config.LimitOffset <= 0 || config.LimitOffset >= 1 {
+		return nil, errors.New("trailing stop: limit offset must be between 0 and 1, the limit of a stop-limit sell is below its stop")
+	}
+	if config.PriceIncrement == 0 {
+		config.PriceIncrement = 0.01
+	}
+	return &TrailingStop{trader: trader, config: config, options: newOptions("trailing_stop", opts), status: TrailingStopStatus{State: TrailingActive}}, nil
+}
+
+func (t *TrailingStop) Status() TrailingStopStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+func (t *TrailingStop) Update(price float64) error {
+	/*
+		Feed a market price (e.g. the bid of a tick or the price of a trade): detect a filled stop, raise the
+		high-water mark and re-place the stop order if it should move up
+	*/
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.status.State != TrailingActive {
+		return nil
+	}
+	if t.status.OrderID != 0 {
+		order, err := t.trader.GetOrderStatus(t.status.OrderID)
+		if err != nil {
+			return err
+		}
+		if t.closed(order) {
+			return nil
+		}
+	}
+	if price > t.status.HighWaterMark {
+		t.status.HighWaterMark = price
+	}
+
+	stop := util.FloorTo(t.status.HighWaterMark*(1-t.config.TrailPercent), t.config.PriceIncrement)
+	if t.status.OrderID != 0 {
+		if stop < t.status.StopPrice+t.config.PriceIncrement-1e-9 {
+			return nil
+		}
+		if t.options.now().Sub(t.lastReprice) < t.config.MinRepriceInterval {
+			return nil
+		}
+		order, err := t.trader.CancelOrder(t.status.OrderID)
+		if err != nil {
+			return err
+		}
+		// the cancelled order is gone, so a failed re-placement is retried on the next update
+		cancelled := t.status.OrderID
+		t.status.OrderID, t.status.Order = 0, order
+		if executed := executedAmount(order); executed > 0 {
+			// the stop traded before it could be moved; keep protecting what is left
+			t.status.Sold += executed
+			if t.remaining() <= 1e-9 {
+				t.status.State = TrailingFilled
+				t.options.logger.Info("trailing stop filled", "order_id", cancelled, "price", order.AvgExecutionPrice)
+				return nil
+			}
+			t.options.logger.Info("trailing stop partially filled", "order_id", cancelled, "executed", executed, "remaining", t.remaining())
+		}
+	}
+	return t.place(stop)
+}
+
+// remaining is the amount of the position the stop still has to protect
+func (t *TrailingStop) remaining() float64 {
+	return t.config.Amount - t.status.Sold
+}
+
+// closed books the fills of a stop order that is no longer live and reports whether the trailing stop is over: the
+// position is sold, or the order was cancelled by someone else before it traded. An order that closed partially
+// filled leaves no order, so the rest of the position gets a new stop.
+func (t *TrailingStop) closed(order *private.Order) bool {
+	t.status.Order = order
+	if order.IsLive {
+		return false
+	}
+	executed := executedAmount(order)
+	t.status.Sold += executed
+	switch {
+	case t.remaining() <= 1e-9:
+		t.status.State = TrailingFilled
+		t.options.logger.Info("trailing stop filled", "order_id", t.status.OrderID, "price", order.AvgExecutionPrice)
+		return true
+	case executed == 0:
+		// cancelled by someone else; the position is no longer protected
+		t.status.State = TrailingCancelled
+		t.options.logger.Warn("trailing stop order cancelled externally", "order_id", t.status.OrderID)
+		return true
+	}
+	t.options.logger.Warn("trailing stop order closed partially filled", "order_id", t.status.OrderID, "executed", executed, "remaining", t.remaining())
+	t.status.OrderID = 0
+	return false
+}
+
+func (t *TrailingStop) place(stop float64) error {
+	limit := util.FloorTo(stop*(1-t.config.LimitOffset), t.config.PriceIncrement)
+	order, err := t.trader.StopLimitSell(t.config.Symbol, t.remaining(), stop, limit)
+	if err != nil {
+		return fmt.Errorf("trailing stop at %v failed: %w", stop, err)
+	}
+	id, err := orderID(order)
+	if err != nil {
+		return errors.New("trailing stop order has non-numeric id " + order.OrderID)
+	}
+	previous := t.status.StopPrice
+	t.status.OrderID, t.status.StopPrice, t.status.LimitPrice, t.status.Order = id, stop, limit, order
+	t.lastReprice = t.options.now()
+	t.options.logger.Info("trailing stop placed", "symbol", t.config.Symbol, "order_id", id, "stop_price", stop, "limit_price", limit,
+		"previous_stop_price", previous, "high_water_mark", t.status.HighWaterMark)
+	return nil
+}
+
+func (t *TrailingStop) Cancel() error {
+	/*
+		Stop trailing and cancel the resting stop order
+	*/
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.status.State != TrailingActive {
+		return nil
+	}
+	if t.status.OrderID != 0 {
+		order, err := t.trader.CancelOrder(t.status.OrderID)
+		if err != nil {
+			return err
+		}
+		t.status.Order = order
+	}
+	t.status.State = TrailingCancelled
+	return nil
+}
+
+func (t *TrailingStop) Run(ctx context.Context, interval time.Duration) error {
+	/*
+		Poll the bid of the symbol's ticker (or WithQuotes) every interval and Update with it until the stop fills, is cancelled or
+		the context is done
+	*/
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if bid, _, err := t.options.quote(t.config.Symbol); err != nil {
+			t.options.logger.Error("trailing stop ticker failed", "symbol", t.config.Symbol, "error", err)
+		} else {
+			if err := t.Update(bid); err != nil {
+				t.options.logger.Error("trailing stop update failed", "symbol", t.config.Symbol, "error", err)
+			}
+		}
+		if t.Status().State != TrailingActive {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
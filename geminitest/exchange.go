@@ -0,0 +1,334 @@
+package geminitest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type balance struct {
+	amount float64
+	held   float64
+}
+
+type order struct {
+	id            int64
+	clientOrderID string
+	symbol        string
+	side          string
+	orderType     string
+	price         float64
+	stopPrice     float64
+	amount        float64
+	executed      float64
+	avgPrice      float64
+	options       []string
+	live          bool
+	cancelled     bool
+	triggered     bool
+	reason        string
+	timestamp     time.Time
+	heldCurrency  string
+	held          float64
+}
+
+type trade struct {
+	id            int64
+	orderID       int64
+	clientOrderID string
+	symbol        string
+	side          string
+	price         float64
+	amount        float64
+	fee           float64
+	feeCurrency   string
+	timestamp     time.Time
+}
+
+type transfer struct {
+	id           int64
+	transferType string
+	currency     string
+	amount       float64
+	timestamp    time.Time
+}
+
+// rejection is an order placement error in the exchange's reason/message format
+type rejection struct {
+	reason  string
+	message string
+}
+
+func (s *Server) balance(currency string) *balance {
+	currency = strings.ToUpper(currency)
+	b, ok := s.balances[currency]
+	if !ok {
+		b = &balance{}
+		s.balances[currency] = b
+	}
+	return b
+}
+
+func (s *Server) seedOrderHistory() {
+	at := s.now().Add(-time.Hour)
+	s.nextID++
+	filled := &order{
+		id: s.nextID, clientOrderID: "geminitest-seed", symbol: "btcusd", side: "buy", orderType: "exchange limit",
+		price: 29000, amount: 0.01, executed: 0.01, avgPrice: 29000, timestamp: at,
+	}
+	s.orders[filled.id] = filled
+	s.orderSequence = append(s.orderSequence, filled.id)
+	s.nextID++
+	s.trades = append(s.trades, trade{
+		id: s.nextID, orderID: filled.id, clientOrderID: filled.clientOrderID, symbol: "btcusd", side: "buy",
+		price: 29000, amount: 0.01, feeCurrency: "USD", timestamp: at,
+	})
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// placeOrder validates, funds and matches a new order; must be called with s.mu held
+func (s *Server) placeOrder(o *order) *rejection {
+	market, ok := s.markets[strings.ToLower(o.symbol)]
+	if !ok {
+		return &rejection{"InvalidSymbol", "Invalid symbol for order: " + o.symbol}
+	}
+	o.symbol = market.Symbol
+	if o.side != "buy" && o.side != "sell" {
+		return &rejection{"InvalidSide", "Invalid side for order: " + o.side}
+	}
+	if o.orderType != "exchange limit" && o.orderType != "exchange stop limit" {
+		return &rejection{"InvalidOrderType", "Invalid order type for symbol " + o.symbol + ": " + o.orderType}
+	}
+	if o.price <= 0 {
+		return &rejection{"InvalidPrice", "Invalid price for symbol " + o.symbol + ": " + formatFloat(o.price)}
+	}
+	if o.amount <= 0 || o.amount < market.MinOrderSize {
+		return &rejection{"InvalidQuantity", "Invalid quantity for symbol " + o.symbol + ": " + formatFloat(o.amount)}
+	}
+	if o.orderType == "exchange stop limit" {
+		if o.stopPrice <= 0 || (o.side == "sell" && o.stopPrice < o.price) || (o.side == "buy" && o.stopPrice > o.price) {
+			return &rejection{"InvalidStopPrice", "Invalid stop price for symbol " + o.symbol + ": " + formatFloat(o.stopPrice)}
+		}
+	}
+
+	if o.side == "buy" {
+		o.heldCurrency, o.held = market.Quote, o.amount*o.price*(1+s.feeRate)
+	} else {
+		o.heldCurrency, o.held = market.Base, o.amount
+	}
+	funds := s.balance(o.heldCurrency)
+	if funds.amount-funds.held < o.held-1e-12 {
+		return &rejection{"InsufficientFunds", "Failed to place " + o.side + " order on symbol '" + strings.ToUpper(o.symbol) + "' for price $" + formatFloat(o.price) + " and quantity " + formatFloat(o.amount) + " due to insufficient funds"}
+	}
+	funds.held += o.held
+
+	s.nextID++
+	o.id = s.nextID
+	o.live = true
+	o.timestamp = s.now()
+	if o.options == nil {
+		o.options = []string{}
+	}
+	s.orders[o.id] = o
+	s.orderSequence = append(s.orderSequence, o.id)
+
+	if o.orderType == "exchange limit" {
+		crosses := s.crosses(market, o)
+		switch {
+		case hasOption(o.options, "maker-or-cancel") && crosses:
+			s.cancelOrder(o, "MakerOrCancelWouldTake")
+			return nil
+		case (hasOption(o.options, "immediate-or-cancel") || hasOption(o.options, "fill-or-kill")) && !crosses:
+			s.cancelOrder(o, "ImmediateOrCancelWouldPost")
+			return nil
+		}
+	}
+	s.matchOrder(market, o, false)
+	return nil
+}
+
+func (s *Server) crosses(market *Market, o *order) bool {
+	if o.side == "buy" {
+		return market.Ask > 0 && o.price >= market.Ask
+	}
+	return market.Bid > 0 && o.price <= market.Bid
+}
+
+// matchMarket triggers and fills resting orders on a market after its quote changed
+func (s *Server) matchMarket(market *Market) {
+	for _, id := range s.orderSequence {
+		o := s.orders[id]
+		if o.live && o.symbol == market.Symbol {
+			s.matchOrder(market, o, true)
+		}
+	}
+}
+
+// matchOrder fills an order that crosses the quote. Orders crossing when placed or triggered take the quote;
+// resting orders the quote moved through are makers and fill at their limit price.
+func (s *Server) matchOrder(market *Market, o *order, resting bool) {
+	if o.orderType == "exchange stop limit" && !o.triggered {
+		if o.side == "buy" && market.Ask >= o.stopPrice || o.side == "sell" && market.Bid > 0 && market.Bid <= o.stopPrice {
+			o.triggered = true
+			resting = false
+		} else {
+			return
+		}
+	}
+	if !s.crosses(market, o) {
+		return
+	}
+	fillPrice := market.Ask
+	if o.side == "sell" {
+		fillPrice = market.Bid
+	}
+	if resting {
+		fillPrice = o.price
+	}
+	s.fill(market, o, fillPrice)
+}
+
+func (s *Server) fill(market *Market, o *order, price float64) {
+	s.releaseHold(o)
+	notional := o.amount * price
+	fee := notional * s.feeRate
+	base, quote := s.balance(market.Base), s.balance(market.Quote)
+	if o.side == "buy" {
+		base.amount += o.amount
+		quote.amount -= notional + fee
+	} else {
+		base.amount -= o.amount
+		quote.amount += notional - fee
+	}
+	o.executed, o.avgPrice, o.live = o.amount, price, false
+	market.Last = price
+
+	s.nextID++
+	s.trades = append(s.trades, trade{
+		id: s.nextID, orderID: o.id, clientOrderID: o.clientOrderID, symbol: market.Symbol, side: o.side,
+		price: price, amount: o.amount, fee: fee, feeCurrency: market.Quote, timestamp: s.now(),
+	})
+}
+
+func (s *Server) releaseHold(o *order) {
+	if o.held > 0 {
+		s.balance(o.heldCurrency).held -= o.held
+		o.held = 0
+	}
+}
+
+func (s *Server) cancelOrder(o *order, reason string) {
+	if !o.live {
+		return
+	}
+	s.releaseHold(o)
+	o.live, o.cancelled, o.reason = false, true, reason
+}
+
+func (s *Server) findOrder(payload map[string]interface{}) *order {
+	if id, err := strconv.ParseInt(stringField(payload, "order_id"), 10, 64); err == nil {
+		return s.orders[id]
+	}
+	if clientOrderID := stringField(payload, "client_order_id"); clientOrderID != "" {
+		for _, id := range s.orderSequence {
+			if s.orders[id].clientOrderID == clientOrderID {
+				return s.orders[id]
+			}
+		}
+	}
+	return nil
+}
+
+func (o *order) json() map[string]interface{} {
+	id := strconv.FormatInt(o.id, 10)
+	body := map[string]interface{}{
+		"order_id":            id,
+		"id":                  id,
+		"client_order_id":     o.clientOrderID,
+		"symbol":              o.symbol,
+		"exchange":            "gemini",
+		"avg_execution_price": formatFloat(o.avgPrice),
+		"side":                o.side,
+		"type":                o.orderType,
+		"timestamp":           strconv.FormatInt(o.timestamp.Unix(), 10),
+		"timestampms":         o.timestamp.UnixMilli(),
+		"is_live":             o.live,
+		"is_cancelled":        o.cancelled,
+		"is_hidden":           false,
+		"was_forced":          false,
+		"executed_amount":     formatFloat(o.executed),
+		"remaining_amount":    formatFloat(o.amount - o.executed),
+		"options":             o.options,
+		"price":               formatFloat(o.price),
+		"original_amount":     formatFloat(o.amount),
+	}
+	if o.orderType == "exchange stop limit" {
+		body["stop_price"] = formatFloat(o.stopPrice)
+	}
+	if o.reason != "" {
+		body["reason"] = o.reason
+	}
+	return body
+}
+
+func (t trade) json() map[string]interface{} {
+	side := "Buy"
+	if t.side == "sell" {
+		side = "Sell"
+	}
+	return map[string]interface{}{
+		"price":            formatFloat(t.price),
+		"amount":           formatFloat(t.amount),
+		"timestamp":        t.timestamp.Unix(),
+		"timestampms":      t.timestamp.UnixMilli(),
+		"type":             side,
+		"aggressor":        true,
+		"fee_currency":     t.feeCurrency,
+		"fee_amount":       formatFloat(t.fee),
+		"tid":              t.id,
+		"order_id":         strconv.FormatInt(t.orderID, 10),
+		"client_order_id":  t.clientOrderID,
+		"exchange":         "gemini",
+		"is_auction_fill":  false,
+		"is_clearing_fill": false,
+		"symbol":           strings.ToUpper(t.symbol),
+	}
+}
+
+// addTransfer records a completed transfer; must be called with s.mu held
+func (s *Server) addTransfer(transferType string, currency string, amount float64) {
+	s.nextID++
+	s.transfers = append(s.transfers, transfer{
+		id: s.nextID, transferType: transferType, currency: strings.ToUpper(currency), amount: amount, timestamp: s.now(),
+	})
+}
+
+func (t transfer) json() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        t.transferType,
+		"status":      "Complete",
+		"timestampms": t.timestamp.UnixMilli(),
+		"eid":         t.id,
+		"currency":    t.currency,
+		"amount":      formatFloat(t.amount),
+		"method":      "Blockchain",
+	}
+}
+
+func (s *Server) sortedCurrencies() []string {
+	currencies := make([]string, 0, len(s.balances))
+	for currency := range s.balances {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
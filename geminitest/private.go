@@ -0,0 +1,372 @@
+package geminitest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type clearingOrder struct {
+	id             string
+	counterpartyID string
+	symbol         string
+	side           string
+	amount         string
+	price          string
+	status         string
+	created        time.Time
+	expires        time.Time
+}
+
+type stakingTransaction struct {
+	id              string
+	transactionType string
+	currency        string
+	amount          float64
+	timestamp       time.Time
+}
+
+func (s *Server) servePrivate(w http.ResponseWriter, r *http.Request) {
+	payload, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/balances":
+		var balances []map[string]string
+		for _, currency := range s.sortedCurrencies() {
+			b := s.balances[currency]
+			balances = append(balances, map[string]string{
+				"type":                   "exchange",
+				"currency":               currency,
+				"amount":                 formatFloat(b.amount),
+				"available":              formatFloat(b.amount - b.held),
+				"availableForWithdrawal": formatFloat(b.amount - b.held),
+			})
+		}
+		writeJSON(w, http.StatusOK, balances)
+	case "/v1/order/new":
+		s.serveNewOrder(w, payload)
+	case "/v1/order/cancel":
+		o := s.findOrder(payload)
+		if o == nil {
+			writeError(w, http.StatusBadRequest, "OrderNotFound", "Order "+stringField(payload, "order_id")+" not found")
+			return
+		}
+		s.cancelOrder(o, "Requested")
+		writeJSON(w, http.StatusOK, o.json())
+	case "/v1/order/cancel/all", "/v1/order/cancel/session":
+		var cancelled []int64
+		for _, id := range s.orderSequence {
+			if o := s.orders[id]; o.live {
+				s.cancelOrder(o, "Requested")
+				cancelled = append(cancelled, id)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"result":  "ok",
+			"details": map[string]interface{}{"cancelledOrders": nonNil(cancelled), "cancelRejects": []int64{}},
+		})
+	case "/v1/order/status":
+		o := s.findOrder(payload)
+		if o == nil {
+			writeError(w, http.StatusBadRequest, "OrderNotFound", "Order "+stringField(payload, "order_id")+" not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, o.json())
+	case "/v1/orders":
+		active := []map[string]interface{}{}
+		for _, id := range s.orderSequence {
+			if o := s.orders[id]; o.live {
+				active = append(active, o.json())
+			}
+		}
+		writeJSON(w, http.StatusOK, active)
+	case "/v1/orders/history":
+		symbol := strings.ToLower(stringField(payload, "symbol"))
+		closed := []map[string]interface{}{}
+		for i := len(s.orderSequence) - 1; i >= 0; i-- {
+			if o := s.orders[s.orderSequence[i]]; !o.live && (symbol == "" || o.symbol == symbol) {
+				closed = append(closed, o.json())
+			}
+		}
+		writeJSON(w, http.StatusOK, closed)
+	case "/v1/mytrades":
+		s.serveMyTrades(w, payload)
+	case "/v1/transfers":
+		since := sinceField(payload)
+		var matched []transfer
+		for _, t := range s.transfers {
+			if t.timestamp.UnixMilli() >= since {
+				matched = append(matched, t)
+			}
+		}
+		start, end := window(len(matched), limitField(payload, "limit_transfers", 10, 50), since != 0)
+		transfers := []map[string]interface{}{}
+		for i := end - 1; i >= start; i-- {
+			transfers = append(transfers, matched[i].json())
+		}
+		writeJSON(w, http.StatusOK, transfers)
+	case "/v1/positions":
+		writeJSON(w, http.StatusOK, s.positions)
+	case "/v1/margin":
+		writeJSON(w, http.StatusOK, map[string]string{
+			"margin_assets_value": "1000", "initial_margin": "1500", "available_margin": "8500",
+			"margin_maintenance_limit": "750", "leverage": "1.5", "notional_value": "15000",
+			"estimated_liquidation_price": "12000", "initial_margin_positions": "1500", "reserved_margin": "0",
+			"reserved_margin_buys": "0", "reserved_margin_sells": "0", "buying_power": "42500", "selling_power": "57500",
+		})
+	case "/v1/perpetuals/fundingPayment":
+		writeJSON(w, http.StatusOK, []map[string]interface{}{{
+			"eventType": "Hourly Funding Transfer", "timestamp": s.now().Truncate(time.Hour).UnixMilli(),
+			"assetCode": "GUSD", "action": "Debit", "quantity": map[string]string{"currency": "GUSD", "value": "0.11"},
+			"instrumentSymbol": "BTCGUSDPERP",
+		}})
+	case "/v1/balances/staking":
+		s.serveStakingBalances(w)
+	case "/v1/staking/stake", "/v1/staking/unstake":
+		s.serveStake(w, payload, r.URL.Path == "/v1/staking/stake")
+	case "/v1/staking/rewards":
+		rewards := map[string]map[string]interface{}{stakingProviderID: {}}
+		for currency, amount := range s.staked {
+			rewards[stakingProviderID][currency] = map[string]interface{}{
+				"value": amount * 0.001, "accrualTotal": amount * 0.001, "ratesCount": 1,
+				"firstAccrualAt": s.now().Add(-24 * time.Hour).UTC().Format(time.RFC3339),
+				"lastAccrualAt":  s.now().UTC().Format(time.RFC3339), "currency": currency,
+			}
+		}
+		writeJSON(w, http.StatusOK, rewards)
+	case "/v1/staking/history":
+		s.serveStakingHistory(w)
+	case "/v1/clearing/new":
+		s.serveNewClearingOrder(w, payload)
+	case "/v1/clearing/status", "/v1/clearing/cancel", "/v1/clearing/confirm":
+		s.serveClearingOrder(w, payload, r.URL.Path)
+	case "/v1/clearing/trades":
+		results := []map[string]interface{}{}
+		for i := int64(1); i < s.nextClearingID; i++ {
+			c := s.clearingOrders[clearingID(i)]
+			results = append(results, map[string]interface{}{
+				"sourceCounterparty": "GEMINITEST", "targetCounterparty": c.counterpartyID, "symbol": c.symbol,
+				"side": c.side, "price": c.price, "quantity": c.amount, "created": c.created.UnixMilli(),
+				"expires": c.expires.UnixMilli(), "status": c.status, "clearingId": c.id, "clearingType": "bilateral",
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+	default:
+		writeError(w, http.StatusNotFound, "EndpointNotFound", "API endpoint "+r.URL.Path+" not found")
+	}
+}
+
+func nonNil(ids []int64) []int64 {
+	if ids == nil {
+		return []int64{}
+	}
+	return ids
+}
+
+func (s *Server) serveNewOrder(w http.ResponseWriter, payload map[string]interface{}) {
+	amount, _ := floatField(payload, "amount")
+	price, _ := floatField(payload, "price")
+	stopPrice, _ := floatField(payload, "stop_price")
+	var options []string
+	if raw, ok := payload["options"].([]interface{}); ok {
+		for _, option := range raw {
+			if value, ok := option.(string); ok {
+				options = append(options, value)
+			}
+		}
+	}
+	o := &order{
+		clientOrderID: stringField(payload, "client_order_id"),
+		symbol:        stringField(payload, "symbol"),
+		side:          stringField(payload, "side"),
+		orderType:     stringField(payload, "type"),
+		price:         price,
+		stopPrice:     stopPrice,
+		amount:        amount,
+		options:       options,
+	}
+	if rejected := s.placeOrder(o); rejected != nil {
+		writeError(w, http.StatusBadRequest, rejected.reason, rejected.message)
+		return
+	}
+	writeJSON(w, http.StatusOK, o.json())
+}
+
+func (s *Server) serveMyTrades(w http.ResponseWriter, payload map[string]interface{}) {
+	symbol := strings.ToLower(stringField(payload, "symbol"))
+	since := sinceField(payload)
+	var matched []trade
+	for _, t := range s.trades {
+		if (symbol == "" || t.symbol == symbol) && t.timestamp.UnixMilli() >= since {
+			matched = append(matched, t)
+		}
+	}
+	start, end := window(len(matched), limitField(payload, "limit_trades", 50, 500), since != 0)
+	trades := []map[string]interface{}{}
+	for i := end - 1; i >= start; i-- {
+		trades = append(trades, matched[i].json())
+	}
+	writeJSON(w, http.StatusOK, trades)
+}
+
+// sinceField reads the timestamp of a history request in Unix milliseconds; like the exchange, it takes seconds too
+func sinceField(payload map[string]interface{}) int64 {
+	since, _ := strconv.ParseInt(stringField(payload, "timestamp"), 10, 64)
+	if since < 100_000_000_000 {
+		return since * 1000
+	}
+	return since
+}
+
+// limitField reads the page size of a history request, which defaults to fallback and is capped at most
+func limitField(payload map[string]interface{}, key string, fallback int, most int) int {
+	limit, err := strconv.Atoi(stringField(payload, key))
+	if err != nil || limit <= 0 {
+		return fallback
+	}
+	return min(limit, most)
+}
+
+// window picks the page of n records, oldest first, that a history request returns: the oldest limit records on or
+// after its timestamp, or the most recent limit records without one
+func window(n int, limit int, fromOldest bool) (start int, end int) {
+	if n <= limit {
+		return 0, n
+	}
+	if fromOldest {
+		return 0, limit
+	}
+	return n - limit, n
+}
+
+func (s *Server) serveStakingBalances(w http.ResponseWriter) {
+	balances := []map[string]interface{}{}
+	for _, currency := range s.sortedCurrencies() {
+		staked := s.staked[currency]
+		if staked == 0 {
+			continue
+		}
+		balances = append(balances, map[string]interface{}{
+			"type": "Staking", "currency": currency, "balance": staked, "available": 0,
+			"availableForWithdrawal": staked,
+			"balanceByProvider":      map[string]map[string]float64{stakingProviderID: {"balance": staked}},
+		})
+	}
+	writeJSON(w, http.StatusOK, balances)
+}
+
+func (s *Server) serveStake(w http.ResponseWriter, payload map[string]interface{}, stake bool) {
+	currency := strings.ToUpper(stringField(payload, "currency"))
+	amount, ok := floatField(payload, "amount")
+	if !ok || amount <= 0 {
+		writeError(w, http.StatusBadRequest, "InvalidQuantity", "Invalid staking amount")
+		return
+	}
+	if stringField(payload, "providerId") != stakingProviderID {
+		writeError(w, http.StatusBadRequest, "InvalidProvider", "Unknown staking provider")
+		return
+	}
+	funds := s.balance(currency)
+	transactionType := "Stake"
+	if stake {
+		if funds.amount-funds.held < amount {
+			writeError(w, http.StatusBadRequest, "InsufficientFunds", "Insufficient "+currency+" to stake")
+			return
+		}
+		funds.amount -= amount
+		s.staked[currency] += amount
+	} else {
+		if s.staked[currency] < amount {
+			writeError(w, http.StatusBadRequest, "InsufficientFunds", "Insufficient staked "+currency+" to unstake")
+			return
+		}
+		s.staked[currency] -= amount
+		funds.amount += amount
+		transactionType = "Unstake"
+	}
+	s.nextID++
+	transaction := stakingTransaction{
+		id: strconv.FormatInt(s.nextID, 10), transactionType: transactionType, currency: currency, amount: amount, timestamp: s.now(),
+	}
+	s.stakingHistory = append(s.stakingHistory, transaction)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"transactionId": transaction.id, "providerId": stakingProviderID, "currency": currency,
+		"amount": amount, "accrualTotal": s.staked[currency], "status": "Complete",
+	})
+}
+
+func (s *Server) serveStakingHistory(w http.ResponseWriter) {
+	transactions := []map[string]interface{}{}
+	for i := len(s.stakingHistory) - 1; i >= 0; i-- {
+		t := s.stakingHistory[i]
+		transactions = append(transactions, map[string]interface{}{
+			"timestamp": t.timestamp.UTC().Format(time.RFC3339), "transactionId": t.id, "transactionType": t.transactionType,
+			"amount": t.amount, "amountPaidSettlement": 0, "priceAtRequest": 0, "currency": t.currency,
+		})
+	}
+	writeJSON(w, http.StatusOK, []map[string]interface{}{{"providerId": stakingProviderID, "transactions": transactions}})
+}
+
+func clearingID(n int64) string {
+	return "GT" + strconv.FormatInt(n, 36)
+}
+
+func (s *Server) serveNewClearingOrder(w http.ResponseWriter, payload map[string]interface{}) {
+	if _, ok := s.markets[strings.ToLower(stringField(payload, "symbol"))]; !ok {
+		writeError(w, http.StatusBadRequest, "InvalidSymbol", "Invalid symbol for clearing order")
+		return
+	}
+	hours, err := strconv.Atoi(stringField(payload, "expires_in_hrs"))
+	if err != nil || hours <= 0 {
+		writeError(w, http.StatusBadRequest, "InvalidExpiration", "expires_in_hrs must be a positive integer")
+		return
+	}
+	c := &clearingOrder{
+		id:             clearingID(s.nextClearingID),
+		counterpartyID: stringField(payload, "counterparty_id"),
+		symbol:         strings.ToLower(stringField(payload, "symbol")),
+		side:           stringField(payload, "side"),
+		amount:         stringField(payload, "amount"),
+		price:          stringField(payload, "price"),
+		status:         "AwaitConfirm",
+		created:        s.now(),
+		expires:        s.now().Add(time.Duration(hours) * time.Hour),
+	}
+	s.nextClearingID++
+	s.clearingOrders[c.id] = c
+	writeJSON(w, http.StatusOK, map[string]string{"result": "AwaitConfirm", "clearing_id": c.id})
+}
+
+func (s *Server) serveClearingOrder(w http.ResponseWriter, payload map[string]interface{}, path string) {
+	id := stringField(payload, "clearing_id")
+	c, ok := s.clearingOrders[id]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "ClearingOrderNotFound", "Clearing order "+id+" not found")
+		return
+	}
+	switch path {
+	case "/v1/clearing/status":
+		writeJSON(w, http.StatusOK, map[string]string{"result": "ok", "status": c.status, "clearing_id": c.id})
+	case "/v1/clearing/cancel":
+		if c.status != "AwaitConfirm" {
+			writeError(w, http.StatusBadRequest, "ClearingOrderNotCancellable", "Clearing order "+id+" is "+c.status)
+			return
+		}
+		c.status = "Cancelled"
+		writeJSON(w, http.StatusOK, map[string]string{"result": "ok", "details": c.id + " order canceled"})
+	case "/v1/clearing/confirm":
+		if c.status != "AwaitConfirm" || stringField(payload, "symbol") == "" ||
+			strings.ToLower(stringField(payload, "symbol")) != c.symbol || stringField(payload, "amount") != c.amount || stringField(payload, "price") != c.price {
+			writeError(w, http.StatusBadRequest, "InvalidClearingConfirmation", "Confirmation does not match clearing order "+id)
+			return
+		}
+		c.status = "Confirmed"
+		writeJSON(w, http.StatusOK, map[string]string{"result": "confirmed"})
+	}
+}
@@ -0,0 +1,231 @@
+package geminitest
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var defaultMarkets = []Market{
+	{Symbol: "btcusd", Base: "BTC", Quote: "USD", Bid: 30000, Ask: 30000, Last: 30000, TickSize: 1e-8, QuoteIncrement: 0.01, MinOrderSize: 0.00001},
+	{Symbol: "ethusd", Base: "ETH", Quote: "USD", Bid: 2000, Ask: 2000, Last: 2000, TickSize: 1e-6, QuoteIncrement: 0.01, MinOrderSize: 0.001},
+	{Symbol: "ethbtc", Base: "ETH", Quote: "BTC", Bid: 0.066, Ask: 0.066, Last: 0.066, TickSize: 1e-6, QuoteIncrement: 1e-5, MinOrderSize: 0.001},
+	{Symbol: "bchusd", Base: "BCH", Quote: "USD", Bid: 250, Ask: 250, Last: 250, TickSize: 1e-6, QuoteIncrement: 0.01, MinOrderSize: 0.001},
+	{Symbol: "bchbtc", Base: "BCH", Quote: "BTC", Bid: 0.0083, Ask: 0.0083, Last: 0.0083, TickSize: 1e-6, QuoteIncrement: 1e-5, MinOrderSize: 0.001},
+	{Symbol: "bcheth", Base: "BCH", Quote: "ETH", Bid: 0.125, Ask: 0.125, Last: 0.125, TickSize: 1e-6, QuoteIncrement: 1e-5, MinOrderSize: 0.001},
+	{Symbol: "ltcusd", Base: "LTC", Quote: "USD", Bid: 80, Ask: 80, Last: 80, TickSize: 1e-5, QuoteIncrement: 0.01, MinOrderSize: 0.01},
+	{Symbol: "ltcbtc", Base: "LTC", Quote: "BTC", Bid: 0.0027, Ask: 0.0027, Last: 0.0027, TickSize: 1e-5, QuoteIncrement: 1e-5, MinOrderSize: 0.01},
+	{Symbol: "ltceth", Base: "LTC", Quote: "ETH", Bid: 0.04, Ask: 0.04, Last: 0.04, TickSize: 1e-5, QuoteIncrement: 1e-5, MinOrderSize: 0.01},
+	{Symbol: "ltcbch", Base: "LTC", Quote: "BCH", Bid: 0.32, Ask: 0.32, Last: 0.32, TickSize: 1e-5, QuoteIncrement: 1e-4, MinOrderSize: 0.01},
+	{Symbol: "batusd", Base: "BAT", Quote: "USD", Bid: 0.25, Ask: 0.25, Last: 0.25, TickSize: 1e-6, QuoteIncrement: 1e-5, MinOrderSize: 1},
+	{Symbol: "daiusd", Base: "DAI", Quote: "USD", Bid: 1, Ask: 1, Last: 1, TickSize: 1e-6, QuoteIncrement: 1e-5, MinOrderSize: 0.1},
+	{Symbol: "linkusd", Base: "LINK", Quote: "USD", Bid: 15, Ask: 15, Last: 15, TickSize: 1e-6, QuoteIncrement: 1e-5, MinOrderSize: 0.1},
+	{Symbol: "oxtusd", Base: "OXT", Quote: "USD", Bid: 0.08, Ask: 0.08, Last: 0.08, TickSize: 1e-6, QuoteIncrement: 1e-4, MinOrderSize: 1},
+	{Symbol: "linkbtc", Base: "LINK", Quote: "BTC", Bid: 0.0005, Ask: 0.0005, Last: 0.0005, TickSize: 1e-6, QuoteIncrement: 1e-8, MinOrderSize: 0.1},
+	{Symbol: "linketh", Base: "LINK", Quote: "ETH", Bid: 0.0075, Ask: 0.0075, Last: 0.0075, TickSize: 1e-6, QuoteIncrement: 1e-8, MinOrderSize: 0.1},
+	{Symbol: "ampusd", Base: "AMP", Quote: "USD", Bid: 0.005, Ask: 0.005, Last: 0.005, TickSize: 1e-6, QuoteIncrement: 1e-5, MinOrderSize: 10},
+	{Symbol: "compusd", Base: "COMP", Quote: "USD", Bid: 50, Ask: 50, Last: 50, TickSize: 1e-6, QuoteIncrement: 0.01, MinOrderSize: 0.001},
+	{Symbol: "paxgusd", Base: "PAXG", Quote: "USD", Bid: 2000, Ask: 2000, Last: 2000, TickSize: 1e-8, QuoteIncrement: 0.01, MinOrderSize: 0.0001},
+	{Symbol: "maticusd", Base: "MATIC", Quote: "USD", Bid: 0.7, Ask: 0.7, Last: 0.7, TickSize: 1e-6, QuoteIncrement: 1e-5, MinOrderSize: 0.1},
+	{Symbol: "btcgusdperp", Base: "BTC", Quote: "GUSD", Bid: 30000, Ask: 30000, Last: 30000, TickSize: 1e-4, QuoteIncrement: 0.5, MinOrderSize: 0.0001, Perpetual: true},
+}
+
+const stakingProviderID = "62b21e17-2534-4b9f-afcf-b7edb609dd8d"
+
+func (s *Server) servePublic(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	last := parts[len(parts)-1]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	market := s.markets[strings.ToLower(last)]
+	if len(parts) >= 2 && strings.HasPrefix(path, "/v2/") && strings.Contains(path, "candles") {
+		market = s.markets[strings.ToLower(parts[len(parts)-2])]
+	}
+
+	switch {
+	case path == "/v1/symbols":
+		writeJSON(w, http.StatusOK, s.symbols)
+	case path == "/v1/feepromos":
+		writeJSON(w, http.StatusOK, map[string][]string{"symbols": {"GUSDUSD", "GUSDGBP"}})
+	case path == "/v1/pricefeed":
+		s.servePriceFeed(w)
+	case path == "/v1/staking/rates":
+		writeJSON(w, http.StatusOK, map[string]map[string]map[string]interface{}{
+			stakingProviderID: {
+				"MATIC": {"providerId": stakingProviderID, "rate": 429.38, "apy": 5.36, "currency": "MATIC", "minimum": 1},
+				"ETH":   {"providerId": stakingProviderID, "rate": 301.2, "apy": 3.05, "currency": "ETH", "minimum": 0.01},
+			},
+		})
+	case path == "/v1/fundingamountreport/records.xlsx":
+		s.serveFundingAmountReport(w)
+	case strings.HasPrefix(path, "/v1/network/"):
+		writeJSON(w, http.StatusOK, map[string]interface{}{"token": strings.ToUpper(last), "network": []string{"Bitcoin"}})
+	case market == nil:
+		writeError(w, http.StatusBadRequest, "InvalidSymbol", "Supplied value '"+last+"' is not a valid symbol")
+	case strings.HasPrefix(path, "/v1/symbols/details/"):
+		s.serveSymbolDetails(w, market)
+	case strings.HasPrefix(path, "/v1/pubticker/"):
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"bid":    formatFloat(market.Bid),
+			"ask":    formatFloat(market.Ask),
+			"last":   formatFloat(market.Last),
+			"volume": map[string]interface{}{market.Base: "100", market.Quote: formatFloat(100 * market.Last), "timestamp": s.now().UnixMilli()},
+		})
+	case strings.HasPrefix(path, "/v2/ticker/"):
+		s.serveTickerV2(w, market)
+	case strings.HasPrefix(path, "/v2/candles/"), strings.HasPrefix(path, "/v2/derivatives/candles/"):
+		s.serveCandles(w, market, last)
+	case strings.HasPrefix(path, "/v1/book/"):
+		s.serveBook(w, market)
+	case strings.HasPrefix(path, "/v1/trades/"):
+		s.serveMarketTrades(w, market)
+	case strings.HasPrefix(path, "/v1/fundingamount/") && market.Perpetual:
+		now := s.now().UTC()
+		hour := now.Truncate(time.Hour)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"symbol":                    market.Symbol,
+			"fundingDateTime":           hour.Format("2006-01-02T15:04:05.000Z"),
+			"fundingTimestampMilliSecs": hour.UnixMilli(),
+			"nextFundingTimestamp":      hour.Add(time.Hour).UnixMilli(),
+			"amount":                    0.51692,
+			"estimatedFundingAmount":    0.22,
+		})
+	case strings.HasPrefix(path, "/v1/riskstats/") && market.Perpetual:
+		writeJSON(w, http.StatusOK, map[string]string{
+			"product_type":           "PerpetualSwapContract",
+			"mark_price":             formatFloat(market.Last),
+			"index_price":            formatFloat(market.Last),
+			"open_interest":          "14.719668",
+			"open_interest_notional": formatFloat(14.719668 * market.Last),
+		})
+	default:
+		writeError(w, http.StatusNotFound, "EndpointNotFound", "API endpoint "+path+" not found")
+	}
+}
+
+func (s *Server) serveSymbolDetails(w http.ResponseWriter, market *Market) {
+	productType, contractType := "spot", "vanilla"
+	if market.Perpetual {
+		productType, contractType = "swap", "linear"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol":                  strings.ToUpper(market.Symbol),
+		"base_currency":           market.Base,
+		"quote_currency":          market.Quote,
+		"tick_size":               market.TickSize,
+		"quote_increment":         market.QuoteIncrement,
+		"min_order_size":          formatFloat(market.MinOrderSize),
+		"status":                  "open",
+		"wrap_enabled":            false,
+		"product_type":            productType,
+		"contract_type":           contractType,
+		"contract_price_currency": market.Quote,
+	})
+}
+
+func (s *Server) serveTickerV2(w http.ResponseWriter, market *Market) {
+	changes := make([]string, 24)
+	for i := range changes {
+		changes[i] = formatFloat(market.Last)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol":  strings.ToUpper(market.Symbol),
+		"open":    formatFloat(market.Last),
+		"high":    formatFloat(market.Ask),
+		"low":     formatFloat(market.Bid),
+		"close":   formatFloat(market.Last),
+		"changes": changes,
+		"bid":     formatFloat(market.Bid),
+		"ask":     formatFloat(market.Ask),
+	})
+}
+
+var candleFrames = map[string]time.Duration{
+	"1m": time.Minute, "5m": 5 * time.Minute, "15m": 15 * time.Minute, "30m": 30 * time.Minute,
+	"1hr": time.Hour, "6hr": 6 * time.Hour, "1day": 24 * time.Hour,
+}
+
+// serveCandles returns 60 flat candles at the last price, newest first, like the exchange
+func (s *Server) serveCandles(w http.ResponseWriter, market *Market, timeFrame string) {
+	frame, ok := candleFrames[timeFrame]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "InvalidTimeFrame", "Supplied value '"+timeFrame+"' is not a valid time frame")
+		return
+	}
+	newest := s.now().UTC().Truncate(frame)
+	candles := make([][]interface{}, 60)
+	for i := range candles {
+		candles[i] = []interface{}{newest.Add(-time.Duration(i) * frame).UnixMilli(), market.Last, market.Ask, market.Bid, market.Last, 1.5}
+	}
+	writeJSON(w, http.StatusOK, candles)
+}
+
+func (s *Server) serveBook(w http.ResponseWriter, market *Market) {
+	timestamp := formatFloat(float64(s.now().Unix()))
+	var bids, asks []map[string]string
+	for i := 0; i < 5; i++ {
+		step := float64(i) * market.QuoteIncrement
+		bids = append(bids, map[string]string{"price": formatFloat(market.Bid - step), "amount": "1", "timestamp": timestamp})
+		asks = append(asks, map[string]string{"price": formatFloat(market.Ask + step), "amount": "1", "timestamp": timestamp})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"bids": bids, "asks": asks})
+}
+
+func (s *Server) serveMarketTrades(w http.ResponseWriter, market *Market) {
+	now := s.now()
+	var trades []map[string]interface{}
+	for i := 0; i < 5; i++ {
+		at := now.Add(-time.Duration(i) * time.Second)
+		side := "buy"
+		if i%2 == 1 {
+			side = "sell"
+		}
+		trades = append(trades, map[string]interface{}{
+			"timestamp":   at.Unix(),
+			"timestampms": at.UnixMilli(),
+			"tid":         int64(1000 + i),
+			"price":       formatFloat(market.Last),
+			"amount":      "0.1",
+			"exchange":    "gemini",
+			"type":        side,
+		})
+	}
+	writeJSON(w, http.StatusOK, trades)
+}
+
+func (s *Server) servePriceFeed(w http.ResponseWriter) {
+	var feed []map[string]string
+	for _, symbol := range s.symbols {
+		feed = append(feed, map[string]string{
+			"pair":             strings.ToUpper(symbol),
+			"price":            formatFloat(s.markets[symbol].Last),
+			"percentChange24h": "0.0000",
+		})
+	}
+	writeJSON(w, http.StatusOK, feed)
+}
+
+func (s *Server) serveFundingAmountReport(w http.ResponseWriter) {
+	report := excelize.NewFile()
+	sheet := report.GetSheetName(0)
+	rows := [][]interface{}{
+		{"Symbol", "FundingDateTime", "Amount", "Is Realized ?"},
+		{"BTCGUSDPERP", s.now().UTC().Truncate(time.Hour).Format("2006-01-02 15:04:05"), 0.51692, true},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		report.SetSheetRow(sheet, cell, &row)
+	}
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		writeError(w, http.StatusInternalServerError, "ReportFailed", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Write(buf.Bytes())
+}
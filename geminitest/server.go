@@ -0,0 +1,332 @@
+// Package geminitest provides an in-memory emulation of the Gemini exchange REST API for hermetic tests.
+//
+// The server answers the public market data endpoints from configurable quotes, and the signed private endpoints
+// from in-memory balances and orders, verifying the API key, HMAC-SHA384 signature and nonce of every private request
+// the way the exchange does. Limit and stop-limit orders are matched against the current quote whenever they are
+// placed or the quote changes.
+package geminitest
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAPIKey    = "account-geminitest"
+	DefaultAPISecret = "geminitest-secret"
+)
+
+// Server is a mock Gemini exchange backed by an httptest.Server
+type Server struct {
+	*httptest.Server
+
+	APIKey    string
+	APISecret string
+
+	mu             sync.Mutex
+	now            func() time.Time
+	lastNonce      int64
+	markets        map[string]*Market
+	symbols        []string
+	balances       map[string]*balance
+	staked         map[string]float64
+	stakingHistory []stakingTransaction
+	orders         map[int64]*order
+	orderSequence  []int64
+	nextID         int64
+	trades         []trade
+	transfers      []transfer
+	feeRate        float64
+	positions      []map[string]interface{}
+	clearingOrders map[string]*clearingOrder
+	nextClearingID int64
+}
+
+// Market is the quote and trading rules of a symbol on the mock exchange
+type Market struct {
+	Symbol         string
+	Base           string
+	Quote          string
+	Bid            float64
+	Ask            float64
+	Last           float64
+	TickSize       float64
+	QuoteIncrement float64
+	MinOrderSize   float64
+	Perpetual      bool
+}
+
+func NewServer() *Server {
+	/*
+		Start a mock exchange with the default markets and balances (10,000 USD, 1 BTC, 10 ETH, 100 MATIC and 1,000 GUSD),
+		and one filled order in the order history. Close it when done.
+	*/
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+func NewUnstartedServer() *Server {
+	/*
+		Create a mock exchange without starting it, so markets and balances can be configured first. Call Start when ready.
+	*/
+	s := &Server{
+		APIKey:         DefaultAPIKey,
+		APISecret:      DefaultAPISecret,
+		now:            time.Now,
+		markets:        map[string]*Market{},
+		balances:       map[string]*balance{},
+		staked:         map[string]float64{},
+		orders:         map[int64]*order{},
+		nextID:         100000000000,
+		clearingOrders: map[string]*clearingOrder{},
+		nextClearingID: 1,
+	}
+	for _, m := range defaultMarkets {
+		market := m
+		s.AddMarket(&market)
+	}
+	for currency, amount := range map[string]float64{"USD": 10000, "BTC": 1, "ETH": 10, "MATIC": 100, "GUSD": 1000} {
+		s.balances[currency] = &balance{amount: amount}
+	}
+	s.positions = []map[string]interface{}{{
+		"symbol":          "btcgusdperp",
+		"instrument_type": "perp",
+		"quantity":        "0.5",
+		"notional_value":  "15000",
+		"realised_pnl":    "0",
+		"unrealised_pnl":  "12.5",
+		"mark_price":      "30000",
+	}}
+	s.seedOrderHistory()
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) Env() map[string]string {
+	/*
+		Get the environment variables that point the library at this server with its credentials
+	*/
+	return map[string]string{
+		"GEMINI_EXCHANGE_API_BASE_URL": s.URL,
+		"GEMINI_EXCHANGE_API_KEY":      s.APIKey,
+		"GEMINI_EXCHANGE_API_SECRET":   s.APISecret,
+	}
+}
+
+func (s *Server) SetClock(now func() time.Time) {
+	/*
+		Replace the clock used for order, trade and candle timestamps
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+func (s *Server) SetFeeRate(rate float64) {
+	/*
+		Charge the given fraction of the notional value of every fill as a fee, in the quote currency. 0 by default.
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feeRate = rate
+}
+
+func (s *Server) AddMarket(market *Market) {
+	/*
+		Add or replace a market. Symbols are case-insensitive.
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	market.Symbol = strings.ToLower(market.Symbol)
+	if _, ok := s.markets[market.Symbol]; !ok {
+		s.symbols = append(s.symbols, market.Symbol)
+	}
+	s.markets[market.Symbol] = market
+}
+
+func (s *Server) Market(symbol string) *Market {
+	/*
+		Get a copy of a market, or nil if the symbol is unknown
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	market, ok := s.markets[strings.ToLower(symbol)]
+	if !ok {
+		return nil
+	}
+	copied := *market
+	return &copied
+}
+
+func (s *Server) SetPrice(symbol string, price float64) {
+	/*
+		Move the bid, ask and last price of a market to price and match resting orders against it
+	*/
+	s.SetQuote(symbol, price, price)
+}
+
+func (s *Server) SetQuote(symbol string, bid float64, ask float64) {
+	/*
+		Set the bid and ask of a market (last becomes the mid price) and match resting orders against them
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	market, ok := s.markets[strings.ToLower(symbol)]
+	if !ok {
+		return
+	}
+	market.Bid, market.Ask, market.Last = bid, ask, (bid+ask)/2
+	s.matchMarket(market)
+}
+
+func (s *Server) SetBalance(currency string, amount float64) {
+	/*
+		Set the total exchange balance of a currency; funds held by open orders stay held
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.balance(currency)
+	b.amount = amount
+}
+
+func (s *Server) Deposit(currency string, amount float64) {
+	/*
+		Credit a deposit of currency to the exchange balance and record it in the transfer history
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balance(currency).amount += amount
+	s.addTransfer("Deposit", currency, amount)
+}
+
+func (s *Server) Withdraw(currency string, amount float64) {
+	/*
+		Debit a withdrawal of currency from the exchange balance and record it in the transfer history
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balance(currency).amount -= amount
+	s.addTransfer("Withdrawal", currency, amount)
+}
+
+func (s *Server) Balance(currency string) (amount float64, available float64) {
+	/*
+		Get the total and available exchange balance of a currency
+	*/
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.balance(currency)
+	return b.amount, b.amount - b.held
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.servePrivate(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not supported")
+		return
+	}
+	s.servePublic(w, r)
+}
+
+// authenticate verifies the API key, signature and nonce of a private request and returns its decoded payload
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	apiKey := r.Header.Get("X-GEMINI-APIKEY")
+	encodedPayload := r.Header.Get("X-GEMINI-PAYLOAD")
+	signature := r.Header.Get("X-GEMINI-SIGNATURE")
+	if apiKey == "" {
+		writeError(w, http.StatusBadRequest, "MissingApikeyHeader", "Missing API key header")
+		return nil, false
+	}
+	if apiKey != s.APIKey {
+		writeError(w, http.StatusBadRequest, "InvalidSignature", "InvalidSignature: API key not found")
+		return nil, false
+	}
+	if encodedPayload == "" || signature == "" {
+		writeError(w, http.StatusBadRequest, "MissingPayloadHeader", "Missing payload or signature header")
+		return nil, false
+	}
+	mac := hmac.New(sha512.New384, []byte(s.APISecret))
+	mac.Write([]byte(encodedPayload))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		writeError(w, http.StatusBadRequest, "InvalidSignature", "InvalidSignature")
+		return nil, false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidJson", "Payload is not valid base64")
+		return nil, false
+	}
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(decoded)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidJson", "Payload is not valid JSON")
+		return nil, false
+	}
+	if request, _ := payload["request"].(string); request != r.URL.Path {
+		writeError(w, http.StatusBadRequest, "EndpointMismatch", "Payload request "+request+" does not match "+r.URL.Path)
+		return nil, false
+	}
+
+	nonce, err := strconv.ParseInt(stringField(payload, "nonce"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidNonce", "Nonce must be an integer")
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if nonce <= s.lastNonce {
+		writeError(w, http.StatusBadRequest, "InvalidNonce", "Nonce '"+strconv.FormatInt(nonce, 10)+"' has not increased since your last call to the Gemini API.")
+		return nil, false
+	}
+	s.lastNonce = nonce
+	return payload, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, reason string, message string) {
+	writeJSON(w, status, map[string]string{"result": "error", "reason": reason, "message": message})
+}
+
+func stringField(payload map[string]interface{}, key string) string {
+	switch v := payload[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+func floatField(payload map[string]interface{}, key string) (float64, bool) {
+	value, err := strconv.ParseFloat(stringField(payload, key), 64)
+	return value, err == nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,163 @@
+package geminitest_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+func newClient(t *testing.T, server *geminitest.Server, secret string) *private.Client {
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: secret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+func placeLimitOrder(client *private.Client, side string, amount string, price string, options ...string) (private.Order, error) {
+	var order private.Order
+	payload, _ := json.Marshal(map[string]interface{}{
+		"request": "/v1/order/new", "nonce": util.GenerateNonceString(),
+		"symbol": "btcusd", "amount": amount, "price": price, "side": side, "type": "exchange limit", "options": options,
+	})
+	err := client.PostPrivateEndpoint(payload, &order)
+	return order, err
+}
+
+func TestRejectsInvalidSignature(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+
+	var balances []private.AvailableBalance
+	payload := []byte(`{"request":"/v1/balances","nonce":"` + util.GenerateNonceString() + `"}`)
+	err := newClient(t, server, "wrong-secret").PostPrivateEndpoint(payload, &balances)
+	if err == nil || !strings.Contains(err.Error(), "InvalidSignature") {
+		t.Errorf("expected InvalidSignature, got %v", err)
+	}
+}
+
+func TestRejectsReusedNonce(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client := newClient(t, server, server.APISecret)
+
+	var balances []private.AvailableBalance
+	payload := []byte(`{"request":"/v1/balances","nonce":"` + util.GenerateNonceString() + `"}`)
+	if err := client.PostPrivateEndpoint(payload, &balances); err != nil {
+		t.Fatalf("first request failed: %v", err)
+	}
+	if err := client.PostPrivateEndpoint(payload, &balances); err == nil || !strings.Contains(err.Error(), "InvalidNonce") {
+		t.Errorf("expected InvalidNonce for a reused nonce, got %v", err)
+	}
+}
+
+func TestMatchesLimitOrders(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.SetFeeRate(0.001)
+	client := newClient(t, server, server.APISecret)
+
+	// a buy below the ask rests and holds funds
+	order, err := placeLimitOrder(client, "buy", "0.1", "29000")
+	if err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	if !order.IsLive {
+		t.Fatalf("order below the ask should rest: %+v", order)
+	}
+	if amount, available := server.Balance("USD"); amount != 10000 || available > 10000-2900 {
+		t.Errorf("USD balance %f, available %f after resting buy", amount, available)
+	}
+
+	// it fills once the ask drops to its price, paying the fee in USD
+	server.SetPrice("btcusd", 29000)
+	btc, _ := server.Balance("BTC")
+	usd, available := server.Balance("USD")
+	if btc != 1.1 {
+		t.Errorf("BTC balance %f after fill, expected 1.1", btc)
+	}
+	expectedUSD := 10000 - 0.1*29000*1.001
+	if usd < expectedUSD-1e-6 || usd > expectedUSD+1e-6 || available != usd {
+		t.Errorf("USD balance %f (available %f) after fill, expected %f", usd, available, expectedUSD)
+	}
+
+	// a maker-or-cancel buy at or above the ask is cancelled instead of taking
+	order, err = placeLimitOrder(client, "buy", "0.1", "29000", "maker-or-cancel")
+	if err != nil {
+		t.Fatalf("maker-or-cancel LimitBuy failed: %v", err)
+	}
+	if order.IsLive || !order.IsCancelled {
+		t.Errorf("crossing maker-or-cancel order should be cancelled: %+v", order)
+	}
+
+	// selling more than the balance is rejected
+	if _, err := placeLimitOrder(client, "sell", "5", "30000"); err == nil || !strings.Contains(err.Error(), "InsufficientFunds") {
+		t.Errorf("expected InsufficientFunds, got %v", err)
+	}
+}
+
+func TestFillsRestingOrdersAtTheirPrice(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client := newClient(t, server, server.APISecret)
+
+	buy, err := placeLimitOrder(client, "buy", "0.1", "29000")
+	if err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	sell, err := placeLimitOrder(client, "sell", "0.1", "31000")
+	if err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+
+	// resting orders are makers: a quote moving through them fills them at their limit price, not at the quote
+	server.SetPrice("btcusd", 28000)
+	server.SetPrice("btcusd", 32000)
+	for _, placed := range []private.Order{buy, sell} {
+		id, _ := strconv.Atoi(placed.OrderID)
+		order, err := client.GetOrderStatus(id)
+		if err != nil {
+			t.Fatalf("GetOrderStatus failed: %v", err)
+		}
+		if order.IsLive || order.AvgExecutionPrice != placed.Price {
+			t.Errorf("resting %s should fill at %s: %+v", placed.Side, placed.Price, order)
+		}
+	}
+
+	// an order crossing when placed takes the quote
+	taker, err := placeLimitOrder(client, "buy", "0.1", "33000")
+	if err != nil || taker.IsLive || taker.AvgExecutionPrice != "32000" {
+		t.Errorf("crossing buy should fill at the ask: %+v, %v", taker, err)
+	}
+}
+
+func TestTriggersStopLimitOrders(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client := newClient(t, server, server.APISecret)
+
+	var order private.Order
+	payload, _ := json.Marshal(private.StopLimitOrderRequest{
+		Amount: "0.5", Price: "27000", StopPrice: "28000", Side: "sell", Symbol: "btcusd",
+		Type: "exchange stop limit", Request: "/v1/order/new", Nonce: util.GenerateNonceString(),
+	})
+	if err := client.PostPrivateEndpoint(payload, &order); err != nil {
+		t.Fatalf("StopLimitSell failed: %v", err)
+	}
+	server.SetPrice("btcusd", 29000)
+	if btc, _ := server.Balance("BTC"); btc != 1 {
+		t.Errorf("stop sell filled before its stop price: BTC %f", btc)
+	}
+	server.SetPrice("btcusd", 27500)
+	if btc, _ := server.Balance("BTC"); btc != 0.5 {
+		t.Errorf("stop sell did not fill after triggering: BTC %f", btc)
+	}
+	if usd, _ := server.Balance("USD"); usd != 10000+0.5*27500 {
+		t.Errorf("USD balance %f after stop sell", usd)
+	}
+}
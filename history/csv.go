@@ -0,0 +1,104 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/backtest"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// CSVStore keeps the candles of each symbol and time frame in a CSV file of a directory, named like btcusd_1m.csv,
+// in the format of backtest.LoadCandlesCSV
+type CSVStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+var _ Store = (*CSVStore)(nil)
+
+func NewCSVStore(dir string) (*CSVStore, error) {
+	/*
+		Create a store in dir, creating the directory if needed. Files already in it are resumed from
+	*/
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.New("error creating candles directory: " + err.Error())
+	}
+	return &CSVStore{dir: dir}, nil
+}
+
+func (s *CSVStore) Path(symbol string, timeFrame string) string {
+	/*
+		Get the file the candles of symbol and timeFrame are kept in
+	*/
+	return filepath.Join(s.dir, strings.ToLower(symbol)+"_"+timeFrame+".csv")
+}
+
+func (s *CSVStore) UpsertCandles(ctx context.Context, symbol string, timeFrame string, candles []public.Candle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.load(symbol, timeFrame)
+	if err != nil {
+		return err
+	}
+	merged := make(map[int64]public.Candle, len(existing)+len(candles))
+	for _, candle := range existing {
+		merged[candle.Time.UnixMilli()] = candle
+	}
+	for _, candle := range candles {
+		merged[candle.Time.UnixMilli()] = candle
+	}
+	all := make([]public.Candle, 0, len(merged))
+	for _, candle := range merged {
+		all = append(all, candle)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Time.Before(all[j].Time) })
+
+	// replace the file atomically, so an interrupted write never truncates the history
+	var buf bytes.Buffer
+	if err := backtest.WriteCandlesCSV(&buf, all); err != nil {
+		return errors.New("error writing candles file: " + err.Error())
+	}
+	if err := util.WriteFileAtomic(s.Path(symbol, timeFrame), buf.Bytes(), 0o600); err != nil {
+		return errors.New("error writing candles file: " + err.Error())
+	}
+	return nil
+}
+
+func (s *CSVStore) Candles(ctx context.Context, symbol string, timeFrame string, from time.Time, to time.Time) ([]public.Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load(symbol, timeFrame)
+	if err != nil {
+		return nil, err
+	}
+	candles := []public.Candle{}
+	for _, candle := range all {
+		if (from.IsZero() || !candle.Time.Before(from)) && (to.IsZero() || candle.Time.Before(to)) {
+			candles = append(candles, candle)
+		}
+	}
+	return candles, nil
+}
+
+// load reads the file of symbol and timeFrame, oldest first; a missing file holds no candles
+func (s *CSVStore) load(symbol string, timeFrame string) ([]public.Candle, error) {
+	path := s.Path(symbol, timeFrame)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	candles, err := backtest.LoadCandlesCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	return candles, nil
+}
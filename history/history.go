@@ -0,0 +1,254 @@
+// Package history downloads candle history into a local store. The candles endpoint only returns the latest window
+// of each time frame, so a Downloader polls it repeatedly and merges every window into a Store, building a history
+// longer than any single response. Each sync resumes from what is already stored, fills holes the fetched window
+// covers, and reports the gaps left where no sync ran within a window's length.
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/bot"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/storage"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// Store persists candles by symbol, time frame and time. CSVStore and every storage.Repository implement it.
+type Store interface {
+	UpsertCandles(ctx context.Context, symbol string, timeFrame string, candles []public.Candle) error
+	// Candles returns the candles in [from, to), oldest first; zero times leave the range open
+	Candles(ctx context.Context, symbol string, timeFrame string, from time.Time, to time.Time) ([]public.Candle, error)
+}
+
+var _ Store = storage.Repository(nil)
+
+// CandleFunc fetches the latest candles of a symbol and time frame
+type CandleFunc func(symbol string, timeFrame string) ([]public.Candle, error)
+
+// timeFrames are the durations of the candle time frames of the API
+var timeFrames = map[string]time.Duration{
+	"1m":   time.Minute,
+	"5m":   5 * time.Minute,
+	"15m":  15 * time.Minute,
+	"30m":  30 * time.Minute,
+	"1hr":  time.Hour,
+	"6hr":  6 * time.Hour,
+	"1day": 24 * time.Hour,
+}
+
+// Gap is a run of missing candles, starting at From and ending before To
+type Gap struct {
+	From    time.Time
+	To      time.Time
+	Missing int
+}
+
+// SyncResult describes one sync of a symbol and time frame
+type SyncResult struct {
+	Symbol    string
+	TimeFrame string
+	// Fetched counts the completed candles returned by the endpoint
+	Fetched int
+	// Added counts the fetched candles that were not stored yet, of which Filled landed in gaps of the stored history
+	Added  int
+	Filled int
+	// Total, First and Last describe the stored history after the sync
+	Total int
+	First time.Time
+	Last  time.Time
+	// Gaps are the holes left in the stored history
+	Gaps []Gap
+}
+
+func (r SyncResult) String() string {
+	missing := 0
+	for _, gap := range r.Gaps {
+		missing += gap.Missing
+	}
+	return fmt.Sprintf("%s %s: fetched %d, added %d (%d gap-filled), %d stored from %s to %s, %d gaps (%d candles missing)",
+		r.Symbol, r.TimeFrame, r.Fetched, r.Added, r.Filled, r.Total,
+		r.First.Format(time.RFC3339), r.Last.Format(time.RFC3339), len(r.Gaps), missing)
+}
+
+// Option configures optional behavior of a Downloader
+type Option func(*Downloader)
+
+func WithFetcher(fetch CandleFunc) Option {
+	/*
+		Fetch candles with fetch instead of the REST candles endpoint
+	*/
+	return func(d *Downloader) {
+		d.fetch = fetch
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	/*
+		Use the given clock instead of time.Now to tell completed candles from the one in progress
+	*/
+	return func(d *Downloader) {
+		d.now = now
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	/*
+		Log through the given logger instead of util.Logger()
+	*/
+	return func(d *Downloader) {
+		d.logger = logger
+	}
+}
+
+// Downloader merges the candles endpoint's latest windows into a Store
+type Downloader struct {
+	store  Store
+	fetch  CandleFunc
+	now    func() time.Time
+	logger *slog.Logger
+}
+
+func NewDownloader(store Store, opts ...Option) *Downloader {
+	/*
+		Create a downloader writing to store. Call Sync or SyncAll once per run, or Run to keep polling
+	*/
+	d := &Downloader{store: store, fetch: bot.PollingMarketData{}.Candles, now: time.Now}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.logger == nil {
+		d.logger = util.Logger()
+	}
+	return d
+}
+
+func (d *Downloader) Sync(ctx context.Context, symbol string, timeFrame string) (SyncResult, error) {
+	/*
+		Fetch the latest candles of symbol and timeFrame, store the completed ones not stored yet, and report the
+		gaps left in the stored history. The candle still in progress is skipped; stored candles are never rewritten
+
+		Args:
+		symbol (string): Trading pair symbol
+		timeFrame (string): Time range for each candle: 1m, 5m, 15m, 30m, 1hr, 6hr or 1day
+	*/
+	result := SyncResult{Symbol: symbol, TimeFrame: timeFrame}
+	frame, ok := timeFrames[timeFrame]
+	if !ok {
+		return result, errors.New("unknown candle time frame " + timeFrame)
+	}
+	stored, err := d.store.Candles(ctx, symbol, timeFrame, time.Time{}, time.Time{})
+	if err != nil {
+		return result, fmt.Errorf("reading stored %s %s candles failed: %w", symbol, timeFrame, err)
+	}
+	fetched, err := d.fetch(symbol, timeFrame)
+	if err != nil {
+		return result, fmt.Errorf("fetching %s %s candles failed: %w", symbol, timeFrame, err)
+	}
+
+	seen := make(map[int64]bool, len(stored))
+	for _, candle := range stored {
+		seen[candle.Time.UnixMilli()] = true
+	}
+	var last time.Time
+	if len(stored) > 0 {
+		last = stored[len(stored)-1].Time
+	}
+	now := d.now()
+	var added []public.Candle
+	for _, candle := range fetched {
+		if candle.Time.Add(frame).After(now) {
+			continue // in progress
+		}
+		result.Fetched++
+		if seen[candle.Time.UnixMilli()] {
+			continue
+		}
+		seen[candle.Time.UnixMilli()] = true
+		added = append(added, candle)
+		if candle.Time.Before(last) {
+			result.Filled++
+		}
+	}
+	if len(added) > 0 {
+		if err := d.store.UpsertCandles(ctx, symbol, timeFrame, added); err != nil {
+			return result, fmt.Errorf("storing %s %s candles failed: %w", symbol, timeFrame, err)
+		}
+	}
+	result.Added = len(added)
+
+	merged := append(stored, added...)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Time.Before(merged[j].Time) })
+	result.Total = len(merged)
+	if len(merged) > 0 {
+		result.First, result.Last = merged[0].Time, merged[len(merged)-1].Time
+	}
+	result.Gaps = Gaps(merged, frame)
+	d.logger.Info("candles synced", "symbol", symbol, "time_frame", timeFrame, "added", result.Added,
+		"filled", result.Filled, "total", result.Total, "gaps", len(result.Gaps))
+	return result, nil
+}
+
+func (d *Downloader) SyncAll(ctx context.Context, symbols []string, timeFrames []string) ([]SyncResult, error) {
+	/*
+		Sync every combination of symbols and timeFrames. A failing combination doesn't stop the others; its error is
+		joined into the returned error and it has no result
+	*/
+	var results []SyncResult
+	var errs []error
+	for _, symbol := range symbols {
+		for _, timeFrame := range timeFrames {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			result, err := d.Sync(ctx, symbol, timeFrame)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			results = append(results, result)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (d *Downloader) Run(ctx context.Context, symbols []string, timeFrames []string, interval time.Duration, report func([]SyncResult)) error {
+	/*
+		SyncAll now and every interval until the context is done, passing each round's results to report, if not nil.
+		To avoid gaps, interval must be shorter than the window the endpoint returns for the smallest time frame
+	*/
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		results, err := d.SyncAll(ctx, symbols, timeFrames)
+		if err != nil && ctx.Err() == nil {
+			d.logger.Error("candle sync failed", "error", err)
+		}
+		if report != nil {
+			report(results)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func Gaps(candles []public.Candle, frame time.Duration) []Gap {
+	/*
+		Find the missing candles between consecutive candles, which must be ordered oldest first
+	*/
+	var gaps []Gap
+	for i := 1; i < len(candles); i++ {
+		from, to := candles[i-1].Time.Add(frame), candles[i].Time
+		if to.After(from) {
+			gaps = append(gaps, Gap{From: from, To: to, Missing: int(to.Sub(from) / frame)})
+		}
+	}
+	return gaps
+}
@@ -0,0 +1,128 @@
+package history
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/storage"
+)
+
+func TestDownloaderResumes(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	t.Setenv("GEMINI_EXCHANGE_API_BASE_URL", server.URL)
+	now := time.Date(2024, 3, 1, 12, 0, 30, 0, time.UTC)
+	clock := func() time.Time { return now }
+	server.SetClock(clock)
+
+	dir := t.TempDir()
+	store, err := NewCSVStore(dir)
+	if err != nil {
+		t.Fatalf("NewCSVStore failed: %v", err)
+	}
+	ctx := context.Background()
+	downloader := NewDownloader(store, WithClock(clock))
+
+	// the mock exchange returns 60 candles; the newest is still in progress
+	results, err := downloader.SyncAll(ctx, []string{"btcusd", "ethusd"}, []string{"1m", "1hr"})
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Fetched != 59 || result.Added != 59 || result.Total != 59 || len(result.Gaps) != 0 {
+			t.Errorf("first sync %s", result)
+		}
+	}
+	if _, err := os.Stat(store.Path("btcusd", "1hr")); err != nil {
+		t.Errorf("expected candles file: %v", err)
+	}
+
+	// ten minutes later only the new candles are added
+	now = now.Add(10 * time.Minute)
+	result, err := downloader.Sync(ctx, "btcusd", "1m")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Added != 10 || result.Total != 69 || len(result.Gaps) != 0 {
+		t.Errorf("incremental sync %s", result)
+	}
+	if want := time.Date(2024, 3, 1, 12, 9, 0, 0, time.UTC); !result.Last.Equal(want) {
+		t.Errorf("expected last candle at %s, got %s", want, result.Last)
+	}
+
+	// a restart two hours later resumes from the files, leaving a gap the endpoint no longer covers
+	now = now.Add(2 * time.Hour)
+	store, err = NewCSVStore(dir)
+	if err != nil {
+		t.Fatalf("NewCSVStore failed: %v", err)
+	}
+	result, err = NewDownloader(store, WithClock(clock)).Sync(ctx, "btcusd", "1m")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Added != 59 || result.Total != 128 || len(result.Gaps) != 1 {
+		t.Fatalf("sync after restart %s", result)
+	}
+	gap := result.Gaps[0]
+	if !gap.From.Equal(time.Date(2024, 3, 1, 12, 10, 0, 0, time.UTC)) || gap.Missing != 61 {
+		t.Errorf("gap %+v", gap)
+	}
+
+	if _, err := downloader.Sync(ctx, "btcusd", "2m"); err == nil {
+		t.Error("expected an error for an unknown time frame")
+	}
+}
+
+func TestDownloaderFillsGaps(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	series := func(from int, to int) []public.Candle {
+		var candles []public.Candle
+		for i := from; i < to; i++ {
+			candles = append(candles, public.Candle{Time: start.Add(time.Duration(i) * time.Hour), Close: float64(i)})
+		}
+		return candles
+	}
+	store := storage.NewMemoryStore()
+	ctx := context.Background()
+	// hours 3 and 4 are missing
+	if err := store.UpsertCandles(ctx, "btcusd", "1hr", append(series(0, 3), series(5, 8)...)); err != nil {
+		t.Fatalf("UpsertCandles failed: %v", err)
+	}
+	fetch := func(symbol string, timeFrame string) ([]public.Candle, error) {
+		return series(2, 10), nil
+	}
+	downloader := NewDownloader(store, WithFetcher(fetch), WithClock(func() time.Time { return start.Add(10 * time.Hour) }))
+
+	result, err := downloader.Sync(ctx, "btcusd", "1hr")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Fetched != 8 || result.Added != 4 || result.Filled != 2 || result.Total != 10 || len(result.Gaps) != 0 {
+		t.Errorf("gap filling sync %s", result)
+	}
+	candles, err := store.Candles(ctx, "btcusd", "1hr", start.Add(3*time.Hour), start.Add(5*time.Hour))
+	if err != nil || len(candles) != 2 || candles[0].Close != 3 {
+		t.Errorf("filled candles %+v, %v", candles, err)
+	}
+
+	// syncing the same window again adds nothing
+	if result, err = downloader.Sync(ctx, "btcusd", "1hr"); err != nil || result.Added != 0 {
+		t.Errorf("repeated sync %s, %v", result, err)
+	}
+}
+
+func TestGaps(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	candles := []public.Candle{{Time: start}, {Time: start.Add(5 * time.Minute)}, {Time: start.Add(25 * time.Minute)}}
+	gaps := Gaps(candles, 5*time.Minute)
+	if len(gaps) != 1 || !gaps[0].From.Equal(start.Add(10*time.Minute)) || !gaps[0].To.Equal(start.Add(25*time.Minute)) || gaps[0].Missing != 3 {
+		t.Errorf("gaps %+v", gaps)
+	}
+}
@@ -0,0 +1,348 @@
+// Package killswitch cancels every active order of an account and optionally flattens it, selling every non-quote
+// balance with aggressive immediate-or-cancel limit orders.
+package killswitch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// DefaultSlippage is how far below the bid flattening sells are priced
+const DefaultSlippage = 0.05
+
+// Config configures a kill switch run
+type Config struct {
+	// Flatten sells every balance other than QuoteCurrency after cancelling the orders
+	Flatten bool
+	// DryRun reports what would be cancelled and sold without doing it
+	DryRun bool
+	// Exclude lists symbols whose orders are kept and whose base currency is not sold, e.g. "ethusd"
+	Exclude []string
+	// QuoteCurrency is the currency balances are sold for (default "USD")
+	QuoteCurrency string
+	// Slippage prices each sell this fraction below the bid so it crosses the book (default DefaultSlippage)
+	Slippage float64
+	// Logger receives a line per action (default util.Logger())
+	Logger *slog.Logger
+}
+
+// Cancellation is an order the kill switch cancelled, or would cancel in a dry run
+type Cancellation struct {
+	Order private.Order
+	Error string
+}
+
+// Sale is a balance the kill switch sold, or would sell in a dry run
+type Sale struct {
+	Symbol   string
+	Currency string
+	Amount   float64
+	Price    float64
+	OrderID  string
+	Executed float64
+	AvgPrice string
+	Error    string
+}
+
+// Skip is a balance the kill switch left alone, with the reason
+type Skip struct {
+	Currency string
+	Amount   float64
+	Reason   string
+}
+
+// Report summarizes a kill switch run
+type Report struct {
+	DryRun    bool
+	Cancelled []Cancellation
+	Kept      []private.Order
+	Sold      []Sale
+	Skipped   []Skip
+}
+
+// details are the symbol details the kill switch needs
+type details struct {
+	base           string
+	tickSize       float64
+	quoteIncrement float64
+	minOrderSize   float64
+}
+
+type run struct {
+	trader  private.Trader
+	config  Config
+	details map[string]*details
+}
+
+func Run(trader private.Trader, config Config) (*Report, error) {
+	/*
+		Cancel every active order, except those on excluded symbols, and with config.Flatten sell every balance other
+		than the quote currency with an immediate-or-cancel limit order config.Slippage below the bid. Without
+		exclusions, a trader with CancelAllOrders, like *private.Client, cancels everything with a single request.
+		Failures of single orders are recorded in the report and joined in the returned error; the run carries on.
+
+		Args:
+		trader (private.Trader): trader to cancel and sell with, e.g. private.DefaultClient()
+		config (Config): what to do
+
+		Returns the report of what was (or, in a dry run, would be) cancelled, kept, sold and skipped
+	*/
+	if config.QuoteCurrency == "" {
+		config.QuoteCurrency = "USD"
+	}
+	if config.Slippage <= 0 {
+		config.Slippage = DefaultSlippage
+	}
+	if config.Logger == nil {
+		config.Logger = util.Logger()
+	}
+	config.Logger = config.Logger.With("killswitch", true, "dry_run", config.DryRun)
+	r := &run{trader: trader, config: config, details: map[string]*details{}}
+	report := &Report{DryRun: config.DryRun}
+
+	active, err := trader.GetActiveOrders()
+	if err != nil {
+		return report, errors.New("error getting active orders: " + err.Error())
+	}
+	var errs []error
+	// released is the base currency the cancelled sells held, which a dry run can't see in the balances
+	released := map[string]float64{}
+	if canceller, ok := trader.(allCanceller); ok && !config.DryRun && len(config.Exclude) == 0 {
+		// one request also cancels orders placed since the list was read
+		report.Cancelled, err = r.cancelAll(canceller, active)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		active = nil
+	}
+	for _, order := range active {
+		if r.excluded(order.Symbol) {
+			report.Kept = append(report.Kept, order)
+			continue
+		}
+		cancellation := Cancellation{Order: order}
+		if config.DryRun {
+			if order.Side == "sell" {
+				if d, err := r.symbolDetails(order.Symbol); err == nil {
+					original, _ := strconv.ParseFloat(order.OriginalAmount, 64)
+					executed, _ := strconv.ParseFloat(order.ExecutedAmount, 64)
+					released[d.base] += original - executed
+				}
+			}
+		} else if err := r.cancel(order); err != nil {
+			cancellation.Error = err.Error()
+			errs = append(errs, err)
+		}
+		config.Logger.Info("cancel order", "order_id", order.OrderID, "symbol", order.Symbol, "side", order.Side, "error", cancellation.Error)
+		report.Cancelled = append(report.Cancelled, cancellation)
+	}
+	if !config.Flatten {
+		return report, errors.Join(errs...)
+	}
+
+	balances, err := trader.GetAvailableBalances()
+	if err != nil {
+		return report, errors.Join(append(errs, errors.New("error getting balances: "+err.Error()))...)
+	}
+	for _, balance := range balances {
+		if strings.EqualFold(balance.Currency, config.QuoteCurrency) {
+			continue
+		}
+		available, _ := strconv.ParseFloat(balance.Available, 64)
+		available += released[strings.ToUpper(balance.Currency)]
+		if available <= 0 {
+			continue
+		}
+		sale, skip, err := r.sell(strings.ToUpper(balance.Currency), available)
+		switch {
+		case skip != nil:
+			config.Logger.Info("skip balance", "currency", skip.Currency, "amount", skip.Amount, "reason", skip.Reason)
+			report.Skipped = append(report.Skipped, *skip)
+		case sale != nil:
+			if err != nil {
+				errs = append(errs, err)
+			}
+			config.Logger.Info("sell balance", "symbol", sale.Symbol, "amount", sale.Amount, "price", sale.Price, "executed", sale.Executed, "error", sale.Error)
+			report.Sold = append(report.Sold, *sale)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+func (r *run) excluded(symbol string) bool {
+	for _, excluded := range r.config.Exclude {
+		if strings.EqualFold(excluded, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// allCanceller is implemented by traders that can cancel every order in one request, like *private.Client
+type allCanceller interface {
+	CancelAllOrders() (*private.CancelAllOrdersResponse, error)
+}
+
+// cancelAll cancels every order of the account with one request. Orders missing from active, placed after it was
+// read, are reported by id only
+func (r *run) cancelAll(canceller allCanceller, active []private.Order) ([]Cancellation, error) {
+	response, err := canceller.CancelAllOrders()
+	if err != nil {
+		err = errors.New("error cancelling all orders: " + err.Error())
+		var cancellations []Cancellation
+		for _, order := range active {
+			cancellations = append(cancellations, Cancellation{Order: order, Error: err.Error()})
+		}
+		return cancellations, err
+	}
+	known := map[string]private.Order{}
+	for _, order := range active {
+		known[order.OrderID] = order
+	}
+	var cancellations []Cancellation
+	var errs []error
+	for _, ids := range []struct {
+		ids      []int64
+		rejected bool
+	}{{response.Details.CancelledOrders, false}, {response.Details.CancelRejects, true}} {
+		for _, id := range ids.ids {
+			orderID := strconv.FormatInt(id, 10)
+			order, ok := known[orderID]
+			if !ok {
+				order = private.Order{OrderID: orderID, ID: orderID}
+			}
+			cancellation := Cancellation{Order: order}
+			if ids.rejected {
+				cancellation.Error = "cancel rejected"
+				errs = append(errs, errors.New("error cancelling order "+orderID+": cancel rejected"))
+			}
+			r.config.Logger.Info("cancel order", "order_id", orderID, "symbol", order.Symbol, "side", order.Side, "error", cancellation.Error)
+			cancellations = append(cancellations, cancellation)
+		}
+	}
+	return cancellations, errors.Join(errs...)
+}
+
+func (r *run) cancel(order private.Order) error {
+	id, err := strconv.Atoi(order.OrderID)
+	if err != nil {
+		return errors.New("order has non-numeric id " + order.OrderID)
+	}
+	if _, err := r.trader.CancelOrder(id); err != nil {
+		return fmt.Errorf("error cancelling order %s: %w", order.OrderID, err)
+	}
+	return nil
+}
+
+// sell sells amount of currency for the quote currency, or explains why it is skipped
+func (r *run) sell(currency string, amount float64) (*Sale, *Skip, error) {
+	symbol := strings.ToLower(currency + r.config.QuoteCurrency)
+	if r.excluded(symbol) {
+		return nil, &Skip{Currency: currency, Amount: amount, Reason: symbol + " is excluded"}, nil
+	}
+	d, err := r.symbolDetails(symbol)
+	if err != nil {
+		return nil, &Skip{Currency: currency, Amount: amount, Reason: "no " + symbol + " market: " + err.Error()}, nil
+	}
+	sellAmount := util.FloorTo(amount, d.tickSize)
+	if sellAmount < d.minOrderSize || sellAmount <= 0 {
+		return nil, &Skip{Currency: currency, Amount: amount, Reason: "below the minimum order size of " + symbol}, nil
+	}
+	sale := &Sale{Symbol: symbol, Currency: currency, Amount: sellAmount}
+	var ticker public.TickerV2
+	if err := public.GetPublicEndpoint("/v2/ticker/"+symbol, &ticker); err != nil {
+		sale.Error = "error getting the " + symbol + " ticker: " + err.Error()
+		return sale, nil, errors.New(sale.Error)
+	}
+	bid, err := strconv.ParseFloat(ticker.Bid, 64)
+	if err != nil || bid <= 0 {
+		sale.Error = "no bid for " + symbol
+		return sale, nil, errors.New(sale.Error)
+	}
+	sale.Price = util.FloorTo(bid*(1-r.config.Slippage), d.quoteIncrement)
+	if r.config.DryRun {
+		return sale, nil, nil
+	}
+	order, err := r.trader.LimitSell(symbol, sellAmount, sale.Price, "immediate-or-cancel")
+	if err != nil {
+		sale.Error = err.Error()
+		return sale, nil, fmt.Errorf("error selling %s: %w", symbol, err)
+	}
+	sale.OrderID = order.OrderID
+	sale.Executed, _ = strconv.ParseFloat(order.ExecutedAmount, 64)
+	sale.AvgPrice = order.AvgExecutionPrice
+	return sale, nil, nil
+}
+
+func (r *run) symbolDetails(symbol string) (*details, error) {
+	symbol = strings.ToLower(symbol)
+	if d, ok := r.details[symbol]; ok {
+		return d, nil
+	}
+	var response map[string]interface{}
+	if err := public.GetPublicEndpoint("/v1/symbols/details/"+symbol, &response); err != nil {
+		return nil, err
+	}
+	d := &details{}
+	base, _ := response["base_currency"].(string)
+	d.base = strings.ToUpper(base)
+	for key, target := range map[string]*float64{"tick_size": &d.tickSize, "quote_increment": &d.quoteIncrement, "min_order_size": &d.minOrderSize} {
+		switch v := response[key].(type) {
+		case float64:
+			*target = v
+		case string:
+			*target, _ = strconv.ParseFloat(v, 64)
+		}
+	}
+	r.details[symbol] = d
+	return d, nil
+}
+
+func (r *Report) Write(w io.Writer) error {
+	/*
+		Write a human readable summary of the report
+	*/
+	verb := map[bool]string{true: "Would cancel", false: "Cancelled"}[r.DryRun]
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d order(s)\n", verb, len(r.Cancelled))
+	for _, c := range r.Cancelled {
+		fmt.Fprintf(&b, "  %s %s %s %s @ %s", c.Order.OrderID, c.Order.Symbol, c.Order.Side, c.Order.OriginalAmount, c.Order.Price)
+		if c.Error != "" {
+			fmt.Fprintf(&b, " FAILED: %s", c.Error)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Kept) > 0 {
+		fmt.Fprintf(&b, "Kept %d order(s) on excluded symbols\n", len(r.Kept))
+		for _, o := range r.Kept {
+			fmt.Fprintf(&b, "  %s %s %s %s @ %s\n", o.OrderID, o.Symbol, o.Side, o.OriginalAmount, o.Price)
+		}
+	}
+	verb = map[bool]string{true: "Would sell", false: "Sold"}[r.DryRun]
+	if len(r.Sold) > 0 {
+		fmt.Fprintf(&b, "%s %d balance(s)\n", verb, len(r.Sold))
+		for _, s := range r.Sold {
+			fmt.Fprintf(&b, "  %s %v at or above %v", s.Symbol, s.Amount, s.Price)
+			switch {
+			case s.Error != "":
+				fmt.Fprintf(&b, " FAILED: %s", s.Error)
+			case !r.DryRun:
+				fmt.Fprintf(&b, ": executed %v at %s (order %s)", s.Executed, s.AvgPrice, s.OrderID)
+			}
+			b.WriteString("\n")
+		}
+	}
+	for _, s := range r.Skipped {
+		fmt.Fprintf(&b, "Skipped %v %s: %s\n", s.Amount, s.Currency, s.Reason)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
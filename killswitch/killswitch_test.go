@@ -0,0 +1,141 @@
+package killswitch
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+func newTrader(t *testing.T) (*geminitest.Server, *private.Client) {
+	server := geminitest.NewServer()
+	t.Cleanup(server.Close)
+	for key, value := range server.Env() {
+		t.Setenv(key, value)
+	}
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return server, client
+}
+
+func TestKillSwitch(t *testing.T) {
+	server, client := newTrader(t)
+	server.SetBalance("GUSD", 0)
+	server.SetBalance("MATIC", 0)
+	if _, err := client.LimitSell("btcusd", 0.5, 35000); err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+	if _, err := client.LimitBuy("btcusd", 0.1, 25000); err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	kept, err := client.LimitSell("ethusd", 1, 2500)
+	if err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+
+	// a dry run reports the full BTC balance, including the amount held by the sell it would cancel
+	report, err := Run(client, Config{Flatten: true, DryRun: true, Exclude: []string{"ETHUSD"}})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if len(report.Cancelled) != 2 || len(report.Kept) != 1 || len(report.Sold) != 1 || len(report.Skipped) != 1 {
+		t.Fatalf("dry run report %+v", report)
+	}
+	if sale := report.Sold[0]; sale.Symbol != "btcusd" || sale.Amount != 1 || sale.Price != 28500 || sale.OrderID != "" {
+		t.Errorf("dry run sale %+v", sale)
+	}
+	if active, _ := client.GetActiveOrders(); len(active) != 3 {
+		t.Errorf("dry run cancelled orders: %d still active", len(active))
+	}
+
+	report, err = Run(client, Config{Flatten: true, Exclude: []string{"ethusd"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	active, _ := client.GetActiveOrders()
+	if len(active) != 1 || active[0].OrderID != kept.OrderID {
+		t.Errorf("only the excluded order should remain: %+v", active)
+	}
+	if btc, _ := server.Balance("BTC"); btc != 0 {
+		t.Errorf("BTC balance %v after flattening", btc)
+	}
+	if usd, _ := server.Balance("USD"); usd != 40000 {
+		t.Errorf("USD balance %v after flattening", usd)
+	}
+	if sale := report.Sold[0]; sale.Executed != 1 || sale.AvgPrice != "30000" {
+		t.Errorf("sale %+v", sale)
+	}
+
+	var summary bytes.Buffer
+	report.Write(&summary)
+	for _, line := range []string{"Cancelled 2 order(s)", "Kept 1 order(s)", "Sold 1 balance(s)", "Skipped 9 ETH: ethusd is excluded"} {
+		if !strings.Contains(summary.String(), line) {
+			t.Errorf("summary is missing %q:\n%s", line, summary.String())
+		}
+	}
+}
+
+func TestKillSwitchCancelOnly(t *testing.T) {
+	server, client := newTrader(t)
+	order, _ := client.LimitBuy("btcusd", 0.1, 25000)
+	report, err := Run(client, Config{})
+	if err != nil || len(report.Cancelled) != 1 || len(report.Sold) != 0 {
+		t.Fatalf("Run returned %+v, %v", report, err)
+	}
+	id, _ := strconv.Atoi(order.OrderID)
+	if status, _ := client.GetOrderStatus(id); !status.IsCancelled {
+		t.Errorf("order not cancelled: %+v", status)
+	}
+	if btc, _ := server.Balance("BTC"); btc != 1 {
+		t.Errorf("BTC should not be sold without Flatten, balance %v", btc)
+	}
+}
+
+// racingTrader places another order right after the active orders are listed
+type racingTrader struct {
+	*private.Client
+	late *private.Order
+}
+
+func (r *racingTrader) GetActiveOrders() ([]private.Order, error) {
+	active, err := r.Client.GetActiveOrders()
+	if err == nil && r.late == nil {
+		r.late, err = r.Client.LimitBuy("btcusd", 0.1, 24000)
+	}
+	return active, err
+}
+
+func TestKillSwitchCancelAll(t *testing.T) {
+	server, client := newTrader(t)
+	for _, currency := range []string{"BTC", "ETH", "MATIC", "GUSD"} {
+		server.SetBalance(currency, 0)
+	}
+	server.SetBalance("AMP", 1000)
+	listed, _ := client.LimitBuy("btcusd", 0.1, 25000)
+	trader := &racingTrader{Client: client}
+
+	report, err := Run(trader, Config{Flatten: true})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if active, _ := client.GetActiveOrders(); len(active) != 0 {
+		t.Errorf("the order placed after listing should be cancelled too: %+v", active)
+	}
+	if len(report.Cancelled) != 2 || report.Cancelled[0].Order.OrderID != listed.OrderID || report.Cancelled[0].Order.Symbol != "btcusd" ||
+		report.Cancelled[1].Order.OrderID != trader.late.OrderID {
+		t.Errorf("cancellations %+v", report.Cancelled)
+	}
+
+	// ampusd is quoted in 0.00001 USD: the sell is priced 5% below the 0.005 bid, not rounded to cents
+	if len(report.Sold) != 1 || report.Sold[0].Price != 0.00475 || report.Sold[0].Executed != 1000 || report.Sold[0].Error != "" {
+		t.Errorf("sales %+v", report.Sold)
+	}
+	if amp, _ := server.Balance("AMP"); amp != 0 {
+		t.Errorf("AMP balance %v after flattening", amp)
+	}
+}
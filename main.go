@@ -1,18 +1,153 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/austinjhunt/go-gemini/bot"
+	"github.com/austinjhunt/go-gemini/history"
+	"github.com/austinjhunt/go-gemini/killswitch"
+	"github.com/austinjhunt/go-gemini/private"
 	"github.com/austinjhunt/go-gemini/public"
+	_ "github.com/austinjhunt/go-gemini/strategy" // registers the built-in strategies
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "killswitch" {
+		killSwitch(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "candles" {
+		downloadCandles(os.Args[2:])
+		return
+	}
 
-	// Start the server
-	symbols := public.GetSymbols()
+	configPath := flag.String("config", "", "path to a JSON bot configuration; without it the available symbols are printed")
+	flag.Parse()
 
-	// Print the response
-	log.Println(symbols) 
+	if *configPath == "" {
+		symbols := public.GetSymbols()
+
+		// Print the response
+		log.Println(symbols)
+		return
+	}
+
+	config, err := bot.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := bot.NewFromConfig(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// SIGINT/SIGTERM stop the bot gracefully, cancelling its open orders; SIGUSR1 pauses it and SIGUSR2 resumes it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	control := make(chan os.Signal, 1)
+	signal.Notify(control, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range control {
+			if sig == syscall.SIGUSR1 {
+				b.Pause()
+			} else {
+				b.Resume()
+			}
+		}
+	}()
+
+	if err := b.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// killSwitch cancels all active orders and optionally flattens the account, after confirmation
+func killSwitch(args []string) {
+	flags := flag.NewFlagSet("killswitch", flag.ExitOnError)
+	flatten := flags.Bool("flatten", false, "also sell every non-USD balance with aggressive immediate-or-cancel limit orders")
+	dryRun := flags.Bool("dry-run", false, "print what would be cancelled and sold without doing it")
+	exclude := flags.String("exclude", "", "comma separated symbols whose orders and base currency are left alone, e.g. ethusd,solusd")
+	slippage := flags.Float64("slippage", killswitch.DefaultSlippage, "fraction below the bid flattening sells are priced at")
+	yes := flags.Bool("yes", false, "skip the confirmation prompt")
+	flags.Parse(args)
+
+	config := killswitch.Config{Flatten: *flatten, DryRun: true, Slippage: *slippage, Exclude: splitList(*exclude)}
+	client := private.DefaultClient()
+
+	// always show the plan first
+	plan, err := killswitch.Run(client, config)
+	plan.Write(os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *dryRun {
+		return
+	}
+	if !*yes {
+		fmt.Print("Type KILL to proceed: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != "KILL" {
+			fmt.Println("Aborted")
+			os.Exit(1)
+		}
+	}
+
+	config.DryRun = false
+	report, err := killswitch.Run(client, config)
+	report.Write(os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
- 
\ No newline at end of file
+// downloadCandles merges the latest candles of symbols and time frames into CSV files, once or repeatedly
+func downloadCandles(args []string) {
+	flags := flag.NewFlagSet("candles", flag.ExitOnError)
+	symbols := flags.String("symbols", "btcusd", "comma separated symbols, e.g. btcusd,ethusd")
+	timeFrames := flags.String("time-frames", "1m", "comma separated time frames: 1m, 5m, 15m, 30m, 1hr, 6hr or 1day")
+	dir := flags.String("dir", "candles", "directory of the CSV files, one per symbol and time frame")
+	every := flags.Duration("every", 0, "keep syncing at this interval, e.g. 30m; sync once if 0")
+	flags.Parse(args)
+
+	store, err := history.NewCSVStore(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	downloader := history.NewDownloader(store)
+	report := func(results []history.SyncResult) {
+		for _, result := range results {
+			fmt.Println(result)
+		}
+	}
+	if *every <= 0 {
+		results, err := downloader.SyncAll(context.Background(), splitList(*symbols), splitList(*timeFrames))
+		report(results)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	downloader.Run(ctx, splitList(*symbols), splitList(*timeFrames), *every, report)
+}
+
+// splitList splits a comma separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,390 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// Option configures optional behavior of a Manager
+type Option func(*Manager)
+
+func WithClock(now func() time.Time) Option {
+	/*
+		Timestamp transitions with the given clock instead of time.Now
+	*/
+	return func(m *Manager) {
+		m.now = now
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	/*
+		Log transitions through the given logger instead of util.Logger()
+	*/
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+func WithHandler(handler func(Transition)) Option {
+	/*
+		Call handler for every state transition. It is called with the manager's lock held and must not call back
+		into the manager
+	*/
+	return func(m *Manager) {
+		m.handlers = append(m.handlers, handler)
+	}
+}
+
+// Reconciliation is the outcome of comparing the tracked orders with the exchange's active orders
+type Reconciliation struct {
+	// Unknown are active orders on the exchange that were not tracked; they are tracked from now on
+	Unknown []TrackedOrder
+	// Missing are orders tracked as open that are no longer active on the exchange; their final state was read with
+	// GetOrderStatus. Orders still open when re-read, e.g. placed after the active orders were read, are not missing
+	Missing []TrackedOrder
+}
+
+// Manager wraps a private.Trader and tracks every order placed through it. It implements private.Trader itself.
+type Manager struct {
+	private.Trader
+	now      func() time.Time
+	logger   *slog.Logger
+	handlers []func(Transition)
+
+	mu        sync.Mutex
+	orders    map[string]*TrackedOrder
+	byOrderID map[string]*TrackedOrder
+}
+
+var _ private.Trader = (*Manager)(nil)
+
+func NewManager(trader private.Trader, opts ...Option) *Manager {
+	/*
+		Create an order manager placing orders with trader
+
+		Args:
+		trader (private.Trader): trader orders are placed with, e.g. private.DefaultClient()
+		opts (...Option): optional behavior, e.g. WithHandler
+
+		Returns a pointer to the Manager
+	*/
+	m := &Manager{Trader: trader, now: time.Now, orders: map[string]*TrackedOrder{}, byOrderID: map[string]*TrackedOrder{}}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.logger == nil {
+		m.logger = util.Logger()
+	}
+	return m
+}
+
+func (m *Manager) LimitBuy(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	return m.LimitOrder("buy", symbol, amount, limitPrice, "", options...)
+}
+
+func (m *Manager) LimitSell(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	return m.LimitOrder("sell", symbol, amount, limitPrice, "", options...)
+}
+
+func (m *Manager) LimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options ...string) (*private.Order, error) {
+	/*
+		Place and track a limit order under clientOrderID, or under the tracked order's ID if it is empty
+	*/
+	tracked := TrackedOrder{ID: util.GenerateUUID(), ClientOrderID: clientOrderID, Symbol: symbol, Side: side, Type: "exchange limit", Amount: amount, Price: limitPrice}
+	if tracked.ClientOrderID == "" {
+		tracked.ClientOrderID = tracked.ID
+	}
+	return m.place(tracked, func() (*private.Order, error) {
+		return m.Trader.LimitOrder(side, symbol, amount, limitPrice, tracked.ClientOrderID, options...)
+	})
+}
+
+func (m *Manager) StopLimitBuy(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	return m.place(TrackedOrder{Symbol: symbol, Side: "buy", Type: "exchange stop limit", Amount: amount, Price: limitPrice, StopPrice: stopPrice}, func() (*private.Order, error) {
+		return m.Trader.StopLimitBuy(symbol, amount, stopPrice, limitPrice)
+	})
+}
+
+func (m *Manager) StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	return m.place(TrackedOrder{Symbol: symbol, Side: "sell", Type: "exchange stop limit", Amount: amount, Price: limitPrice, StopPrice: stopPrice}, func() (*private.Order, error) {
+		return m.Trader.StopLimitSell(symbol, amount, stopPrice, limitPrice)
+	})
+}
+
+func (m *Manager) CancelOrder(order_id int) (*private.Order, error) {
+	order, err := m.Trader.CancelOrder(order_id)
+	if err == nil {
+		m.Apply(order)
+	}
+	return order, err
+}
+
+func (m *Manager) GetOrderStatus(order_id int) (*private.Order, error) {
+	order, err := m.Trader.GetOrderStatus(order_id)
+	if err == nil {
+		m.Apply(order)
+	}
+	return order, err
+}
+
+// place tracks an order as pending, places it, and moves it to the state of the response, or to rejected. A
+// reconciliation running meanwhile matches the order to its pending slot rather than adopting it.
+func (m *Manager) place(tracked TrackedOrder, submit func() (*private.Order, error)) (*private.Order, error) {
+	m.mu.Lock()
+	if tracked.ID == "" {
+		tracked.ID = util.GenerateUUID()
+	}
+	tracked.Symbol = strings.ToLower(tracked.Symbol)
+	tracked.State = PendingNew
+	tracked.CreatedAt = m.now()
+	tracked.UpdatedAt = tracked.CreatedAt
+	m.orders[tracked.ID] = &tracked
+	m.mu.Unlock()
+
+	order, err := submit()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		tracked.Error = err.Error()
+		if tracked.OrderID == "" {
+			m.transition(&tracked, Rejected)
+		}
+		return nil, err
+	}
+	if tracked.OrderID != "" {
+		// already matched by a reconciliation, from a newer view of the order than this response
+		return order, nil
+	}
+	tracked.OrderID = order.OrderID
+	m.byOrderID[order.OrderID] = &tracked
+	m.update(&tracked, order)
+	return order, nil
+}
+
+// pending finds the tracked order still being placed that an exchange order is the result of: by client order id,
+// or for orders placed without one, by their terms; must be called with m.mu held
+func (m *Manager) pending(order *private.Order) *TrackedOrder {
+	for _, tracked := range m.orders {
+		if tracked.State != PendingNew || tracked.OrderID != "" {
+			continue
+		}
+		if tracked.ClientOrderID != "" {
+			if tracked.ClientOrderID == order.ClientOrderID {
+				return tracked
+			}
+			continue
+		}
+		amount, _ := strconv.ParseFloat(order.OriginalAmount, 64)
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		if strings.EqualFold(tracked.Symbol, order.Symbol) && tracked.Side == order.Side && tracked.Type == order.Type &&
+			tracked.Amount == amount && tracked.Price == price && tracked.StopPrice == stopPrice {
+			return tracked
+		}
+	}
+	return nil
+}
+
+func (m *Manager) Apply(order *private.Order) {
+	/*
+		Update the tracked order from an exchange representation of it, e.g. an order status response or an order
+		event. Orders that are not tracked are ignored; see Reconcile
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if tracked, ok := m.byOrderID[order.OrderID]; ok {
+		m.update(tracked, order)
+	}
+}
+
+// update applies an exchange representation to a tracked order; must be called with m.mu held
+func (m *Manager) update(tracked *TrackedOrder, order *private.Order) {
+	if Terminal(tracked.State) {
+		return
+	}
+	copy := *order
+	tracked.Order = &copy
+	executed := tracked.Executed
+	tracked.Executed, _ = strconv.ParseFloat(order.ExecutedAmount, 64)
+	tracked.AvgPrice, _ = strconv.ParseFloat(order.AvgExecutionPrice, 64)
+	tracked.UpdatedAt = m.now()
+	// a further partial fill is a transition too
+	if state := stateOf(order); state != tracked.State || (state == PartiallyFilled && tracked.Executed != executed) {
+		m.transition(tracked, state)
+	}
+}
+
+// transition moves a tracked order to a new state and notifies the handlers; must be called with m.mu held
+func (m *Manager) transition(tracked *TrackedOrder, to string) {
+	from := tracked.State
+	if !allowed(from, to) {
+		m.logger.Warn("ignoring invalid order transition", "id", tracked.ID, "order_id", tracked.OrderID, "from", from, "to", to)
+		return
+	}
+	tracked.State = to
+	tracked.UpdatedAt = m.now()
+	m.logger.Info("order transition", "id", tracked.ID, "order_id", tracked.OrderID, "symbol", tracked.Symbol, "from", from, "to", to, "executed", tracked.Executed)
+	for _, handler := range m.handlers {
+		handler(Transition{From: from, To: to, Order: *tracked})
+	}
+}
+
+func (m *Manager) Poll() error {
+	/*
+		Update every open tracked order with GetOrderStatus
+	*/
+	var errs []error
+	for _, tracked := range m.Orders() {
+		if Terminal(tracked.State) || tracked.OrderID == "" {
+			continue
+		}
+		id, err := strconv.Atoi(tracked.OrderID)
+		if err != nil {
+			continue
+		}
+		if _, err := m.GetOrderStatus(id); err != nil {
+			errs = append(errs, errors.New("error polling order "+tracked.OrderID+": "+err.Error()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) Reconcile() (*Reconciliation, error) {
+	/*
+		Compare the tracked orders with the exchange's active orders (/v1/orders). Active orders that are not tracked,
+		nor being placed through the manager, are adopted, and open tracked orders that are no longer active are
+		resolved with GetOrderStatus
+
+		Returns what was out of sync, or an error if the active orders cannot be read
+	*/
+	active, err := m.Trader.GetActiveOrders()
+	if err != nil {
+		return nil, errors.New("error getting active orders: " + err.Error())
+	}
+	reconciliation := &Reconciliation{}
+	remote := map[string]bool{}
+	m.mu.Lock()
+	for i := range active {
+		order := &active[i]
+		remote[order.OrderID] = true
+		if _, ok := m.byOrderID[order.OrderID]; ok {
+			m.update(m.byOrderID[order.OrderID], order)
+			continue
+		}
+		if tracked := m.pending(order); tracked != nil {
+			tracked.OrderID = order.OrderID
+			m.byOrderID[order.OrderID] = tracked
+			m.update(tracked, order)
+			continue
+		}
+		tracked := adopt(order, m.now())
+		m.orders[tracked.ID] = tracked
+		m.byOrderID[tracked.OrderID] = tracked
+		m.logger.Warn("adopted untracked order", "order_id", order.OrderID, "symbol", order.Symbol, "side", order.Side)
+		reconciliation.Unknown = append(reconciliation.Unknown, *tracked)
+	}
+	var missing []*TrackedOrder
+	for _, tracked := range m.orders {
+		if tracked.OrderID != "" && !Terminal(tracked.State) && !remote[tracked.OrderID] {
+			missing = append(missing, tracked)
+		}
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, tracked := range missing {
+		id, _ := strconv.Atoi(tracked.OrderID)
+		if _, err := m.GetOrderStatus(id); err != nil {
+			errs = append(errs, errors.New("error resolving missing order "+tracked.OrderID+": "+err.Error()))
+			continue
+		}
+		m.mu.Lock()
+		// an order placed after the active orders were read is still open
+		if Terminal(tracked.State) {
+			m.logger.Warn("tracked order no longer active", "order_id", tracked.OrderID, "state", tracked.State)
+			reconciliation.Missing = append(reconciliation.Missing, *tracked)
+		}
+		m.mu.Unlock()
+	}
+	return reconciliation, errors.Join(errs...)
+}
+
+// adopt creates the tracked order of an untracked exchange order
+func adopt(order *private.Order, now time.Time) *TrackedOrder {
+	tracked := &TrackedOrder{
+		ID: util.GenerateUUID(), OrderID: order.OrderID, Symbol: strings.ToLower(order.Symbol), Side: order.Side,
+		Type: order.Type, State: stateOf(order), CreatedAt: now, UpdatedAt: now,
+	}
+	tracked.Amount, _ = strconv.ParseFloat(order.OriginalAmount, 64)
+	tracked.Price, _ = strconv.ParseFloat(order.Price, 64)
+	tracked.Executed, _ = strconv.ParseFloat(order.ExecutedAmount, 64)
+	tracked.AvgPrice, _ = strconv.ParseFloat(order.AvgExecutionPrice, 64)
+	if order.TimestampMs > 0 {
+		tracked.CreatedAt = time.UnixMilli(order.TimestampMs)
+	}
+	copy := *order
+	tracked.Order = &copy
+	return tracked
+}
+
+func (m *Manager) Orders() []TrackedOrder {
+	/*
+		Get copies of all tracked orders, oldest first
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	orders := make([]TrackedOrder, 0, len(m.orders))
+	for _, tracked := range m.orders {
+		orders = append(orders, *tracked)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+	return orders
+}
+
+func (m *Manager) Order(orderID string) (TrackedOrder, bool) {
+	/*
+		Get a copy of the tracked order with the given exchange order id
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tracked, ok := m.byOrderID[orderID]
+	if !ok {
+		return TrackedOrder{}, false
+	}
+	return *tracked, true
+}
+
+func (m *Manager) Run(ctx context.Context, pollInterval time.Duration, reconcileInterval time.Duration) error {
+	/*
+		Poll open orders every pollInterval and reconcile every reconcileInterval until the context is done
+	*/
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	reconcile := time.NewTicker(reconcileInterval)
+	defer reconcile.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-poll.C:
+			if err := m.Poll(); err != nil {
+				m.logger.Error("order poll failed", "error", err)
+			}
+		case <-reconcile.C:
+			if _, err := m.Reconcile(); err != nil {
+				m.logger.Error("order reconciliation failed", "error", err)
+			}
+		}
+	}
+}
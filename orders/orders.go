@@ -0,0 +1,90 @@
+// Package orders tracks every order placed through a private.Trader in a local state machine, keeps it current by
+// polling order status or applying order events, and reconciles it with the exchange's list of active orders.
+package orders
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+// Order states
+const (
+	// PendingNew: the order is being placed and the exchange has not acknowledged it yet
+	PendingNew      = "pending_new"
+	Live            = "live"
+	PartiallyFilled = "partially_filled"
+	Filled          = "filled"
+	// Cancelled orders may have partially filled first; see Executed
+	Cancelled = "cancelled"
+	// Rejected: the exchange refused the order; see Error
+	Rejected = "rejected"
+)
+
+// transitions lists the states each state may move to; terminal states have none
+var transitions = map[string][]string{
+	PendingNew:      {Live, PartiallyFilled, Filled, Cancelled, Rejected},
+	Live:            {PartiallyFilled, Filled, Cancelled},
+	PartiallyFilled: {PartiallyFilled, Filled, Cancelled},
+}
+
+// Terminal reports whether no further transitions are possible from state
+func Terminal(state string) bool {
+	return len(transitions[state]) == 0
+}
+
+func allowed(from string, to string) bool {
+	for _, state := range transitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackedOrder is the local record of an order
+type TrackedOrder struct {
+	// ID is the local id, assigned before the order is placed
+	ID string
+	// ClientOrderID is the client order id a limit order is placed under: the caller's, or else ID
+	ClientOrderID string
+	// OrderID is the exchange order id, empty while pending or if rejected
+	OrderID   string
+	Symbol    string
+	Side      string
+	Type      string
+	Amount    float64
+	Price     float64
+	StopPrice float64
+	State     string
+	Executed  float64
+	AvgPrice  float64
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Order is the latest exchange representation of the order
+	Order *private.Order
+}
+
+// Transition is a state change of a tracked order
+type Transition struct {
+	From  string
+	To    string
+	Order TrackedOrder
+}
+
+// stateOf derives the state of an order from its exchange representation
+func stateOf(order *private.Order) string {
+	executed, _ := strconv.ParseFloat(order.ExecutedAmount, 64)
+	switch {
+	case order.IsLive && executed > 0:
+		return PartiallyFilled
+	case order.IsLive:
+		return Live
+	case order.IsCancelled:
+		return Cancelled
+	default:
+		return Filled
+	}
+}
@@ -0,0 +1,186 @@
+package orders
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+func TestManager(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	var transitions []string
+	manager := NewManager(client, WithHandler(func(transition Transition) {
+		transitions = append(transitions, transition.From+">"+transition.To)
+	}))
+
+	order, err := manager.LimitBuy("btcusd", 0.1, 25000)
+	if err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	if tracked, _ := manager.Order(order.OrderID); tracked.State != Live || tracked.Amount != 0.1 {
+		t.Errorf("resting buy should be live: %+v", tracked)
+	}
+	if _, err := manager.LimitBuy("btcusd", 1000, 25000); err == nil {
+		t.Fatalf("an unfunded buy should fail")
+	}
+	for _, tracked := range manager.Orders() {
+		if tracked.Amount == 1000 && (tracked.State != Rejected || tracked.Error == "" || tracked.OrderID != "") {
+			t.Errorf("unfunded buy should be rejected: %+v", tracked)
+		}
+	}
+
+	// order events are applied like status responses
+	partial := *order
+	partial.ExecutedAmount = "0.04"
+	manager.Apply(&partial)
+	if tracked, _ := manager.Order(order.OrderID); tracked.State != PartiallyFilled || tracked.Executed != 0.04 {
+		t.Errorf("partial fill event not applied: %+v", tracked)
+	}
+	server.SetPrice("btcusd", 24000)
+	if err := manager.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if tracked, _ := manager.Order(order.OrderID); tracked.State != Filled || tracked.Executed != 0.1 {
+		t.Errorf("buy should have filled: %+v", tracked)
+	}
+	// stale events can't move a terminal order
+	manager.Apply(&partial)
+	if tracked, _ := manager.Order(order.OrderID); tracked.State != Filled {
+		t.Errorf("stale event reopened a filled order: %+v", tracked)
+	}
+
+	// an order placed elsewhere is adopted; one cancelled elsewhere is resolved
+	sell, _ := manager.LimitSell("btcusd", 0.5, 30000)
+	elsewhere, _ := client.LimitSell("btcusd", 0.2, 31000)
+	id, _ := strconv.Atoi(sell.OrderID)
+	if _, err := client.CancelOrder(id); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	reconciliation, err := manager.Reconcile()
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(reconciliation.Unknown) != 1 || reconciliation.Unknown[0].OrderID != elsewhere.OrderID || reconciliation.Unknown[0].State != Live {
+		t.Errorf("unknown orders %+v", reconciliation.Unknown)
+	}
+	if len(reconciliation.Missing) != 1 || reconciliation.Missing[0].OrderID != sell.OrderID || reconciliation.Missing[0].State != Cancelled {
+		t.Errorf("missing orders %+v", reconciliation.Missing)
+	}
+
+	expected := []string{"pending_new>live", "pending_new>rejected", "live>partially_filled", "partially_filled>filled", "pending_new>live", "live>cancelled"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("transitions %v, expected %v", transitions, expected)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("transition %d is %s, expected %s", i, transitions[i], expected[i])
+		}
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	if !allowed(PendingNew, Filled) || !allowed(PartiallyFilled, PartiallyFilled) || allowed(Live, Rejected) || allowed(Filled, Cancelled) {
+		t.Errorf("unexpected transition table")
+	}
+	for state, terminal := range map[string]bool{PendingNew: false, Live: false, PartiallyFilled: false, Filled: true, Cancelled: true, Rejected: true} {
+		if Terminal(state) != terminal {
+			t.Errorf("Terminal(%s) = %v", state, !terminal)
+		}
+	}
+}
+
+// racingTrader runs a reconciliation while an order is being placed, and places an order right after the active
+// orders are read, as Run can interleave them
+type racingTrader struct {
+	*private.Client
+	manager *Manager
+	during  func()
+	after   func()
+}
+
+func (r *racingTrader) LimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options ...string) (*private.Order, error) {
+	order, err := r.Client.LimitOrder(side, symbol, amount, limitPrice, clientOrderID, options...)
+	if during := r.during; during != nil {
+		r.during = nil
+		during()
+	}
+	return order, err
+}
+
+func (r *racingTrader) StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	order, err := r.Client.StopLimitSell(symbol, amount, stopPrice, limitPrice)
+	if during := r.during; during != nil {
+		r.during = nil
+		during()
+	}
+	return order, err
+}
+
+func (r *racingTrader) GetActiveOrders() ([]private.Order, error) {
+	active, err := r.Client.GetActiveOrders()
+	if after := r.after; after != nil {
+		r.after = nil
+		after()
+	}
+	return active, err
+}
+
+func TestReconcileDuringPlacement(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	trader := &racingTrader{Client: client}
+	manager := NewManager(trader)
+	var reconciliation *Reconciliation
+	reconcile := func() {
+		if reconciliation, err = manager.Reconcile(); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+	}
+
+	// orders seen by a reconciliation while they are being placed are matched to their pending slot, limit orders
+	// by client order id and stop orders by their terms
+	trader.during = reconcile
+	order, err := manager.LimitBuy("btcusd", 0.1, 25000)
+	if err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	if len(reconciliation.Unknown) != 0 || len(manager.Orders()) != 1 {
+		t.Errorf("limit order adopted as unknown: %+v, tracked %+v", reconciliation.Unknown, manager.Orders())
+	}
+	if tracked, ok := manager.Order(order.OrderID); !ok || tracked.State != Live || tracked.ClientOrderID != tracked.ID {
+		t.Errorf("tracked limit order %+v", tracked)
+	}
+	trader.during = reconcile
+	if _, err := manager.StopLimitSell("btcusd", 0.1, 28000, 27900); err != nil {
+		t.Fatalf("StopLimitSell failed: %v", err)
+	}
+	if len(reconciliation.Unknown) != 0 || len(manager.Orders()) != 2 {
+		t.Errorf("stop order adopted as unknown: %+v, tracked %+v", reconciliation.Unknown, manager.Orders())
+	}
+
+	// an order placed after the active orders were read is not missing
+	var late *private.Order
+	trader.after = func() {
+		if late, err = manager.LimitOrder("buy", "btcusd", 0.1, 24000, "late"); err != nil {
+			t.Fatalf("LimitBuy failed: %v", err)
+		}
+	}
+	reconcile()
+	if len(reconciliation.Missing) != 0 {
+		t.Errorf("open order reported missing: %+v", reconciliation.Missing)
+	}
+	if tracked, _ := manager.Order(late.OrderID); tracked.State != Live || tracked.ClientOrderID != "late" || late.ClientOrderID != "late" {
+		t.Errorf("late order %+v", tracked)
+	}
+}
@@ -0,0 +1,123 @@
+package paper
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+// Level is one price level of an order book
+type Level struct {
+	Price  float64
+	Amount float64
+}
+
+// Book is a snapshot of an order book, best prices first
+type Book struct {
+	Bids []Level
+	Asks []Level
+}
+
+// MarketData supplies the order books paper orders are filled against
+type MarketData interface {
+	Book(symbol string) (*Book, error)
+	Currencies(symbol string) (base string, quote string, err error)
+}
+
+// LiveMarketData reads market data from the public API. By default books hold only the top of book from the v2
+// ticker, with unlimited size; with Depth the full /v1/book order book is used and large orders fill partially.
+type LiveMarketData struct {
+	Depth bool
+
+	mu         sync.Mutex
+	currencies map[string][2]string
+}
+
+func (m *LiveMarketData) Book(symbol string) (*Book, error) {
+	/*
+		Get the current order book of a symbol
+	*/
+	if m.Depth {
+		return bookFromDepth(symbol)
+	}
+	var ticker public.TickerV2
+	if err := public.GetPublicEndpoint("/v2/ticker/"+symbol, &ticker); err != nil {
+		return nil, err
+	}
+	bid, err := strconv.ParseFloat(ticker.Bid, 64)
+	if err != nil {
+		return nil, errors.New("error parsing bid of " + symbol + ": " + err.Error())
+	}
+	ask, err := strconv.ParseFloat(ticker.Ask, 64)
+	if err != nil {
+		return nil, errors.New("error parsing ask of " + symbol + ": " + err.Error())
+	}
+	return &Book{Bids: []Level{{Price: bid, Amount: unlimited}}, Asks: []Level{{Price: ask, Amount: unlimited}}}, nil
+}
+
+func bookFromDepth(symbol string) (*Book, error) {
+	var response struct {
+		Bids []map[string]string `json:"bids"`
+		Asks []map[string]string `json:"asks"`
+	}
+	if err := public.GetPublicEndpoint("/v1/book/"+symbol, &response); err != nil {
+		return nil, err
+	}
+	parse := func(entries []map[string]string) ([]Level, error) {
+		levels := make([]Level, 0, len(entries))
+		for _, entry := range entries {
+			price, err := strconv.ParseFloat(entry["price"], 64)
+			if err != nil {
+				return nil, errors.New("error parsing book price of " + symbol + ": " + err.Error())
+			}
+			amount, err := strconv.ParseFloat(entry["amount"], 64)
+			if err != nil {
+				return nil, errors.New("error parsing book amount of " + symbol + ": " + err.Error())
+			}
+			levels = append(levels, Level{Price: price, Amount: amount})
+		}
+		return levels, nil
+	}
+	bids, err := parse(response.Bids)
+	if err != nil {
+		return nil, err
+	}
+	asks, err := parse(response.Asks)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(bids, func(i, j int) bool { return bids[i].Price > bids[j].Price })
+	sort.Slice(asks, func(i, j int) bool { return asks[i].Price < asks[j].Price })
+	return &Book{Bids: bids, Asks: asks}, nil
+}
+
+func (m *LiveMarketData) Currencies(symbol string) (string, string, error) {
+	/*
+		Get the base and quote currency of a symbol from its symbol details. Results are cached.
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pair, ok := m.currencies[symbol]; ok {
+		return pair[0], pair[1], nil
+	}
+	var details struct {
+		BaseCurrency  string `json:"base_currency"`
+		QuoteCurrency string `json:"quote_currency"`
+	}
+	if err := public.GetPublicEndpoint("/v1/symbols/details/"+symbol, &details); err != nil {
+		return "", "", err
+	}
+	if details.BaseCurrency == "" || details.QuoteCurrency == "" {
+		return "", "", errors.New("no currencies in details of symbol " + symbol)
+	}
+	if m.currencies == nil {
+		m.currencies = map[string][2]string{}
+	}
+	pair := [2]string{strings.ToUpper(details.BaseCurrency), strings.ToUpper(details.QuoteCurrency)}
+	m.currencies[symbol] = pair
+	return pair[0], pair[1], nil
+}
@@ -0,0 +1,190 @@
+package paper
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/austinjhunt/go-gemini/geminitest"
+)
+
+// staticMarket serves fixed books for btcusd
+type staticMarket struct {
+	book *Book
+}
+
+func (m *staticMarket) Book(symbol string) (*Book, error) {
+	return m.book, nil
+}
+
+func (m *staticMarket) Currencies(symbol string) (string, string, error) {
+	return "BTC", "USD", nil
+}
+
+func quote(bid float64, ask float64) *Book {
+	return &Book{Bids: []Level{{Price: bid, Amount: unlimited}}, Asks: []Level{{Price: ask, Amount: unlimited}}}
+}
+
+func balanceOf(t *testing.T, trader *Trader, currency string) (float64, float64) {
+	t.Helper()
+	balances, err := trader.GetAvailableBalances()
+	if err != nil {
+		t.Fatalf("GetAvailableBalances failed: %v", err)
+	}
+	for _, b := range balances {
+		if b.Currency == currency {
+			amount, _ := strconv.ParseFloat(b.Amount, 64)
+			available, _ := strconv.ParseFloat(b.Available, 64)
+			return amount, available
+		}
+	}
+	return 0, 0
+}
+
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestTakerFill(t *testing.T) {
+	market := &staticMarket{book: quote(99, 100)}
+	trader := NewTrader(market, map[string]float64{"USD": 1000}, WithFees(0.001, 0.01))
+
+	order, err := trader.LimitBuy("btcusd", 2, 105)
+	if err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	if order.IsLive || order.ExecutedAmount != "2" || order.AvgExecutionPrice != "100" {
+		t.Errorf("crossing buy should fill at the ask: %+v", order)
+	}
+	if usd, _ := balanceOf(t, trader, "USD"); !near(usd, 1000-200-2) {
+		t.Errorf("USD balance %v, want 798 after cost and taker fee", usd)
+	}
+	if btc, _ := balanceOf(t, trader, "BTC"); btc != 2 {
+		t.Errorf("BTC balance %v, want 2", btc)
+	}
+}
+
+func TestRestingOrderFillsAsMaker(t *testing.T) {
+	market := &staticMarket{book: quote(99, 100)}
+	trader := NewTrader(market, map[string]float64{"BTC": 1}, WithFees(0.001, 0.01))
+
+	order, err := trader.LimitSell("btcusd", 1, 110)
+	if err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+	if !order.IsLive {
+		t.Fatalf("sell above the bid should rest: %+v", order)
+	}
+	if _, available := balanceOf(t, trader, "BTC"); available != 0 {
+		t.Errorf("resting sell should hold the BTC, %v available", available)
+	}
+
+	market.book = quote(111, 112)
+	id, _ := strconv.Atoi(order.OrderID)
+	status, err := trader.GetOrderStatus(id)
+	if err != nil {
+		t.Fatalf("GetOrderStatus failed: %v", err)
+	}
+	if status.IsLive || status.AvgExecutionPrice != "110" {
+		t.Errorf("resting sell should fill at its limit once the bid reaches it: %+v", status)
+	}
+	if usd, _ := balanceOf(t, trader, "USD"); !near(usd, 110-0.11) {
+		t.Errorf("USD balance %v, want proceeds less maker fee", usd)
+	}
+	history, _ := trader.GetClosedOrdersHistory()
+	if len(history) != 1 || history[0].OrderID != order.OrderID {
+		t.Errorf("closed orders history %+v", history)
+	}
+}
+
+func TestOrderOptionsAndCancel(t *testing.T) {
+	market := &staticMarket{book: &Book{
+		Bids: []Level{{Price: 99, Amount: 1}},
+		Asks: []Level{{Price: 100, Amount: 1}, {Price: 101, Amount: 1}},
+	}}
+	trader := NewTrader(market, map[string]float64{"USD": 10000})
+
+	if order, _ := trader.LimitBuy("btcusd", 1, 100, "maker-or-cancel"); !order.IsCancelled {
+		t.Errorf("crossing maker-or-cancel order should be cancelled: %+v", order)
+	}
+	if order, _ := trader.LimitBuy("btcusd", 3, 101, "fill-or-kill"); !order.IsCancelled || order.ExecutedAmount != "0" {
+		t.Errorf("fill-or-kill beyond the book depth should be cancelled unfilled: %+v", order)
+	}
+	if order, _ := trader.LimitBuy("btcusd", 3, 101, "immediate-or-cancel"); !order.IsCancelled || order.ExecutedAmount != "2" || order.AvgExecutionPrice != "100.5" {
+		t.Errorf("immediate-or-cancel should fill the available depth and cancel the rest: %+v", order)
+	}
+
+	order, _ := trader.LimitBuy("btcusd", 1, 90)
+	id, _ := strconv.Atoi(order.OrderID)
+	if _, available := balanceOf(t, trader, "USD"); available >= 10000-2*100.5*(1+DefaultTakerFee) {
+		t.Errorf("resting buy should hold USD, %v available", available)
+	}
+	cancelled, err := trader.CancelOrder(id)
+	if err != nil || !cancelled.IsCancelled {
+		t.Errorf("CancelOrder failed: %+v %v", cancelled, err)
+	}
+	if amount, available := balanceOf(t, trader, "USD"); !near(amount, available) {
+		t.Errorf("cancel should release held USD: amount %v, available %v", amount, available)
+	}
+	if _, err := trader.CancelOrder(999); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
+	}
+	if _, err := trader.LimitBuy("btcusd", 1000, 100); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+}
+
+func TestStopLimitTriggers(t *testing.T) {
+	market := &staticMarket{book: quote(99, 100)}
+	trader := NewTrader(market, map[string]float64{"BTC": 1})
+
+	if _, err := trader.StopLimitSell("btcusd", 1, 90, 95); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("expected ErrInvalidOrder for a sell stop below its limit, got %v", err)
+	}
+	order, err := trader.StopLimitSell("btcusd", 1, 95, 90)
+	if err != nil || !order.IsLive {
+		t.Fatalf("StopLimitSell should rest until triggered: %+v %v", order, err)
+	}
+	market.book = quote(94, 95)
+	id, _ := strconv.Atoi(order.OrderID)
+	status, _ := trader.GetOrderStatus(id)
+	if status.IsLive || status.AvgExecutionPrice != "94" {
+		t.Errorf("triggered stop should sell into the bid: %+v", status)
+	}
+}
+
+func TestLiveMarketData(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	t.Setenv("GEMINI_EXCHANGE_API_BASE_URL", server.URL)
+	server.SetQuote("ethusd", 1990, 2010)
+
+	for _, market := range []*LiveMarketData{{}, {Depth: true}} {
+		trader := NewTrader(market, map[string]float64{"USD": 5000})
+		order, err := trader.LimitBuy("ethusd", 1, 2100)
+		if err != nil {
+			t.Fatalf("LimitBuy failed: %v", err)
+		}
+		if order.AvgExecutionPrice != "2010" || order.IsLive {
+			t.Errorf("order should fill at the ask of the mock exchange: %+v", order)
+		}
+		if eth, _ := balanceOf(t, trader, "ETH"); eth != 1 {
+			t.Errorf("ETH balance %v, want 1", eth)
+		}
+	}
+}
+
+func TestSlippageAndFills(t *testing.T) {
+	market := &staticMarket{book: quote(99, 100)}
+	trader := NewTrader(market, map[string]float64{"USD": 1000}, WithFees(0, 0.01), WithSlippage(0.01))
+
+	if order, _ := trader.LimitBuy("btcusd", 1, 100.5); order.AvgExecutionPrice != "100.5" {
+		t.Errorf("slippage should be capped at the limit price: %+v", order)
+	}
+	trader.LimitBuy("btcusd", 1, 200)
+	fills := trader.Fills()
+	if len(fills) != 2 || fills[1].Price != 101 || fills[1].Maker || !near(fills[1].Fee, 1.01) {
+		t.Errorf("Fills returned %+v", fills)
+	}
+}
@@ -0,0 +1,548 @@
+// Package paper simulates trading against live market data with virtual balances, so strategies can be dry-run
+// through the same private.Trader interface as the live API without risking funds.
+package paper
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+const (
+	// DefaultMakerFee and DefaultTakerFee are the fee rates charged unless configured with WithFees
+	DefaultMakerFee = 0.002
+	DefaultTakerFee = 0.004
+
+	optionMakerOrCancel     = "maker-or-cancel"
+	optionImmediateOrCancel = "immediate-or-cancel"
+	optionFillOrKill        = "fill-or-kill"
+)
+
+var unlimited = math.Inf(1)
+
+var (
+	ErrInsufficientFunds = errors.New("paper: insufficient funds")
+	ErrOrderNotFound     = errors.New("paper: order not found")
+	ErrInvalidOrder      = errors.New("paper: invalid order")
+)
+
+// Trader is a paper trading account implementing private.Trader. Orders that cross the book fill immediately as
+// taker orders, walking the book levels; resting orders fill completely at their limit price as maker orders once the
+// opposite side of the book reaches it. Fees are charged in the quote currency.
+type Trader struct {
+	market   MarketData
+	makerFee float64
+	takerFee float64
+	slippage float64
+	now      func() time.Time
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	balances map[string]*balance
+	orders   []*order
+	fills    []Fill
+	nextID   int
+}
+
+// Fill is one execution of a paper order
+type Fill struct {
+	OrderID int
+	Symbol  string
+	Side    string
+	Amount  float64
+	Price   float64
+	Fee     float64
+	Maker   bool
+	Time    time.Time
+}
+
+type balance struct {
+	amount float64
+	held   float64
+}
+
+type order struct {
+	id        int
+	clientID  string
+	symbol    string
+	base      string
+	quote     string
+	side      string
+	stop      bool
+	triggered bool
+	options   []string
+	price     float64
+	stopPrice float64
+	amount    float64
+	executed  float64
+	notional  float64
+	held      float64
+	live      bool
+	cancelled bool
+	placedAt  time.Time
+}
+
+// Option configures a paper Trader
+type Option func(*Trader)
+
+func WithFees(makerFee float64, takerFee float64) Option {
+	/*
+		Charge the given fee rates (e.g. 0.002 for 0.2%) on maker and taker fills
+	*/
+	return func(t *Trader) {
+		t.makerFee = makerFee
+		t.takerFee = takerFee
+	}
+}
+
+func WithSlippage(slippage float64) Option {
+	/*
+		Worsen taker fill prices by the given fraction (e.g. 0.001 for 0.1%), never beyond the order's limit price
+	*/
+	return func(t *Trader) {
+		t.slippage = slippage
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	/*
+		Timestamp orders with the given clock instead of time.Now
+	*/
+	return func(t *Trader) {
+		t.now = now
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	/*
+		Log through the given logger instead of util.Logger()
+	*/
+	return func(t *Trader) {
+		t.logger = logger
+	}
+}
+
+var _ private.Trader = (*Trader)(nil)
+
+func NewTrader(market MarketData, balances map[string]float64, opts ...Option) *Trader {
+	/*
+		Create a paper trading account
+
+		Args:
+		market (MarketData): market data orders are filled against, e.g. &LiveMarketData{}
+		balances (map[string]float64): starting balances by currency, e.g. {"USD": 10000}
+		opts (...Option): optional behavior, e.g. WithFees
+
+		Returns a pointer to the Trader
+	*/
+	t := &Trader{
+		market:   market,
+		makerFee: DefaultMakerFee,
+		takerFee: DefaultTakerFee,
+		now:      time.Now,
+		balances: map[string]*balance{},
+		nextID:   1,
+	}
+	for currency, amount := range balances {
+		t.balances[strings.ToUpper(currency)] = &balance{amount: amount}
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.logger == nil {
+		t.logger = util.Logger()
+	}
+	t.logger = t.logger.With("trader", "paper")
+	return t
+}
+
+func (t *Trader) LimitBuy(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	t.logger.Info("LimitBuy", "symbol", symbol, "amount", amount, "limit_price", limitPrice, "options", options)
+	return t.place(&order{symbol: symbol, side: "buy", amount: amount, price: limitPrice, options: options})
+}
+
+func (t *Trader) LimitSell(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	t.logger.Info("LimitSell", "symbol", symbol, "amount", amount, "limit_price", limitPrice, "options", options)
+	return t.place(&order{symbol: symbol, side: "sell", amount: amount, price: limitPrice, options: options})
+}
+
+func (t *Trader) LimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options ...string) (*private.Order, error) {
+	t.logger.Info("LimitOrder", "side", side, "symbol", symbol, "amount", amount, "limit_price", limitPrice, "client_order_id", clientOrderID, "options", options)
+	if side != "buy" && side != "sell" {
+		return nil, fmt.Errorf("%w: side must be buy or sell, got %s", ErrInvalidOrder, side)
+	}
+	return t.place(&order{symbol: symbol, side: side, amount: amount, price: limitPrice, options: options, clientID: clientOrderID})
+}
+
+func (t *Trader) StopLimitBuy(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	t.logger.Info("StopLimitBuy", "symbol", symbol, "amount", amount, "stop_price", stopPrice, "limit_price", limitPrice)
+	if stopPrice >= limitPrice {
+		return nil, fmt.Errorf("%w: stopPrice (%f) must be less than limitPrice (%f)", ErrInvalidOrder, stopPrice, limitPrice)
+	}
+	return t.place(&order{symbol: symbol, side: "buy", stop: true, amount: amount, price: limitPrice, stopPrice: stopPrice})
+}
+
+func (t *Trader) StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	t.logger.Info("StopLimitSell", "symbol", symbol, "amount", amount, "stop_price", stopPrice, "limit_price", limitPrice)
+	if stopPrice <= limitPrice {
+		return nil, fmt.Errorf("%w: stopPrice (%f) must be greater than limitPrice (%f)", ErrInvalidOrder, stopPrice, limitPrice)
+	}
+	return t.place(&order{symbol: symbol, side: "sell", stop: true, amount: amount, price: limitPrice, stopPrice: stopPrice})
+}
+
+func (t *Trader) CancelOrder(order_id int) (*private.Order, error) {
+	/*
+		Cancel a live order, releasing its held funds. Cancelling a closed order returns it unchanged.
+	*/
+	t.logger.Info("CancelOrder", "order_id", order_id)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	o := t.find(order_id)
+	if o == nil {
+		return nil, fmt.Errorf("%w: %d", ErrOrderNotFound, order_id)
+	}
+	if o.live {
+		t.close(o, true)
+	}
+	return o.toOrder(), nil
+}
+
+func (t *Trader) GetOrderStatus(order_id int) (*private.Order, error) {
+	/*
+		Get the status of an order, after filling resting orders against the current market
+	*/
+	if err := t.Refresh(); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	o := t.find(order_id)
+	if o == nil {
+		return nil, fmt.Errorf("%w: %d", ErrOrderNotFound, order_id)
+	}
+	return o.toOrder(), nil
+}
+
+func (t *Trader) GetAvailableBalances() ([]private.AvailableBalance, error) {
+	/*
+		Get the virtual balances, after filling resting orders against the current market
+	*/
+	if err := t.Refresh(); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var balances []private.AvailableBalance
+	for currency, b := range t.balances {
+		if b.amount == 0 {
+			continue
+		}
+		available := formatFloat(b.amount - b.held)
+		balances = append(balances, private.AvailableBalance{
+			Type:                   "exchange",
+			Currency:               currency,
+			Amount:                 formatFloat(b.amount),
+			Available:              available,
+			AvailableForWithdrawal: available,
+		})
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Currency < balances[j].Currency })
+	return balances, nil
+}
+
+func (t *Trader) GetActiveOrders() ([]private.Order, error) {
+	/*
+		Get live orders, oldest first, after filling resting orders against the current market
+	*/
+	if err := t.Refresh(); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var active []private.Order
+	for _, o := range t.orders {
+		if o.live {
+			active = append(active, *o.toOrder())
+		}
+	}
+	return active, nil
+}
+
+func (t *Trader) GetClosedOrdersHistory() ([]private.Order, error) {
+	/*
+		Get filled and cancelled orders, newest first, after filling resting orders against the current market
+	*/
+	if err := t.Refresh(); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var history []private.Order
+	for i := len(t.orders) - 1; i >= 0; i-- {
+		if !t.orders[i].live {
+			history = append(history, *t.orders[i].toOrder())
+		}
+	}
+	return history, nil
+}
+
+func (t *Trader) Fills() []Fill {
+	/*
+		Get every fill so far, oldest first
+	*/
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Fill(nil), t.fills...)
+}
+
+func (t *Trader) Refresh() error {
+	/*
+		Trigger stop orders and fill resting orders against the current books of their symbols
+	*/
+	t.mu.Lock()
+	symbols := map[string]bool{}
+	for _, o := range t.orders {
+		if o.live {
+			symbols[o.symbol] = true
+		}
+	}
+	t.mu.Unlock()
+
+	for symbol := range symbols {
+		book, err := t.market.Book(symbol)
+		if err != nil {
+			return err
+		}
+		t.mu.Lock()
+		for _, o := range t.orders {
+			if o.live && o.symbol == symbol {
+				t.match(o, book, false)
+			}
+		}
+		t.mu.Unlock()
+	}
+	return nil
+}
+
+func (t *Trader) place(o *order) (*private.Order, error) {
+	if o.amount <= 0 || o.price <= 0 {
+		return nil, fmt.Errorf("%w: amount and price must be positive", ErrInvalidOrder)
+	}
+	if len(o.options) > 1 {
+		return nil, fmt.Errorf("%w: at most one order execution option may be given", ErrInvalidOrder)
+	}
+	base, quote, err := t.market.Currencies(o.symbol)
+	if err != nil {
+		return nil, err
+	}
+	book, err := t.market.Book(o.symbol)
+	if err != nil {
+		return nil, err
+	}
+	o.base, o.quote = base, quote
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	holdCurrency, hold := o.quote, o.amount*o.price*(1+t.takerFee)
+	if o.side == "sell" {
+		holdCurrency, hold = o.base, o.amount
+	}
+	b := t.balance(holdCurrency)
+	if b.amount-b.held < hold {
+		return nil, fmt.Errorf("%w: %s order needs %s %s, %s available", ErrInsufficientFunds, o.symbol,
+			formatFloat(hold), holdCurrency, formatFloat(b.amount-b.held))
+	}
+	b.held += hold
+	o.held = hold
+	o.id = t.nextID
+	t.nextID++
+	o.live = true
+	o.placedAt = t.now()
+	t.orders = append(t.orders, o)
+	t.match(o, book, true)
+	return o.toOrder(), nil
+}
+
+// match triggers a stop order whose stop price the book has reached and fills the order as far as the book allows.
+// Orders are takers when they cross on placement or on triggering, and makers when the book moves through them later.
+func (t *Trader) match(o *order, book *Book, placing bool) {
+	if o.stop && !o.triggered {
+		if !stopReached(o, book) {
+			return
+		}
+		o.triggered = true
+		placing = true
+	}
+	levels := book.Asks
+	crosses := func(price float64) bool { return price <= o.price }
+	if o.side == "sell" {
+		levels = book.Bids
+		crosses = func(price float64) bool { return price >= o.price }
+	}
+	option := ""
+	if len(o.options) == 1 {
+		option = o.options[0]
+	}
+
+	if !placing {
+		// a resting order fills completely at its limit price once the book reaches it
+		if len(levels) > 0 && crosses(levels[0].Price) {
+			t.fill(o, o.amount-o.executed, o.price, true)
+		}
+		return
+	}
+
+	if option == optionMakerOrCancel {
+		if len(levels) > 0 && crosses(levels[0].Price) {
+			t.close(o, true)
+		}
+		return
+	}
+	if option == optionFillOrKill {
+		depth := 0.0
+		for _, level := range levels {
+			if crosses(level.Price) {
+				depth += level.Amount
+			}
+		}
+		if depth < o.amount {
+			t.close(o, true)
+			return
+		}
+	}
+	for _, level := range levels {
+		remaining := o.amount - o.executed
+		if remaining <= 0 || !crosses(level.Price) {
+			break
+		}
+		t.fill(o, math.Min(remaining, level.Amount), t.slip(o, level.Price), false)
+	}
+	if o.live && option == optionImmediateOrCancel {
+		t.close(o, true)
+	}
+}
+
+func stopReached(o *order, book *Book) bool {
+	if o.side == "buy" {
+		return len(book.Asks) > 0 && book.Asks[0].Price >= o.stopPrice
+	}
+	return len(book.Bids) > 0 && book.Bids[0].Price <= o.stopPrice
+}
+
+// slip worsens a taker fill price by the configured slippage, capped at the order's limit price
+func (t *Trader) slip(o *order, price float64) float64 {
+	if o.side == "buy" {
+		return math.Min(price*(1+t.slippage), o.price)
+	}
+	return math.Max(price*(1-t.slippage), o.price)
+}
+
+// fill executes amount of the order at price, moving balances and charging the fee in the quote currency
+func (t *Trader) fill(o *order, amount float64, price float64, maker bool) {
+	feeRate := t.takerFee
+	if maker {
+		feeRate = t.makerFee
+	}
+	cost := amount * price
+	fee := cost * feeRate
+	base, quote := t.balance(o.base), t.balance(o.quote)
+	if o.side == "buy" {
+		release := math.Min(o.held, amount*o.price*(1+t.takerFee))
+		quote.held -= release
+		o.held -= release
+		quote.amount -= cost + fee
+		base.amount += amount
+	} else {
+		base.held -= amount
+		o.held -= amount
+		base.amount -= amount
+		quote.amount += cost - fee
+	}
+	o.executed += amount
+	o.notional += cost
+	t.fills = append(t.fills, Fill{OrderID: o.id, Symbol: o.symbol, Side: o.side, Amount: amount, Price: price, Fee: fee, Maker: maker, Time: t.now()})
+	t.logger.Info("paper fill", "order_id", o.id, "symbol", o.symbol, "side", o.side, "amount", amount, "price", price, "fee", fee)
+	if o.amount-o.executed <= 1e-12 {
+		t.close(o, false)
+	}
+}
+
+// close takes the order off the book, releasing whatever it still holds
+func (t *Trader) close(o *order, cancelled bool) {
+	currency := o.quote
+	if o.side == "sell" {
+		currency = o.base
+	}
+	t.balance(currency).held -= o.held
+	o.held = 0
+	o.live = false
+	o.cancelled = cancelled
+}
+
+func (t *Trader) balance(currency string) *balance {
+	b, ok := t.balances[currency]
+	if !ok {
+		b = &balance{}
+		t.balances[currency] = b
+	}
+	return b
+}
+
+func (t *Trader) find(id int) *order {
+	for _, o := range t.orders {
+		if o.id == id {
+			return o
+		}
+	}
+	return nil
+}
+
+func (o *order) toOrder() *private.Order {
+	id := strconv.Itoa(o.id)
+	orderType := "exchange limit"
+	stopPrice := ""
+	if o.stop {
+		orderType = "exchange stop limit"
+		stopPrice = formatFloat(o.stopPrice)
+	}
+	avg := "0"
+	if o.executed > 0 {
+		avg = formatFloat(o.notional / o.executed)
+	}
+	options := o.options
+	if options == nil {
+		options = []string{}
+	}
+	return &private.Order{
+		OrderID:           id,
+		ID:                id,
+		ClientOrderID:     o.clientID,
+		Symbol:            o.symbol,
+		Exchange:          "gemini",
+		AvgExecutionPrice: avg,
+		Side:              o.side,
+		Type:              orderType,
+		Timestamp:         strconv.FormatInt(o.placedAt.Unix(), 10),
+		TimestampMs:       o.placedAt.UnixMilli(),
+		IsLive:            o.live,
+		IsCancelled:       o.cancelled,
+		ExecutedAmount:    formatFloat(o.executed),
+		Options:           options,
+		StopPrice:         stopPrice,
+		Price:             formatFloat(o.price),
+		OriginalAmount:    formatFloat(o.amount),
+	}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
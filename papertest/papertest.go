@@ -0,0 +1,58 @@
+// Package papertest provides market data and helpers for tests that trade against a paper.Trader.
+package papertest
+
+import (
+	"math"
+	"sync"
+
+	"github.com/austinjhunt/go-gemini/paper"
+)
+
+// Market is paper.MarketData serving a single bid and ask of unlimited size for every symbol, which tests move with
+// Set. Every symbol trades BTC against USD. It is safe for concurrent use
+type Market struct {
+	mu  sync.Mutex
+	bid float64
+	ask float64
+}
+
+var _ paper.MarketData = (*Market)(nil)
+
+func NewMarket(bid float64, ask float64) *Market {
+	/*
+		Create a market quoting bid and ask
+	*/
+	return &Market{bid: bid, ask: ask}
+}
+
+func (m *Market) Set(bid float64, ask float64) {
+	/*
+		Move the quote to bid and ask
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bid, m.ask = bid, ask
+}
+
+func (m *Market) Quote() (bid float64, ask float64) {
+	/*
+		Get the current bid and ask
+	*/
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bid, m.ask
+}
+
+func (m *Market) Book(symbol string) (*paper.Book, error) {
+	bid, ask := m.Quote()
+	return &paper.Book{Bids: []paper.Level{{Price: bid, Amount: math.Inf(1)}}, Asks: []paper.Level{{Price: ask, Amount: math.Inf(1)}}}, nil
+}
+
+func (m *Market) Currencies(symbol string) (string, string, error) {
+	return "BTC", "USD", nil
+}
+
+// Near reports whether two amounts or prices are equal but for floating point error
+func Near(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,118 @@
+// Package portfolio tracks positions and profit and loss per symbol from fills, keeping both an average cost basis
+// and FIFO lots, and marks positions to market.
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+// Cost basis methods used for realized and unrealized profit and loss
+const (
+	FIFO        = "fifo"
+	AverageCost = "average"
+)
+
+// Fill is an execution of an order. Fees are in the quote currency, as the exchange charges them for spot trades.
+type Fill struct {
+	Symbol string
+	// Side is "buy" or "sell"
+	Side   string
+	Amount float64
+	Price  float64
+	Fee    float64
+	Time   time.Time
+	// TradeID identifies the fill; fills with an id already seen are ignored. 0 disables the check
+	TradeID int64
+}
+
+// ErrFeeSkipped is returned with a fill whose trade paid its fee in a currency other than the symbol's; the fill is
+// usable but carries no fee
+var ErrFeeSkipped = errors.New("fee not included")
+
+func FromPastTrade(trade private.PastTrade) (Fill, error) {
+	/*
+		Convert a trade from GetPastTrades into a Fill. A fee paid in the base currency is valued at the trade price; a fee
+		in any other currency is left out and reported with ErrFeeSkipped
+	*/
+	fill := Fill{Symbol: strings.ToLower(trade.Symbol), Side: strings.ToLower(trade.Type), TradeID: trade.TID, Time: time.UnixMilli(trade.TimestampMs)}
+	if trade.TimestampMs == 0 {
+		fill.Time = time.Unix(trade.Timestamp, 0)
+	}
+	var err error
+	if fill.Amount, err = strconv.ParseFloat(trade.Amount, 64); err != nil {
+		return fill, errors.New("invalid trade amount " + trade.Amount)
+	}
+	if fill.Price, err = strconv.ParseFloat(trade.Price, 64); err != nil {
+		return fill, errors.New("invalid trade price " + trade.Price)
+	}
+	if trade.FeeAmount != "" {
+		if fill.Fee, err = strconv.ParseFloat(trade.FeeAmount, 64); err != nil {
+			return fill, errors.New("invalid trade fee " + trade.FeeAmount)
+		}
+		if currency := strings.ToLower(trade.FeeCurrency); currency != "" && !strings.HasSuffix(fill.Symbol, currency) {
+			if !strings.HasPrefix(fill.Symbol, currency) {
+				fill.Fee = 0
+				return fill, fmt.Errorf("%w: trade %d paid its fee in %s", ErrFeeSkipped, trade.TID, trade.FeeCurrency)
+			}
+			fill.Fee *= fill.Price
+		}
+	}
+	return fill, nil
+}
+
+// Lot is an open FIFO lot; Price includes the buy fee
+type Lot struct {
+	Amount float64
+	Price  float64
+	Time   time.Time
+}
+
+// Position is a snapshot of the position in a symbol
+type Position struct {
+	Symbol string
+	Amount float64
+	// AverageCost is the average cost per unit of Amount, including buy fees
+	AverageCost float64
+	Lots        []Lot
+	// RealizedPnL is the profit of sells net of fees, on the tracker's cost basis
+	RealizedPnL float64
+	Fees        float64
+	Bought      float64
+	Sold        float64
+	// Unmatched is the amount sold beyond the tracked position, e.g. of holdings bought before the fills tracked
+	Unmatched float64
+	// MarkPrice, MarketValue and UnrealizedPnL are set by Snapshot; UnrealizedPnL is on the tracker's cost basis
+	MarkPrice     float64
+	MarketValue   float64
+	UnrealizedPnL float64
+}
+
+// Snapshot is the marked state of every position
+type Snapshot struct {
+	Time          time.Time
+	Positions     []Position
+	RealizedPnL   float64
+	UnrealizedPnL float64
+	Fees          float64
+	// Errors lists symbols that could not be marked, with the reason; their unrealized P&L is 0
+	Errors map[string]string
+}
+
+func (s *Snapshot) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %14s %14s %14s %14s %14s %12s\n", "symbol", "amount", "avg cost", "mark", "unrealized", "realized", "fees")
+	for _, p := range s.Positions {
+		fmt.Fprintf(&b, "%-10s %14.8f %14.2f %14.2f %14.2f %14.2f %12.2f\n", p.Symbol, p.Amount, p.AverageCost, p.MarkPrice, p.UnrealizedPnL, p.RealizedPnL, p.Fees)
+	}
+	fmt.Fprintf(&b, "%-10s %14s %14s %14s %14.2f %14.2f %12.2f\n", "total", "", "", "", s.UnrealizedPnL, s.RealizedPnL, s.Fees)
+	for symbol, reason := range s.Errors {
+		fmt.Fprintf(&b, "%s not marked: %s\n", symbol, reason)
+	}
+	return b.String()
+}
@@ -0,0 +1,202 @@
+package portfolio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/papertest"
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+func fixedPrices(prices map[string]float64) PriceFunc {
+	return func(symbol string) (float64, error) {
+		if price, ok := prices[symbol]; ok {
+			return price, nil
+		}
+		return 0, errors.New("no price")
+	}
+}
+
+func addFills(t *testing.T, tracker *Tracker) {
+	t.Helper()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, fill := range []Fill{
+		{Symbol: "BTCUSD", Side: "buy", Amount: 1, Price: 100, Fee: 1, TradeID: 1},
+		{Symbol: "btcusd", Side: "buy", Amount: 1, Price: 200, Fee: 1, TradeID: 2},
+		{Symbol: "btcusd", Side: "buy", Amount: 1, Price: 200, Fee: 1, TradeID: 2}, // duplicate
+		{Symbol: "btcusd", Side: "sell", Amount: 1.5, Price: 300, Fee: 3, TradeID: 3},
+		{Symbol: "ethusd", Side: "buy", Amount: 2, Price: 10, TradeID: 4},
+	} {
+		fill.Time = at.Add(time.Duration(i) * time.Minute)
+		if err := tracker.Add(fill); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+}
+
+func TestTrackerFIFO(t *testing.T) {
+	tracker := NewTracker(WithPrices(fixedPrices(map[string]float64{"btcusd": 250})))
+	addFills(t, tracker)
+
+	// the sell consumes the first lot (101/unit) and half of the second (201/unit): 447 - 101 - 100.5
+	p := tracker.Position("btcusd")
+	if p.Amount != 0.5 || len(p.Lots) != 1 || p.Lots[0].Price != 201 || !papertest.Near(p.RealizedPnL, 245.5) || p.Fees != 5 {
+		t.Errorf("FIFO position %+v", p)
+	}
+	if p.AverageCost != 151 {
+		t.Errorf("average cost %v, expected 151", p.AverageCost)
+	}
+
+	snapshot := tracker.Snapshot()
+	btc := snapshot.Positions[0]
+	if btc.MarkPrice != 250 || !papertest.Near(btc.UnrealizedPnL, 0.5*(250-201)) {
+		t.Errorf("marked position %+v", btc)
+	}
+	if _, ok := snapshot.Errors["ethusd"]; !ok || !papertest.Near(snapshot.UnrealizedPnL, 24.5) || !papertest.Near(snapshot.RealizedPnL, 245.5) {
+		t.Errorf("snapshot %+v", snapshot)
+	}
+	if report := snapshot.String(); !strings.Contains(report, "btcusd") || !strings.Contains(report, "ethusd not marked") {
+		t.Errorf("report:\n%s", report)
+	}
+}
+
+func TestTrackerAverageCost(t *testing.T) {
+	tracker := NewTracker(WithBasis(AverageCost), WithPrices(fixedPrices(map[string]float64{"btcusd": 250, "ethusd": 10})))
+	addFills(t, tracker)
+	p := tracker.Position("btcusd")
+	if !papertest.Near(p.RealizedPnL, 447-1.5*151) {
+		t.Errorf("average cost realized P&L %v", p.RealizedPnL)
+	}
+	if snapshot := tracker.Snapshot(); !papertest.Near(snapshot.Positions[0].UnrealizedPnL, 0.5*(250-151)) || len(snapshot.Errors) != 0 {
+		t.Errorf("average cost snapshot %+v", snapshot)
+	}
+
+	// selling more than the tracked position records the excess without a cost
+	tracker.Add(Fill{Symbol: "ethusd", Side: "sell", Amount: 3, Price: 12})
+	if p := tracker.Position("ethusd"); p.Amount != 0 || p.Unmatched != 1 || !papertest.Near(p.RealizedPnL, 4) {
+		t.Errorf("oversold position %+v", p)
+	}
+}
+
+func TestTrackerSync(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	tracker := NewTracker(WithPrices(fixedPrices(map[string]float64{"btcusd": 31000})))
+
+	// the mock exchange's history holds a 0.01 BTC buy at 29000
+	if err := tracker.Sync(client, "btcusd"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if _, err := client.LimitSell("btcusd", 0.005, 30000); err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+	if err := tracker.Sync(client, "btcusd"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	p := tracker.Position("btcusd")
+	if !papertest.Near(p.Amount, 0.005) || !papertest.Near(p.RealizedPnL, 0.005*1000) || p.Bought != 0.01 {
+		t.Errorf("synced position %+v", p)
+	}
+}
+
+func TestTrackerSyncSymbols(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	now := time.Now()
+	server.SetClock(func() time.Time { return now })
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if _, err := client.LimitBuy("ethusd", 1, 2000); err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if _, err := client.LimitSell("btcusd", 0.005, 30000); err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+
+	// syncing btcusd first must not skip the older ethusd trade
+	tracker := NewTracker()
+	for _, symbol := range []string{"btcusd", "ethusd"} {
+		if err := tracker.Sync(client, symbol); err != nil {
+			t.Fatalf("Sync %s failed: %v", symbol, err)
+		}
+	}
+	if p := tracker.Position("ethusd"); p.Amount != 1 {
+		t.Errorf("ethusd position %+v", p)
+	}
+}
+
+func TestTrackerSyncPages(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	now := time.Now()
+	server.SetClock(func() time.Time { return now })
+	server.SetBalance("BTC", 1)
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	// with the seeded buy the history holds 521 trades, more than one page of 500
+	for i := 0; i < 260; i++ {
+		now = now.Add(time.Second)
+		if _, err := client.LimitBuy("btcusd", 0.001, 30000); err != nil {
+			t.Fatalf("LimitBuy failed: %v", err)
+		}
+		now = now.Add(time.Second)
+		if _, err := client.LimitSell("btcusd", 0.001, 30000); err != nil {
+			t.Fatalf("LimitSell failed: %v", err)
+		}
+	}
+	trades, err := client.GetAllPastTrades("btcusd", 0)
+	if err != nil || len(trades) != 521 {
+		t.Fatalf("expected 521 trades, got %d, %v", len(trades), err)
+	}
+	if trades[0].TimestampMs < trades[520].TimestampMs {
+		t.Error("expected the newest trade first")
+	}
+
+	tracker := NewTracker(WithPrices(fixedPrices(map[string]float64{"btcusd": 30000})))
+	if err := tracker.Sync(client, "btcusd"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	p := tracker.Position("btcusd")
+	if !papertest.Near(p.Amount, 0.01) || !papertest.Near(p.Bought, 0.27) || !papertest.Near(p.Sold, 0.26) {
+		t.Errorf("synced position %+v", p)
+	}
+}
+
+func TestFromPastTradeFees(t *testing.T) {
+	trade := private.PastTrade{TID: 1, Symbol: "BTCUSD", Type: "Buy", Amount: "0.5", Price: "30000", FeeAmount: "0.001", FeeCurrency: "BTC"}
+	fill, err := FromPastTrade(trade)
+	if err != nil || !papertest.Near(fill.Fee, 30) {
+		t.Errorf("base currency fee %+v, %v", fill, err)
+	}
+
+	trade.FeeAmount, trade.FeeCurrency = "15", "usd"
+	if fill, err = FromPastTrade(trade); err != nil || fill.Fee != 15 {
+		t.Errorf("quote currency fee %+v, %v", fill, err)
+	}
+
+	// a fee in another currency is left out, but the fill is still added
+	trade.FeeCurrency = "GUSD"
+	if fill, err = FromPastTrade(trade); !errors.Is(err, ErrFeeSkipped) || fill.Fee != 0 || fill.Amount != 0.5 {
+		t.Errorf("foreign fee %+v, %v", fill, err)
+	}
+	tracker := NewTracker()
+	if err := tracker.AddTrades([]private.PastTrade{trade}); !errors.Is(err, ErrFeeSkipped) {
+		t.Errorf("expected ErrFeeSkipped, got %v", err)
+	}
+	if p := tracker.Position("btcusd"); p.Amount != 0.5 {
+		t.Errorf("position %+v", p)
+	}
+}
@@ -0,0 +1,293 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// PriceFunc returns the price a symbol's position is marked at
+type PriceFunc func(symbol string) (float64, error)
+
+// Option configures optional behavior of a Tracker
+type Option func(*Tracker)
+
+func WithBasis(basis string) Option {
+	/*
+		Compute realized and unrealized profit and loss on the given cost basis, FIFO (the default) or AverageCost
+	*/
+	return func(t *Tracker) {
+		t.basis = basis
+	}
+}
+
+func WithPrices(prices PriceFunc) Option {
+	/*
+		Mark positions at the prices returned by prices instead of the last price of the v2 ticker
+	*/
+	return func(t *Tracker) {
+		t.prices = prices
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	/*
+		Timestamp snapshots with the given clock instead of time.Now
+	*/
+	return func(t *Tracker) {
+		t.now = now
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	/*
+		Log through the given logger instead of util.Logger()
+	*/
+	return func(t *Tracker) {
+		t.logger = logger
+	}
+}
+
+// Tracker maintains the positions built by the fills added to it
+type Tracker struct {
+	basis  string
+	prices PriceFunc
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	positions map[string]*Position
+	seen      map[int64]bool
+	// lastTrades is the time of the latest fill added, by symbol
+	lastTrades map[string]time.Time
+}
+
+func NewTracker(opts ...Option) *Tracker {
+	/*
+		Create an empty tracker. Feed it with Add, AddTrades or Sync
+	*/
+	t := &Tracker{basis: FIFO, prices: tickerPrice, now: time.Now, positions: map[string]*Position{}, seen: map[int64]bool{}, lastTrades: map[string]time.Time{}}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.logger == nil {
+		t.logger = util.Logger()
+	}
+	return t
+}
+
+// tickerPrice marks at the last price (close) of the v2 ticker
+func tickerPrice(symbol string) (float64, error) {
+	var ticker public.TickerV2
+	if err := public.GetPublicEndpoint("/v2/ticker/"+symbol, &ticker); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(ticker.Close, 64)
+}
+
+func (t *Tracker) Add(fill Fill) error {
+	/*
+		Apply a fill to its symbol's position. Fills must be added in the order they happened
+	*/
+	if fill.Amount <= 0 || fill.Price <= 0 {
+		return errors.New("portfolio: fill amount and price must be positive")
+	}
+	if fill.Side != "buy" && fill.Side != "sell" {
+		return errors.New("portfolio: fill side must be buy or sell, got " + fill.Side)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if fill.TradeID != 0 {
+		if t.seen[fill.TradeID] {
+			return nil
+		}
+		t.seen[fill.TradeID] = true
+	}
+	symbol := strings.ToLower(fill.Symbol)
+	if fill.Time.After(t.lastTrades[symbol]) {
+		t.lastTrades[symbol] = fill.Time
+	}
+	p, ok := t.positions[symbol]
+	if !ok {
+		p = &Position{Symbol: symbol}
+		t.positions[symbol] = p
+	}
+	p.Fees += fill.Fee
+
+	if fill.Side == "buy" {
+		cost := fill.Amount*fill.Price + fill.Fee
+		p.AverageCost = (p.AverageCost*p.Amount + cost) / (p.Amount + fill.Amount)
+		p.Amount += fill.Amount
+		p.Bought += fill.Amount
+		p.Lots = append(p.Lots, Lot{Amount: fill.Amount, Price: cost / fill.Amount, Time: fill.Time})
+		return nil
+	}
+
+	p.Sold += fill.Amount
+	matched := math.Min(fill.Amount, p.Amount)
+	if unmatched := fill.Amount - matched; unmatched > 1e-12 {
+		p.Unmatched += unmatched
+		t.logger.Warn("sell exceeds tracked position", "symbol", symbol, "amount", fill.Amount, "position", p.Amount)
+	}
+	// proceeds and fee of the matched part; the unmatched part has no known cost
+	proceeds := matched * (fill.Price - fill.Fee/fill.Amount)
+	fifoCost := 0.0
+	for remaining := matched; remaining > 1e-12 && len(p.Lots) > 0; {
+		lot := &p.Lots[0]
+		used := math.Min(remaining, lot.Amount)
+		fifoCost += used * lot.Price
+		lot.Amount -= used
+		remaining -= used
+		if lot.Amount <= 1e-12 {
+			p.Lots = p.Lots[1:]
+		}
+	}
+	if t.basis == AverageCost {
+		p.RealizedPnL += proceeds - matched*p.AverageCost
+	} else {
+		p.RealizedPnL += proceeds - fifoCost
+	}
+	p.Amount -= matched
+	if p.Amount <= 1e-12 {
+		p.Amount, p.AverageCost, p.Lots = 0, 0, nil
+	}
+	return nil
+}
+
+func (t *Tracker) AddTrades(trades []private.PastTrade) error {
+	/*
+		Add trades from GetPastTrades, in any order; they are applied oldest first and trades already seen are skipped.
+		Trades whose fee is left out (see FromPastTrade) are still added, and reported in the returned error
+	*/
+	sorted := append([]private.PastTrade(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].TimestampMs != sorted[j].TimestampMs {
+			return sorted[i].TimestampMs < sorted[j].TimestampMs
+		}
+		return sorted[i].TID < sorted[j].TID
+	})
+	var errs []error
+	for _, trade := range sorted {
+		fill, err := FromPastTrade(trade)
+		if err == nil || errors.Is(err, ErrFeeSkipped) {
+			err = errors.Join(err, t.Add(fill))
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Tracker) Sync(client *private.Client, symbol string) error {
+	/*
+		Add the trades of symbol made since the last trade of symbol the tracker has seen, paging through its whole
+		history the first time
+	*/
+	t.mu.Lock()
+	since := int64(0)
+	if last := t.lastTrades[strings.ToLower(symbol)]; !last.IsZero() {
+		since = last.Unix()
+	}
+	t.mu.Unlock()
+	trades, err := client.GetAllPastTrades(symbol, since)
+	if err != nil {
+		return errors.New("error syncing trades: " + err.Error())
+	}
+	return t.AddTrades(trades)
+}
+
+func (t *Tracker) Position(symbol string) Position {
+	/*
+		Get a copy of a symbol's position, without marking it to market
+	*/
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p, ok := t.positions[strings.ToLower(symbol)]; ok {
+		return copyPosition(p)
+	}
+	return Position{Symbol: strings.ToLower(symbol)}
+}
+
+func copyPosition(p *Position) Position {
+	copy := *p
+	copy.Lots = append([]Lot(nil), p.Lots...)
+	return copy
+}
+
+func (t *Tracker) Snapshot() *Snapshot {
+	/*
+		Mark every position to market and total the profit and loss. Positions that cannot be priced are listed in
+		Errors and contribute no unrealized profit and loss
+	*/
+	t.mu.Lock()
+	positions := make([]Position, 0, len(t.positions))
+	for _, p := range t.positions {
+		positions = append(positions, copyPosition(p))
+	}
+	t.mu.Unlock()
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+
+	snapshot := &Snapshot{Time: t.now(), Errors: map[string]string{}}
+	for i := range positions {
+		p := &positions[i]
+		if p.Amount > 0 {
+			price, err := t.prices(p.Symbol)
+			if err != nil {
+				snapshot.Errors[p.Symbol] = err.Error()
+			} else {
+				p.MarkPrice = price
+				p.MarketValue = p.Amount * price
+				p.UnrealizedPnL = p.MarketValue - t.costOf(p)
+			}
+		}
+		snapshot.RealizedPnL += p.RealizedPnL
+		snapshot.UnrealizedPnL += p.UnrealizedPnL
+		snapshot.Fees += p.Fees
+	}
+	snapshot.Positions = positions
+	return snapshot
+}
+
+// costOf is the cost of the open position on the tracker's cost basis
+func (t *Tracker) costOf(p *Position) float64 {
+	if t.basis == AverageCost {
+		return p.Amount * p.AverageCost
+	}
+	cost := 0.0
+	for _, lot := range p.Lots {
+		cost += lot.Amount * lot.Price
+	}
+	return cost
+}
+
+func (t *Tracker) Run(ctx context.Context, interval time.Duration, report func(*Snapshot)) error {
+	/*
+		Take a snapshot every interval and pass it to report (or log it when report is nil) until the context is done
+	*/
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		snapshot := t.Snapshot()
+		if report != nil {
+			report(snapshot)
+		} else {
+			t.logger.Info("portfolio snapshot", "realized_pnl", snapshot.RealizedPnL, "unrealized_pnl", snapshot.UnrealizedPnL, "fees", snapshot.Fees, "positions", len(snapshot.Positions))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
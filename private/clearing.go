@@ -0,0 +1,195 @@
+package private
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// invoke Gemini exchange REST API clearing (OTC) endpoints
+
+func NewClearingOrder(counterpartyID string, symbol string, side string, amount float64, price float64, expiresInHrs int) *ClearingOrder {
+	/*
+		Create a new clearing order to settle a block trade with a counterparty
+
+		Args:
+		counterpartyID (string): Optional. The counterparty id of the other side of the trade; empty for a broker clearing order
+		symbol (string): The trading pair symbol (e.g., "BTCUSD")
+		side (string): "buy" or "sell"
+		amount (float64): The amount of the asset to clear
+		price (float64): The price at which the trade will be settled
+		expiresInHrs (int): Number of hours the counterparty has to confirm the clearing order
+
+		Returns:
+		Pointer to a ClearingOrder holding the new clearing_id
+	*/
+	clearingOrder, err := DefaultClient().NewClearingOrder(counterpartyID, symbol, side, amount, price, expiresInHrs)
+	if err != nil {
+		log.Fatalf("Error creating clearing order: %v", err)
+		return nil
+	}
+	return clearingOrder
+}
+
+func (c *Client) NewClearingOrder(counterpartyID string, symbol string, side string, amount float64, price float64, expiresInHrs int) (*ClearingOrder, error) {
+	/*
+		Create a new clearing order to settle a block trade with a counterparty. See NewClearingOrder
+	*/
+	c.Logger().Info("NewClearingOrder", "counterparty_id", counterpartyID, "symbol", symbol, "side", side, "amount", amount, "price", price)
+	var clearingOrder ClearingOrder
+	payload, _ := json.Marshal(NewClearingOrderRequest{
+		CounterpartyID: counterpartyID,
+		ExpiresInHrs:   expiresInHrs,
+		Symbol:         symbol,
+		Amount:         strconv.FormatFloat(amount, 'f', 8, 64),
+		Price:          formatPrice(price),
+		Side:           side,
+		Request:        "/v1/clearing/new",
+		Nonce:          util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &clearingOrder); err != nil {
+		return nil, err
+	}
+	return &clearingOrder, nil
+}
+
+func GetClearingOrderStatus(clearingID string) *ClearingOrder {
+	/*
+		Get the status of a clearing order
+
+		Args:
+		clearingID (string): The clearing_id returned when the clearing order was created
+
+		Returns:
+		Pointer to a ClearingOrder holding the status (e.g., "AwaitConfirm", "Confirmed", "Settled")
+	*/
+	clearingOrder, err := DefaultClient().GetClearingOrderStatus(clearingID)
+	if err != nil {
+		log.Fatalf("Error fetching clearing order status: %v", err)
+		return nil
+	}
+	return clearingOrder
+}
+
+func (c *Client) GetClearingOrderStatus(clearingID string) (*ClearingOrder, error) {
+	/*
+		Get the status of a clearing order. See GetClearingOrderStatus
+	*/
+	c.Logger().Info("GetClearingOrderStatus", "clearing_id", clearingID)
+	return c.postClearingOrderRequest("/v1/clearing/status", clearingID)
+}
+
+func CancelClearingOrder(clearingID string) *ClearingOrder {
+	/*
+		Cancel a clearing order that has not been confirmed yet
+
+		Args:
+		clearingID (string): The clearing_id returned when the clearing order was created
+
+		Returns:
+		Pointer to a ClearingOrder holding the cancellation result
+	*/
+	clearingOrder, err := DefaultClient().CancelClearingOrder(clearingID)
+	if err != nil {
+		log.Fatalf("Error canceling clearing order: %v", err)
+		return nil
+	}
+	return clearingOrder
+}
+
+func (c *Client) CancelClearingOrder(clearingID string) (*ClearingOrder, error) {
+	/*
+		Cancel a clearing order that has not been confirmed yet. See CancelClearingOrder
+	*/
+	c.Logger().Info("CancelClearingOrder", "clearing_id", clearingID)
+	return c.postClearingOrderRequest("/v1/clearing/cancel", clearingID)
+}
+
+func (c *Client) postClearingOrderRequest(request string, clearingID string) (*ClearingOrder, error) {
+	var clearingOrder ClearingOrder
+	payload, _ := json.Marshal(ClearingOrderRequest{
+		ClearingID: clearingID,
+		Request:    request,
+		Nonce:      util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &clearingOrder); err != nil {
+		return nil, err
+	}
+	return &clearingOrder, nil
+}
+
+func ConfirmClearingOrder(clearingID string, symbol string, side string, amount float64, price float64) *ClearingOrder {
+	/*
+		Confirm a clearing order created by a counterparty. The details must match the order as created.
+
+		Args:
+		clearingID (string): The clearing_id shared by the counterparty
+		symbol (string): The trading pair symbol (e.g., "BTCUSD")
+		side (string): "buy" or "sell", from the confirming party's perspective
+		amount (float64): The amount of the asset to clear
+		price (float64): The price at which the trade will be settled
+
+		Returns:
+		Pointer to a ClearingOrder holding the confirmation result
+	*/
+	clearingOrder, err := DefaultClient().ConfirmClearingOrder(clearingID, symbol, side, amount, price)
+	if err != nil {
+		log.Fatalf("Error confirming clearing order: %v", err)
+		return nil
+	}
+	return clearingOrder
+}
+
+func (c *Client) ConfirmClearingOrder(clearingID string, symbol string, side string, amount float64, price float64) (*ClearingOrder, error) {
+	/*
+		Confirm a clearing order created by a counterparty. See ConfirmClearingOrder
+	*/
+	c.Logger().Info("ConfirmClearingOrder", "clearing_id", clearingID, "symbol", symbol, "side", side, "amount", amount, "price", price)
+	var clearingOrder ClearingOrder
+	payload, _ := json.Marshal(ConfirmClearingOrderRequest{
+		ClearingID: clearingID,
+		Symbol:     symbol,
+		Amount:     strconv.FormatFloat(amount, 'f', 8, 64),
+		Price:      formatPrice(price),
+		Side:       side,
+		Request:    "/v1/clearing/confirm",
+		Nonce:      util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &clearingOrder); err != nil {
+		return nil, err
+	}
+	return &clearingOrder, nil
+}
+
+func GetClearingTrades() []ClearingTrade {
+	/*
+		List the clearing trades the account is a party to
+
+		Returns:
+		An array of ClearingTrade objects
+	*/
+	trades, err := DefaultClient().GetClearingTrades()
+	if err != nil {
+		log.Fatalf("Error fetching clearing trades: %v", err)
+		return nil
+	}
+	return trades
+}
+
+func (c *Client) GetClearingTrades() ([]ClearingTrade, error) {
+	/*
+		List the clearing trades the account is a party to. See GetClearingTrades
+	*/
+	c.Logger().Info("GetClearingTrades")
+	var trades ClearingTrades
+	payload, _ := json.Marshal(GetClearingTradesRequest{
+		Request: "/v1/clearing/trades",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &trades); err != nil {
+		return nil, err
+	}
+	return trades.Results, nil
+}
@@ -0,0 +1,353 @@
+package private
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/telemetry"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// Client signs and sends requests to private Gemini API endpoints with credentials resolved once at construction
+type Client struct {
+	credentials     Credentials
+	trace           bool
+	sensitiveFields []string
+	redactor        *util.Redactor
+	logger          *slog.Logger
+	httpClient      *http.Client
+	middleware      []util.Middleware
+	metrics         telemetry.Metrics
+	tracer          telemetry.Tracer
+	baseURL         string
+}
+
+// ClientOption configures optional behavior of a Client
+type ClientOption func(*Client)
+
+func WithTrace(trace bool) ClientOption {
+	/*
+		Log every request payload and response body (with sensitive fields redacted). Off by default.
+	*/
+	return func(c *Client) {
+		c.trace = trace
+	}
+}
+
+func WithLogger(logger *slog.Logger) ClientOption {
+	/*
+		Log through the given logger instead of util.Logger()
+	*/
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	/*
+		Send requests with the given HTTP client instead of util.HTTPClient()
+	*/
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithMiddleware(middleware ...util.Middleware) ClientOption {
+	/*
+		Wrap the client's HTTP transport in middleware, in addition to any middleware added with util.Use
+	*/
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+func WithBaseURL(baseURL string) ClientOption {
+	/*
+		Send requests to the given base URL (e.g. a geminitest server) instead of util.GetBaseAPIUrl()
+	*/
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+func WithMetrics(metrics telemetry.Metrics) ClientOption {
+	/*
+		Record request metrics to the given metrics instead of telemetry.DefaultMetrics()
+	*/
+	return func(c *Client) {
+		c.metrics = metrics
+	}
+}
+
+func WithTracer(tracer telemetry.Tracer) ClientOption {
+	/*
+		Start a span for every request with the given tracer instead of telemetry.DefaultTracer()
+	*/
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+func WithSensitiveFields(fields ...string) ClientOption {
+	/*
+		Redact the given JSON fields, in addition to util.DefaultSensitiveFields, from traced payloads and responses
+	*/
+	return func(c *Client) {
+		c.sensitiveFields = append(c.sensitiveFields, fields...)
+	}
+}
+
+var (
+	defaultClient     *Client
+	defaultClientLock sync.Mutex
+)
+
+func NewClient(provider CredentialsProvider, opts ...ClientOption) (*Client, error) {
+	/*
+		Create a client, resolving its credentials from the provider
+
+		Args:
+		provider (CredentialsProvider): source of the API key and secret, e.g. StaticCredentials, EnvCredentials, FileCredentials or CommandCredentials
+		opts (...ClientOption): optional behavior, e.g. WithTrace
+
+		Returns a pointer to the Client, or an error if the credentials cannot be resolved
+	*/
+	credentials, err := provider.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	return newClient(credentials, opts...), nil
+}
+
+func newClient(credentials Credentials, opts ...ClientOption) *Client {
+	c := &Client{credentials: credentials}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.redactor = util.NewRedactor(c.sensitiveFields...).WithSecrets(credentials.APIKey, credentials.APISecret)
+	return c
+}
+
+func DefaultClient() *Client {
+	/*
+		Get the client used by the package-level private endpoint functions.
+
+		Unless replaced with SetDefaultClient, it is created on first use from DefaultCredentialsProvider, with tracing
+		enabled if GEMINI_EXCHANGE_API_TRACE is "true". If no credentials can be resolved the client is still created,
+		and requests will be rejected by the API.
+	*/
+	defaultClientLock.Lock()
+	defer defaultClientLock.Unlock()
+	if defaultClient == nil {
+		trace := WithTrace(strings.ToLower(util.GetEnvOrDefault("GEMINI_EXCHANGE_API_TRACE", "false")) == "true")
+		client, err := NewClient(DefaultCredentialsProvider(), trace)
+		if err != nil {
+			util.Logger().Warn("unable to resolve private API credentials", "error", err)
+			client = newClient(Credentials{}, trace)
+		}
+		defaultClient = client
+	}
+	return defaultClient
+}
+
+func SetDefaultClient(client *Client) {
+	/*
+		Replace the client used by the package-level private endpoint functions
+	*/
+	defaultClientLock.Lock()
+	defer defaultClientLock.Unlock()
+	defaultClient = client
+}
+
+func (c *Client) Logger() *slog.Logger {
+	/*
+		Get the logger the client writes to: the one given with WithLogger, or util.Logger()
+	*/
+	if c.logger != nil {
+		return c.logger
+	}
+	return util.Logger()
+}
+
+func (c *Client) HTTPClient() *http.Client {
+	/*
+		Get the HTTP client requests are sent with: the one given with WithHTTPClient or util.HTTPClient(), wrapped in the client's middleware
+	*/
+	base := c.httpClient
+	if base == nil {
+		base = util.HTTPClient()
+	}
+	if len(c.middleware) == 0 {
+		return base
+	}
+	wrapped := *base
+	wrapped.Transport = util.Chain(base.Transport, c.middleware...)
+	return &wrapped
+}
+
+// logger is the logger of the default client, used by the package-level endpoint functions
+func logger() *slog.Logger {
+	return DefaultClient().Logger()
+}
+
+func (c *Client) Metrics() telemetry.Metrics {
+	/*
+		Get the metrics the client records to: the ones given with WithMetrics, or telemetry.DefaultMetrics()
+	*/
+	if c.metrics != nil {
+		return c.metrics
+	}
+	return telemetry.DefaultMetrics()
+}
+
+func (c *Client) Tracer() telemetry.Tracer {
+	/*
+		Get the tracer the client starts spans with: the one given with WithTracer, or telemetry.DefaultTracer()
+	*/
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return telemetry.DefaultTracer()
+}
+
+func (c *Client) PostPrivateEndpoint(payload []byte, target interface{}) error {
+	/*
+		Perform an HTTP POST request on a private Gemini API endpoint and unmarshal the JSON response into the provided target interface.
+		Requests rejected with HTTP 429 are retried, up to util.MaxRateLimitRetries times, with a fresh nonce.
+
+		Args:
+		payload - post payload
+		target - any type of JSON object in which the JSON response gets stored, passed as &target (pointer to a variable in which response is to be stored) when method is invoked
+
+		Returns nothing if successful, returns error if it fails
+	*/
+	var payloadJSON map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payloadJSON); err != nil {
+		return errors.New("Error parsing payload JSON: " + err.Error())
+	}
+	request, ok := payloadJSON["request"].(string)
+	if !ok {
+		return errors.New("Error: payload is missing the request endpoint")
+	}
+	logger := c.Logger().With("endpoint", request)
+	metrics := c.Metrics()
+	_, span := c.Tracer().Start(context.Background(), "POST "+request)
+	defer span.End()
+	span.SetAttributes(telemetry.String("api", "private"), telemetry.String("endpoint", request))
+
+	var status int
+	var body []byte
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// a retried request must be signed with a new nonce
+			payloadJSON["nonce"] = util.GenerateNonceString()
+			payload, _ = json.Marshal(payloadJSON)
+		}
+		if c.trace {
+			logger.Info("private request trace", "payload", c.redactor.RedactJSON(payload))
+		}
+
+		var res *http.Response
+		var err error
+		status, body, res, err = c.send(request, payload)
+		if err != nil {
+			metrics.IncError("private", request, "RequestFailed")
+			span.RecordError(err)
+			return err
+		}
+
+		if c.trace {
+			logger.Info("private response trace", "status", status, "body", c.redactor.RedactJSON(body))
+		}
+		if status != http.StatusTooManyRequests || attempt >= util.MaxRateLimitRetries {
+			break
+		}
+		wait := util.RateLimitWait(res, attempt+1)
+		logger.Warn("private request rate limited", "wait", wait, "attempt", attempt+1)
+		metrics.IncRetry("private", request)
+		metrics.ObserveRateLimitWait("private", request, wait)
+		util.Sleep(wait)
+	}
+	span.SetAttributes(telemetry.Int("status", status))
+
+	// Handle non-OK HTTP status codes
+	if status != http.StatusOK {
+		reason := util.APIErrorReason(status, body)
+		metrics.IncError("private", request, reason)
+		span.SetAttributes(telemetry.String("reason", reason))
+		err := errors.New("Error: status " + strconv.Itoa(status) + " " + http.StatusText(status) + ", response: " + c.redactor.RedactJSON(body))
+		span.RecordError(err)
+		return err
+	}
+
+	// Parse the response JSON into the target interface
+	if err := json.Unmarshal(body, target); err != nil {
+		span.RecordError(err)
+		return errors.New("Error parsing response JSON: " + err.Error())
+	}
+
+	return nil
+}
+
+// send signs the payload and posts it to the request endpoint, returning the status, body and response of the request
+func (c *Client) send(request string, payload []byte) (int, []byte, *http.Response, error) {
+	baseURL := c.baseURL
+	if baseURL == "" {
+		baseURL = util.GetBaseAPIUrl()
+	}
+	url := baseURL + request
+	logger := c.Logger().With("endpoint", request)
+
+	// Base64 encode the JSON payload
+	b64Payload := base64.StdEncoding.EncodeToString(payload)
+
+	// Create the HMAC signature using SHA384
+	h := hmac.New(sha512.New384, []byte(c.credentials.APISecret))
+	h.Write([]byte(b64Payload))
+	signature := fmt.Sprintf("%x", h.Sum(nil))
+
+	// Prepare the HTTP request headers
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return 0, nil, nil, errors.New("Error creating request: " + err.Error())
+	}
+
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Content-Length", "0")
+	req.Header.Set("X-GEMINI-APIKEY", c.credentials.APIKey)
+	req.Header.Set("X-GEMINI-PAYLOAD", b64Payload)
+	req.Header.Set("X-GEMINI-SIGNATURE", signature)
+	req.Header.Set("Cache-Control", "no-cache")
+
+	// Send the request
+	start := time.Now()
+	resp, err := c.HTTPClient().Do(req)
+	if err != nil {
+		logger.Error("private request failed", "latency", time.Since(start), "error", err)
+		return 0, nil, nil, fmt.Errorf("Error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read the response
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(resp.Body)
+	latency := time.Since(start)
+	logger.Info("private request", "status", resp.StatusCode, "latency", latency)
+	c.Metrics().ObserveRequest("private", request, resp.StatusCode, latency)
+
+	return resp.StatusCode, buf.Bytes(), resp, nil
+}
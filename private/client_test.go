@@ -0,0 +1,112 @@
+package private
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/telemetry"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+func TestClientRedactsTrace(t *testing.T) {
+	client := newClient(Credentials{APIKey: "account-key-123", APISecret: "secret-456"}, WithTrace(true), WithSensitiveFields("address"))
+	if !client.trace {
+		t.Errorf("WithTrace did not enable tracing")
+	}
+	redacted := client.redactor.RedactJSON([]byte(`{"request":"/v1/withdraw/btc","address":"bc1qxyz","memo":"account-key-123 secret-456"}`))
+	for _, leaked := range []string{"bc1qxyz", "account-key-123", "secret-456"} {
+		if strings.Contains(redacted, leaked) {
+			t.Errorf("trace output leaked %q: %s", leaked, redacted)
+		}
+	}
+}
+
+func TestClientRetriesRateLimitedRequests(t *testing.T) {
+	previousSleep := util.Sleep
+	util.Sleep = func(time.Duration) {}
+	defer func() { util.Sleep = previousSleep }()
+
+	var nonces []string
+	transport := util.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		payload, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-GEMINI-PAYLOAD"))
+		var decoded map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(string(payload)))
+		decoder.UseNumber()
+		decoder.Decode(&decoded)
+		nonces = append(nonces, decoded["nonce"].(string))
+		if len(nonces) == 1 {
+			return fakeResponse(http.StatusTooManyRequests, `{"result":"error","reason":"RateLimit"}`, "Retry-After", "2"), nil
+		}
+		if decoded["order_id"] != json.Number("123456789012345678") {
+			t.Errorf("retried payload changed order_id: %v", decoded["order_id"])
+		}
+		return fakeResponse(http.StatusBadRequest, `{"result":"error","reason":"OrderNotFound","message":"Order 1 not found"}`), nil
+	})
+
+	metrics := telemetry.NewInMemoryMetrics()
+	tracer := telemetry.NewInMemoryTracer()
+	client := newClient(Credentials{APIKey: "key", APISecret: "secret"},
+		WithHTTPClient(&http.Client{Transport: transport}), WithMetrics(metrics), WithTracer(tracer))
+
+	var order Order
+	err := client.PostPrivateEndpoint([]byte(`{"request":"/v1/order/status","nonce":"1","order_id":123456789012345678}`), &order)
+	if err == nil || !strings.Contains(err.Error(), "OrderNotFound") {
+		t.Fatalf("expected an OrderNotFound error, got %v", err)
+	}
+	if len(nonces) != 2 || nonces[0] == nonces[1] {
+		t.Errorf("retry did not use a fresh nonce: %v", nonces)
+	}
+	if metrics.Retries("private", "/v1/order/status") != 1 {
+		t.Errorf("retry was not counted")
+	}
+	if metrics.RateLimitWaits("private", "/v1/order/status").Sum != 2 {
+		t.Errorf("rate limit wait was not recorded from Retry-After")
+	}
+	if metrics.Errors("private", "/v1/order/status", "OrderNotFound") != 1 {
+		t.Errorf("error reason was not counted")
+	}
+	if metrics.Requests("private", "/v1/order/status", http.StatusTooManyRequests) != 1 || metrics.Requests("private", "/v1/order/status", http.StatusBadRequest) != 1 {
+		t.Errorf("requests were not counted by status")
+	}
+	spans := tracer.Spans()
+	if len(spans) != 1 || spans[0].Attributes["reason"] != "OrderNotFound" || spans[0].Attributes["status"] != http.StatusBadRequest {
+		t.Errorf("unexpected spans: %+v", spans)
+	}
+}
+
+func TestClientMiddleware(t *testing.T) {
+	var seenHeader string
+	transport := util.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		seenHeader = req.Header.Get(util.RequestIDHeader)
+		return fakeResponse(http.StatusOK, `[]`), nil
+	})
+	client := newClient(Credentials{APIKey: "key", APISecret: "secret"},
+		WithHTTPClient(&http.Client{Transport: transport}), WithMiddleware(util.RequestIDMiddleware()))
+
+	var balances []AvailableBalance
+	if err := client.PostPrivateEndpoint([]byte(`{"request":"/v1/balances","nonce":"1"}`), &balances); err != nil {
+		t.Fatalf("PostPrivateEndpoint failed: %v", err)
+	}
+	if seenHeader == "" {
+		t.Errorf("client middleware did not run")
+	}
+}
+
+func fakeResponse(status int, body string, header ...string) *http.Response {
+	res := &http.Response{
+		StatusCode: status,
+		Status:     strconv.Itoa(status) + " " + http.StatusText(status),
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		res.Header.Set(header[i], header[i+1])
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package private
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Credentials are the API key and secret used to sign private API requests
+type Credentials struct {
+	APIKey    string
+	APISecret string
+}
+
+// CredentialsProvider resolves the API credentials used by a Client
+type CredentialsProvider interface {
+	Credentials() (Credentials, error)
+}
+
+const (
+	apiKeyVariable    = "GEMINI_EXCHANGE_API_KEY"
+	apiSecretVariable = "GEMINI_EXCHANGE_API_SECRET"
+)
+
+var ErrMissingCredentials = errors.New(apiKeyVariable + " and " + apiSecretVariable + " are not both present")
+
+func (c Credentials) complete() bool {
+	return c.APIKey != "" && c.APISecret != ""
+}
+
+// StaticCredentials provides a fixed API key and secret
+type StaticCredentials Credentials
+
+func (s StaticCredentials) Credentials() (Credentials, error) {
+	credentials := Credentials(s)
+	if !credentials.complete() {
+		return Credentials{}, ErrMissingCredentials
+	}
+	return credentials, nil
+}
+
+// EnvCredentials reads the API key and secret from environment variables.
+// KeyVariable and SecretVariable default to GEMINI_EXCHANGE_API_KEY and GEMINI_EXCHANGE_API_SECRET.
+type EnvCredentials struct {
+	KeyVariable    string
+	SecretVariable string
+}
+
+func (e EnvCredentials) Credentials() (Credentials, error) {
+	return lookupCredentials(os.Getenv, e.KeyVariable, e.SecretVariable)
+}
+
+// FileCredentials reads the API key and secret from a .env formatted file without modifying the process environment
+type FileCredentials struct {
+	Path string
+}
+
+func (f FileCredentials) Credentials() (Credentials, error) {
+	values, err := godotenv.Read(f.Path)
+	if err != nil {
+		return Credentials{}, errors.New("error reading credentials file: " + err.Error())
+	}
+	return lookupCredentials(func(key string) string { return values[key] }, "", "")
+}
+
+// CommandCredentials runs a command (e.g. a secret manager CLI) and reads the API key and secret
+// from its standard output, which must be in .env format:
+//
+//	GEMINI_EXCHANGE_API_KEY=...
+//	GEMINI_EXCHANGE_API_SECRET=...
+type CommandCredentials struct {
+	Name string
+	Args []string
+}
+
+func (c CommandCredentials) Credentials() (Credentials, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.Name, c.Args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return Credentials{}, errors.New("error running credentials command: " + err.Error() + ": " + strings.TrimSpace(stderr.String()))
+	}
+	values, err := godotenv.Unmarshal(string(output))
+	if err != nil {
+		return Credentials{}, errors.New("error parsing credentials command output: " + err.Error())
+	}
+	return lookupCredentials(func(key string) string { return values[key] }, "", "")
+}
+
+// ChainCredentials tries each provider in order and returns the first complete set of credentials
+type ChainCredentials []CredentialsProvider
+
+func (c ChainCredentials) Credentials() (Credentials, error) {
+	var errs []error
+	for _, provider := range c {
+		credentials, err := provider.Credentials()
+		if err == nil {
+			return credentials, nil
+		}
+		errs = append(errs, err)
+	}
+	return Credentials{}, errors.Join(append([]error{ErrMissingCredentials}, errs...)...)
+}
+
+// DefaultCredentialsProvider reads credentials from the environment, falling back to a .env file in the working directory
+func DefaultCredentialsProvider() CredentialsProvider {
+	return ChainCredentials{EnvCredentials{}, FileCredentials{Path: ".env"}}
+}
+
+func lookupCredentials(lookup func(string) string, keyVariable string, secretVariable string) (Credentials, error) {
+	if keyVariable == "" {
+		keyVariable = apiKeyVariable
+	}
+	if secretVariable == "" {
+		secretVariable = apiSecretVariable
+	}
+	credentials := Credentials{APIKey: lookup(keyVariable), APISecret: lookup(secretVariable)}
+	if !credentials.complete() {
+		return Credentials{}, ErrMissingCredentials
+	}
+	return credentials, nil
+}
@@ -0,0 +1,108 @@
+package private
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticCredentials(t *testing.T) {
+	credentials, err := StaticCredentials{APIKey: "key", APISecret: "secret"}.Credentials()
+	if err != nil {
+		t.Fatalf("StaticCredentials failed: %v", err)
+	}
+	if credentials.APIKey != "key" || credentials.APISecret != "secret" {
+		t.Errorf("StaticCredentials returned %+v", credentials)
+	}
+	if _, err := (StaticCredentials{APIKey: "key"}).Credentials(); !errors.Is(err, ErrMissingCredentials) {
+		t.Errorf("StaticCredentials without a secret should fail with ErrMissingCredentials, got %v", err)
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	t.Setenv("CUSTOM_KEY", "env-key")
+	t.Setenv("CUSTOM_SECRET", "env-secret")
+	credentials, err := EnvCredentials{KeyVariable: "CUSTOM_KEY", SecretVariable: "CUSTOM_SECRET"}.Credentials()
+	if err != nil {
+		t.Fatalf("EnvCredentials failed: %v", err)
+	}
+	if credentials.APIKey != "env-key" || credentials.APISecret != "env-secret" {
+		t.Errorf("EnvCredentials returned %+v", credentials)
+	}
+
+	t.Setenv("GEMINI_EXCHANGE_API_KEY", "")
+	if _, err := (EnvCredentials{}).Credentials(); !errors.Is(err, ErrMissingCredentials) {
+		t.Errorf("EnvCredentials without variables should fail with ErrMissingCredentials, got %v", err)
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	contents := "GEMINI_EXCHANGE_API_KEY=file-key\nGEMINI_EXCHANGE_API_SECRET=file-secret\n"
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("failed to write credentials file: %v", err)
+	}
+	t.Setenv("GEMINI_EXCHANGE_API_KEY", "")
+	credentials, err := FileCredentials{Path: path}.Credentials()
+	if err != nil {
+		t.Fatalf("FileCredentials failed: %v", err)
+	}
+	if credentials.APIKey != "file-key" || credentials.APISecret != "file-secret" {
+		t.Errorf("FileCredentials returned %+v", credentials)
+	}
+	// reading the file must not modify the environment
+	if os.Getenv("GEMINI_EXCHANGE_API_KEY") != "" {
+		t.Errorf("FileCredentials modified the environment")
+	}
+	if _, err := (FileCredentials{Path: filepath.Join(t.TempDir(), "missing")}).Credentials(); err == nil {
+		t.Errorf("FileCredentials should fail on a missing file")
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	provider := CommandCredentials{
+		Name: "sh",
+		Args: []string{"-c", "echo GEMINI_EXCHANGE_API_KEY=cmd-key; echo GEMINI_EXCHANGE_API_SECRET=cmd-secret"},
+	}
+	credentials, err := provider.Credentials()
+	if err != nil {
+		t.Fatalf("CommandCredentials failed: %v", err)
+	}
+	if credentials.APIKey != "cmd-key" || credentials.APISecret != "cmd-secret" {
+		t.Errorf("CommandCredentials returned %+v", credentials)
+	}
+	if _, err := (CommandCredentials{Name: "sh", Args: []string{"-c", "exit 1"}}).Credentials(); err == nil {
+		t.Errorf("CommandCredentials should fail when the command fails")
+	}
+}
+
+func TestChainCredentials(t *testing.T) {
+	chain := ChainCredentials{
+		StaticCredentials{},
+		StaticCredentials{APIKey: "second-key", APISecret: "second-secret"},
+	}
+	credentials, err := chain.Credentials()
+	if err != nil {
+		t.Fatalf("ChainCredentials failed: %v", err)
+	}
+	if credentials.APIKey != "second-key" {
+		t.Errorf("ChainCredentials returned %+v", credentials)
+	}
+	if _, err := (ChainCredentials{StaticCredentials{}}).Credentials(); !errors.Is(err, ErrMissingCredentials) {
+		t.Errorf("ChainCredentials without complete credentials should fail with ErrMissingCredentials, got %v", err)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := NewClient(StaticCredentials{APIKey: "key", APISecret: "secret"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if client.credentials.APIKey != "key" {
+		t.Errorf("NewClient did not resolve credentials: %+v", client.credentials)
+	}
+	if _, err := NewClient(StaticCredentials{}); err == nil {
+		t.Errorf("NewClient should fail without credentials")
+	}
+}
@@ -0,0 +1,184 @@
+package private
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// invoke Gemini exchange REST API derivatives (perpetuals) endpoints
+
+// PositionFunding ties an open perpetual position to the funding amount currently published for its symbol
+type PositionFunding struct {
+	Position Position
+	Funding  public.FundingAmount
+}
+
+func GetOpenPositions() []Position {
+	/*
+		Get all open perpetual positions for the account
+
+		Returns:
+		An array of Position objects; empty if there are no open positions
+	*/
+	positions, err := DefaultClient().GetOpenPositions()
+	if err != nil {
+		log.Fatalf("Error fetching open positions: %v", err)
+		return nil
+	}
+	return positions
+}
+
+func (c *Client) GetOpenPositions() ([]Position, error) {
+	/*
+		Get all open perpetual positions for the account. See GetOpenPositions
+	*/
+	c.Logger().Info("GetOpenPositions")
+	var positions []Position
+	payload, _ := json.Marshal(GetOpenPositionsRequest{
+		Request: "/v1/positions",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+func GetAccountMargin(symbol string) *AccountMargin {
+	/*
+		Get margin details of the derivatives account for a perpetual symbol
+
+		Args:
+		symbol (string): Trading pair symbol. Available only for perpetual pairs like BTCGUSDPERP
+
+		Returns:
+		Pointer to an AccountMargin object
+	*/
+	margin, err := DefaultClient().GetAccountMargin(symbol)
+	if err != nil {
+		log.Fatalf("Error fetching account margin: %v", err)
+		return nil
+	}
+	return margin
+}
+
+func (c *Client) GetAccountMargin(symbol string) (*AccountMargin, error) {
+	/*
+		Get margin details of the derivatives account for a perpetual symbol. See GetAccountMargin
+	*/
+	c.Logger().Info("GetAccountMargin", "symbol", symbol)
+	var margin AccountMargin
+	payload, _ := json.Marshal(GetAccountMarginRequest{
+		Symbol:  symbol,
+		Request: "/v1/margin",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &margin); err != nil {
+		return nil, err
+	}
+	return &margin, nil
+}
+
+func GetFundingPayments(since int64, to int64) []FundingPayment {
+	/*
+		Get the funding payments credited or debited to the account for perpetual positions
+
+		Args:
+		since (int64): Optional. Only return payments at or after this timestamp (milliseconds); 0 to omit
+		to (int64): Optional. Only return payments at or before this timestamp (milliseconds); 0 to omit
+
+		Returns:
+		An array of FundingPayment objects
+	*/
+	payments, err := DefaultClient().GetFundingPayments(since, to)
+	if err != nil {
+		log.Fatalf("Error fetching funding payments: %v", err)
+		return nil
+	}
+	return payments
+}
+
+func (c *Client) GetFundingPayments(since int64, to int64) ([]FundingPayment, error) {
+	/*
+		Get the funding payments of the account's perpetual positions. See GetFundingPayments
+	*/
+	c.Logger().Info("GetFundingPayments", "since", since, "to", to)
+	var payments []FundingPayment
+	payload, _ := json.Marshal(GetFundingPaymentsRequest{
+		Since:   since,
+		To:      to,
+		Request: "/v1/perpetuals/fundingPayment",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &payments); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func GetPositionsWithFunding() []PositionFunding {
+	/*
+		Get all open perpetual positions, each paired with the funding amount published for its symbol
+
+		Returns:
+		An array of PositionFunding objects, one per open position
+	*/
+	result, err := DefaultClient().GetPositionsWithFunding()
+	if err != nil {
+		log.Fatalf("Error fetching positions with funding: %v", err)
+		return nil
+	}
+	return result
+}
+
+func (c *Client) GetPositionsWithFunding() ([]PositionFunding, error) {
+	/*
+		Get all open perpetual positions, each paired with the funding amount published for its symbol. See
+		GetPositionsWithFunding
+	*/
+	c.Logger().Info("GetPositionsWithFunding")
+	positions, err := c.GetOpenPositions()
+	if err != nil {
+		return nil, err
+	}
+	fundingBySymbol := map[string]public.FundingAmount{}
+	var result []PositionFunding
+	for _, position := range positions {
+		symbol := strings.ToLower(position.Symbol)
+		funding, ok := fundingBySymbol[symbol]
+		if !ok {
+			if err := public.GetPublicEndpoint("/v1/fundingamount/"+symbol, &funding); err != nil {
+				return nil, errors.New("error fetching the " + symbol + " funding amount: " + err.Error())
+			}
+			fundingBySymbol[symbol] = funding
+		}
+		result = append(result, PositionFunding{Position: position, Funding: funding})
+	}
+	return result, nil
+}
+
+func (pf PositionFunding) EstimatedNextPayment() (float64, error) {
+	/*
+		Estimate the funding payment for the position at the next funding timestamp.
+
+		Gemini publishes the funding amount for a long position of 1 contract; a positive amount means longs pay shorts.
+		The position quantity is signed (negative for shorts), so the estimate is -quantity * estimatedFundingAmount.
+
+		Returns:
+		The estimated payment from the account's perspective: positive if the account will be credited, negative if debited
+	*/
+	quantity, err := strconv.ParseFloat(pf.Position.Quantity, 64)
+	if err != nil {
+		return 0, errors.New("invalid position quantity: " + err.Error())
+	}
+	if quantity == 0 {
+		return 0, nil
+	}
+	return -quantity * pf.Funding.EstimatedFundingAmount, nil
+}
@@ -3,6 +3,7 @@ package private
 type Order struct {
 	OrderID           string   `json:"order_id"`
 	ID                string   `json:"id"`
+	ClientOrderID     string   `json:"client_order_id"`
 	Symbol            string   `json:"symbol"`
 	Exchange          string   `json:"exchange"`
 	AvgExecutionPrice string   `json:"avg_execution_price"`
@@ -44,14 +45,15 @@ type StopLimitOrderRequest struct {
 }
 
 type LimitOrderRequest struct {
-	ClientOrderID string `json:"client_order_id"`
-	Symbol        string `json:"symbol"`
-	Amount        string `json:"amount"`
-	Price         string `json:"price"`
-	Side          string `json:"side"`
-	Type          string `json:"type"`
-	Request       string `json:"request"`
-	Nonce         string `json:"nonce"`
+	ClientOrderID string   `json:"client_order_id"`
+	Symbol        string   `json:"symbol"`
+	Amount        string   `json:"amount"`
+	Price         string   `json:"price"`
+	Side          string   `json:"side"`
+	Type          string   `json:"type"`
+	Options       []string `json:"options,omitempty"`
+	Request       string   `json:"request"`
+	Nonce         string   `json:"nonce"`
 }
 
 type CancelOrderRequest struct {
@@ -60,6 +62,70 @@ type CancelOrderRequest struct {
 	Nonce   string `json:"nonce"`
 }
 
+type GetActiveOrdersRequest struct {
+	Request string `json:"request"`
+	Nonce   string `json:"nonce"`
+}
+
+type CancelAllOrdersRequest struct {
+	Request string `json:"request"`
+	Nonce   string `json:"nonce"`
+}
+
+type CancelAllOrdersResponse struct {
+	Result  string `json:"result"`
+	Details struct {
+		CancelledOrders []int64 `json:"cancelledOrders"`
+		CancelRejects   []int64 `json:"cancelRejects"`
+	} `json:"details"`
+}
+
+type GetPastTradesRequest struct {
+	Symbol      string `json:"symbol,omitempty"`
+	Timestamp   int64  `json:"timestamp,omitempty"`
+	LimitTrades int    `json:"limit_trades"`
+	Request     string `json:"request"`
+	Nonce       string `json:"nonce"`
+}
+
+type PastTrade struct {
+	Price          string `json:"price"`
+	Amount         string `json:"amount"`
+	Timestamp      int64  `json:"timestamp"`
+	TimestampMs    int64  `json:"timestampms"`
+	Type           string `json:"type"`
+	Aggressor      bool   `json:"aggressor"`
+	FeeCurrency    string `json:"fee_currency"`
+	FeeAmount      string `json:"fee_amount"`
+	TID            int64  `json:"tid"`
+	OrderID        string `json:"order_id"`
+	ClientOrderID  string `json:"client_order_id"`
+	Exchange       string `json:"exchange"`
+	IsAuctionFill  bool   `json:"is_auction_fill"`
+	IsClearingFill bool   `json:"is_clearing_fill"`
+	Symbol         string `json:"symbol"`
+}
+
+type GetTransfersRequest struct {
+	Timestamp      int64  `json:"timestamp,omitempty"`
+	LimitTransfers int    `json:"limit_transfers"`
+	Request        string `json:"request"`
+	Nonce          string `json:"nonce"`
+}
+
+type Transfer struct {
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+	TimestampMs int64  `json:"timestampms"`
+	EID         int64  `json:"eid"`
+	Currency    string `json:"currency"`
+	Amount      string `json:"amount"`
+	Method      string `json:"method"`
+	TxHash      string `json:"txHash"`
+	Destination string `json:"destination"`
+	Purpose     string `json:"purpose"`
+}
+
 type AvailableBalance struct {
 	Type                   string `json:"type"`
 	Currency               string `json:"currency"`
@@ -72,3 +138,204 @@ type GetAvailableBalancesRequest struct {
 	Request string `json:"request"`
 	Nonce   string `json:"nonce"`
 }
+
+type Position struct {
+	Symbol         string `json:"symbol"`
+	InstrumentType string `json:"instrument_type"`
+	Quantity       string `json:"quantity"`
+	NotionalValue  string `json:"notional_value"`
+	RealisedPnl    string `json:"realised_pnl"`
+	UnrealisedPnl  string `json:"unrealised_pnl"`
+	MarkPrice      string `json:"mark_price"`
+}
+
+type GetOpenPositionsRequest struct {
+	Request string `json:"request"`
+	Nonce   string `json:"nonce"`
+}
+
+type AccountMargin struct {
+	MarginAssetsValue         string `json:"margin_assets_value"`
+	InitialMargin             string `json:"initial_margin"`
+	AvailableMargin           string `json:"available_margin"`
+	MarginMaintenanceLimit    string `json:"margin_maintenance_limit"`
+	Leverage                  string `json:"leverage"`
+	NotionalValue             string `json:"notional_value"`
+	EstimatedLiquidationPrice string `json:"estimated_liquidation_price"`
+	InitialMarginPositions    string `json:"initial_margin_positions"`
+	ReservedMargin            string `json:"reserved_margin"`
+	ReservedMarginBuys        string `json:"reserved_margin_buys"`
+	ReservedMarginSells       string `json:"reserved_margin_sells"`
+	BuyingPower               string `json:"buying_power"`
+	SellingPower              string `json:"selling_power"`
+}
+
+type GetAccountMarginRequest struct {
+	Symbol  string `json:"symbol"`
+	Request string `json:"request"`
+	Nonce   string `json:"nonce"`
+}
+
+type FundingPaymentQuantity struct {
+	Currency string `json:"currency"`
+	Value    string `json:"value"`
+}
+
+type FundingPayment struct {
+	EventType        string                 `json:"eventType"`
+	Timestamp        int64                  `json:"timestamp"`
+	AssetCode        string                 `json:"assetCode"`
+	Action           string                 `json:"action"`
+	Quantity         FundingPaymentQuantity `json:"quantity"`
+	InstrumentSymbol string                 `json:"instrumentSymbol"`
+}
+
+type GetFundingPaymentsRequest struct {
+	Since   int64  `json:"since,omitempty"`
+	To      int64  `json:"to,omitempty"`
+	Request string `json:"request"`
+	Nonce   string `json:"nonce"`
+}
+
+type StakingProviderBalance struct {
+	Balance float64 `json:"balance"`
+}
+
+type StakingBalance struct {
+	Type                   string                            `json:"type"`
+	Currency               string                            `json:"currency"`
+	Balance                float64                           `json:"balance"`
+	Available              float64                           `json:"available"`
+	AvailableForWithdrawal float64                           `json:"availableForWithdrawal"`
+	BalanceByProvider      map[string]StakingProviderBalance `json:"balanceByProvider"`
+}
+
+type GetStakingBalancesRequest struct {
+	Request string `json:"request"`
+	Nonce   string `json:"nonce"`
+}
+
+type StakingTransaction struct {
+	TransactionID string  `json:"transactionId"`
+	ProviderID    string  `json:"providerId"`
+	Currency      string  `json:"currency"`
+	Amount        float64 `json:"amount"`
+	AccrualTotal  float64 `json:"accrualTotal"`
+	Status        string  `json:"status"`
+}
+
+type StakingRequest struct {
+	ProviderID string `json:"providerId"`
+	Currency   string `json:"currency"`
+	Amount     string `json:"amount"`
+	Request    string `json:"request"`
+	Nonce      string `json:"nonce"`
+}
+
+type StakingReward struct {
+	Value          float64 `json:"value"`
+	AccrualTotal   float64 `json:"accrualTotal"`
+	RatesCount     int     `json:"ratesCount"`
+	FirstAccrualAt string  `json:"firstAccrualAt"`
+	LastAccrualAt  string  `json:"lastAccrualAt"`
+	Currency       string  `json:"currency"`
+}
+
+type GetStakingRewardsRequest struct {
+	Since      string `json:"since"`
+	Until      string `json:"until,omitempty"`
+	ProviderID string `json:"providerId,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	Request    string `json:"request"`
+	Nonce      string `json:"nonce"`
+}
+
+type StakingHistoryTransaction struct {
+	Timestamp            string  `json:"timestamp"`
+	TransactionID        string  `json:"transactionId"`
+	TransactionType      string  `json:"transactionType"`
+	Amount               float64 `json:"amount"`
+	AmountPaidSettlement float64 `json:"amountPaidSettlement"`
+	PriceAtRequest       float64 `json:"priceAtRequest"`
+	Currency             string  `json:"currency"`
+}
+
+type StakingHistory struct {
+	ProviderID   string                      `json:"providerId"`
+	Transactions []StakingHistoryTransaction `json:"transactions"`
+}
+
+type GetStakingHistoryRequest struct {
+	Since      string `json:"since,omitempty"`
+	Until      string `json:"until,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	ProviderID string `json:"providerId,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	Request    string `json:"request"`
+	Nonce      string `json:"nonce"`
+}
+
+type Holding struct {
+	Currency  string
+	Available float64
+	Exchange  float64
+	Staked    float64
+	Total     float64
+}
+
+type NewClearingOrderRequest struct {
+	CounterpartyID string `json:"counterparty_id,omitempty"`
+	ExpiresInHrs   int    `json:"expires_in_hrs"`
+	Symbol         string `json:"symbol"`
+	Amount         string `json:"amount"`
+	Price          string `json:"price"`
+	Side           string `json:"side"`
+	Request        string `json:"request"`
+	Nonce          string `json:"nonce"`
+}
+
+type ClearingOrderRequest struct {
+	ClearingID string `json:"clearing_id"`
+	Request    string `json:"request"`
+	Nonce      string `json:"nonce"`
+}
+
+type ConfirmClearingOrderRequest struct {
+	ClearingID string `json:"clearing_id"`
+	Symbol     string `json:"symbol"`
+	Amount     string `json:"amount"`
+	Price      string `json:"price"`
+	Side       string `json:"side"`
+	Request    string `json:"request"`
+	Nonce      string `json:"nonce"`
+}
+
+type ClearingOrder struct {
+	Result     string `json:"result"`
+	ClearingID string `json:"clearing_id"`
+	Status     string `json:"status"`
+	Details    string `json:"details"`
+}
+
+type ClearingTrade struct {
+	SourceCounterparty string `json:"sourceCounterparty"`
+	TargetCounterparty string `json:"targetCounterparty"`
+	Symbol             string `json:"symbol"`
+	Side               string `json:"side"`
+	Price              string `json:"price"`
+	Quantity           string `json:"quantity"`
+	Created            int64  `json:"created"`
+	Expires            int64  `json:"expires"`
+	Status             string `json:"status"`
+	ClearingID         string `json:"clearingId"`
+	ClearingType       string `json:"clearingType"`
+}
+
+type ClearingTrades struct {
+	Results []ClearingTrade `json:"results"`
+}
+
+type GetClearingTradesRequest struct {
+	Request string `json:"request"`
+	Nonce   string `json:"nonce"`
+}
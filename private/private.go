@@ -1,128 +1,298 @@
 package private
 
 import (
-	"bytes"
-	"crypto/hmac"
-	"crypto/sha512"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
-	"os"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/austinjhunt/go-gemini/util"
-	"github.com/joho/godotenv"
 )
 
-func init() {
-	// Load environment variables
-	util.Info("Initializing private.go")
-	err := godotenv.Load("../.env")
+func PostPrivateEndpoint(payload []byte, target interface{}) error {
+	/*
+		Perform an HTTP POST request on a private Gemini API endpoint using the default client. See Client.PostPrivateEndpoint
+	*/
+	return DefaultClient().PostPrivateEndpoint(payload, target)
+}
+
+func GetClosedOrdersHistory() []Order {
+	/*
+		This API retrieves (closed) orders history for an account.
+
+		The API key you use to access this endpoint must have the Trader or Auditor role assigned. See Roles for more information.
+	*/
+	ordersHistory, err := DefaultClient().GetClosedOrdersHistory()
 	if err != nil {
-		log.Println("Unable to load .env file:", err)
+		log.Fatalf("Error fetching orders history: %v", err)
+		return nil
+	}
+	return ordersHistory
+}
+
+func (c *Client) GetClosedOrdersHistory() ([]Order, error) {
+	/*
+		Get the (closed) orders history of the account. See GetClosedOrdersHistory
+	*/
+	c.Logger().Info("GetClosedOrdersHistory")
+	var ordersHistory []Order
+	payload, _ := json.Marshal(GetClosedOrdersHistoryRequest{
+		Request: "/v1/orders/history",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &ordersHistory); err != nil {
+		return nil, err
 	}
+	return ordersHistory, nil
 }
 
-func PostPrivateEndpoint(payload []byte, target interface{}) error {
+func GetActiveOrders() []Order {
 	/*
-				Perform an HTTP POST request on a private Gemini API endpoint and unmarshal the JSON response into the provided target interface.
+		Get the live (open) orders of the account.
 
-				Args:
-		        payload - post payload
-		        target - any type of JSON object in which the JSON response gets stored, passed as &target (pointer to a variable in which response is to be stored) when method is invoked
+		The API key you use to access this endpoint must have the Trader or Auditor role assigned. See Roles for more information.
+	*/
+	activeOrders, err := DefaultClient().GetActiveOrders()
+	if err != nil {
+		log.Fatalf("Error fetching active orders: %v", err)
+		return nil
+	}
+	return activeOrders
+}
 
-				Returns nothing if successful, returns error if it fails
+func (c *Client) GetActiveOrders() ([]Order, error) {
+	/*
+		Get the live (open) orders of the account. See GetActiveOrders
 	*/
-	util.Info(fmt.Sprintf("Posting payload to private endpoint: %s", string(payload)))
-	// Ensure required API keys are set
-	if os.Getenv("GEMINI_EXCHANGE_API_KEY") == "" || len([]byte(os.Getenv("GEMINI_EXCHANGE_API_SECRET"))) == 0 {
-		log.Println("GEMINI_EXCHANGE_API_KEY and GEMINI_EXCHANGE_API_SECRET are not both present in environment")
-	} else {
-		log.Println("Private credentials from .env successfully loaded for private API functions!")
+	c.Logger().Info("GetActiveOrders")
+	var activeOrders []Order
+	payload, _ := json.Marshal(GetActiveOrdersRequest{
+		Request: "/v1/orders",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &activeOrders); err != nil {
+		return nil, err
 	}
+	return activeOrders, nil
+}
 
-	// Retrieve API key and secret from environment
-	apiKey := util.GetEnvOrDefault("GEMINI_EXCHANGE_API_KEY", "")
-	apiSecret := []byte(util.GetEnvOrDefault("GEMINI_EXCHANGE_API_SECRET", ""))
+func CancelAllOrders() *CancelAllOrdersResponse {
+	/*
+		Cancel all outstanding orders of the account, including those placed through the UI and other API sessions.
+
+		The API key you use to access this endpoint must have the Trader role assigned. See Roles for more information.
+	*/
+	response, err := DefaultClient().CancelAllOrders()
+	if err != nil {
+		log.Fatalf("Error canceling all orders: %v", err)
+		return nil
+	}
+	return response
+}
 
-	var payloadJSON map[string]interface{}
-	json.Unmarshal(payload, &payloadJSON)
-	url := util.GetBaseAPIUrl() + payloadJSON["request"].(string)
+func (c *Client) CancelAllOrders() (*CancelAllOrdersResponse, error) {
+	/*
+		Cancel all outstanding orders of the account. See CancelAllOrders
+	*/
+	c.Logger().Info("CancelAllOrders")
+	var response CancelAllOrdersResponse
+	payload, _ := json.Marshal(CancelAllOrdersRequest{
+		Request: "/v1/order/cancel/all",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
 
-	// Base64 encode the JSON payload
-	b64Payload := base64.StdEncoding.EncodeToString(payload)
+func GetPastTrades(symbol string, timestamp int64) []PastTrade {
+	/*
+		Get the account's past trades (fills), newest first, at most 500 per request. See GetAllPastTrades for the whole history.
 
-	// Create the HMAC signature using SHA384
-	h := hmac.New(sha512.New384, apiSecret)
-	h.Write([]byte(b64Payload))
-	signature := fmt.Sprintf("%x", h.Sum(nil))
+		The API key you use to access this endpoint must have the Trader or Auditor role assigned. See Roles for more information.
 
-	// Prepare the HTTP request headers
-	req, err := http.NewRequest("POST", url, nil)
+		Args:
+		symbol (string): trading pair symbol, e.g. "btcusd"; empty for all symbols
+		timestamp (int64): only return trades on or after this Unix time, in seconds or milliseconds; 0 for the most recent trades
+	*/
+	trades, err := DefaultClient().GetPastTrades(symbol, timestamp)
 	if err != nil {
-		return errors.New("Error creating request: " + err.Error())
+		log.Fatalf("Error fetching past trades: %v", err)
+		return nil
 	}
+	return trades
+}
 
-	req.Header.Set("Content-Type", "text/plain")
-	req.Header.Set("Content-Length", "0")
-	req.Header.Set("X-GEMINI-APIKEY", apiKey)
-	req.Header.Set("X-GEMINI-PAYLOAD", b64Payload)
-	req.Header.Set("X-GEMINI-SIGNATURE", signature)
-	req.Header.Set("Cache-Control", "no-cache")
+func (c *Client) GetPastTrades(symbol string, timestamp int64) ([]PastTrade, error) {
+	/*
+		Get the account's past trades, newest first. See GetPastTrades
+	*/
+	c.Logger().Info("GetPastTrades", "symbol", symbol, "timestamp", timestamp)
+	var trades []PastTrade
+	payload, _ := json.Marshal(GetPastTradesRequest{
+		Symbol:      symbol,
+		Timestamp:   timestamp,
+		LimitTrades: pastTradesLimit,
+		Request:     "/v1/mytrades",
+		Nonce:       util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// pastTradesLimit is the most trades /v1/mytrades returns per request
+const pastTradesLimit = 500
+
+func GetAllPastTrades(symbol string, since int64) []PastTrade {
+	/*
+		Get every trade of the account on or after since, newest first, requesting as many pages as the history needs.
+
+		The API key you use to access this endpoint must have the Trader or Auditor role assigned. See Roles for more information.
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+		Args:
+		symbol (string): trading pair symbol, e.g. "btcusd"; empty for all symbols
+		since (int64): Unix time in seconds of the oldest trade to return; 0 for the whole history
+	*/
+	trades, err := DefaultClient().GetAllPastTrades(symbol, since)
 	if err != nil {
-		return errors.New("Error sending request: " + err.Error())
+		log.Fatalf("Error fetching past trades: %v", err)
+		return nil
 	}
-	defer resp.Body.Close()
+	return trades
+}
 
-	// Read the response
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(resp.Body)
+func (c *Client) GetAllPastTrades(symbol string, since int64) ([]PastTrade, error) {
+	/*
+		Get every trade on or after since, newest first, paging forward from since. See GetAllPastTrades
+	*/
+	return pageForward(since, pastTradesLimit, func(timestamp int64) ([]PastTrade, error) {
+		return c.GetPastTrades(symbol, timestamp)
+	}, func(trade PastTrade) (int64, int64) {
+		return trade.TID, tradeMillis(trade)
+	})
+}
 
-	// Handle non-OK HTTP status codes
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("Error: status " + resp.Status + ", response: " + buf.String())
+// pageForward requests every record of a history endpoint on or after since (Unix seconds; 0 for all), newest first.
+// The endpoints only take a lower bound, so pages are requested forward in time: each page starts at the newest record
+// of the page before, and records seen twice are dropped, until a page comes back shorter than limit. key gives the
+// id and Unix time in milliseconds of a record
+func pageForward[T any](since int64, limit int, fetch func(timestamp int64) ([]T, error), key func(record T) (int64, int64)) ([]T, error) {
+	timestamp := since * 1000
+	if timestamp == 0 {
+		// without a timestamp the endpoints return the most recent records rather than the oldest
+		timestamp = 1
 	}
+	seen := map[int64]bool{}
+	var all []T
+	for {
+		page, err := fetch(timestamp)
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, record := range page {
+			id, millis := key(record)
+			timestamp = max(timestamp, millis)
+			if !seen[id] {
+				seen[id] = true
+				all = append(all, record)
+				added++
+			}
+		}
+		if len(page) < limit {
+			break
+		}
+		if added == 0 {
+			return nil, fmt.Errorf("more than %d records at %d, cannot page past them", limit, timestamp)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		idI, millisI := key(all[i])
+		idJ, millisJ := key(all[j])
+		if millisI != millisJ {
+			return millisI > millisJ
+		}
+		return idI > idJ
+	})
+	return all, nil
+}
 
-	// Parse the response JSON into the target interface
-	if err := json.Unmarshal(buf.Bytes(), target); err != nil {
-		return errors.New("Error parsing response JSON: " + err.Error())
+// tradeMillis is the Unix time of a trade in milliseconds
+func tradeMillis(trade PastTrade) int64 {
+	if trade.TimestampMs != 0 {
+		return trade.TimestampMs
 	}
+	return trade.Timestamp * 1000
+}
+
+func GetTransfers(timestamp int64) []Transfer {
+	/*
+		Get the account's fiat and crypto deposits and withdrawals, newest first, at most 50 per request. See GetAllTransfers for the whole history.
 
-	responseStr, err := json.Marshal(target)
+		The API key you use to access this endpoint must have the Fund Manager or Auditor role assigned. See Roles for more information.
+
+		Args:
+		timestamp (int64): only return transfers on or after this Unix time, in seconds or milliseconds; 0 for the most recent transfers
+	*/
+	transfers, err := DefaultClient().GetTransfers(timestamp)
 	if err != nil {
-		return errors.New("Error converting response to string: " + err.Error())
+		log.Fatalf("Error fetching transfers: %v", err)
+		return nil
 	}
-	util.Info("Response from POST: \n\t" + string(responseStr))
+	return transfers
+}
 
-	return nil
+func (c *Client) GetTransfers(timestamp int64) ([]Transfer, error) {
+	/*
+		Get the account's deposits and withdrawals, newest first. See GetTransfers
+	*/
+	c.Logger().Info("GetTransfers", "timestamp", timestamp)
+	var transfers []Transfer
+	payload, _ := json.Marshal(GetTransfersRequest{
+		Timestamp:      timestamp,
+		LimitTransfers: transfersLimit,
+		Request:        "/v1/transfers",
+		Nonce:          util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
 }
 
-func GetClosedOrdersHistory() []Order {
+// transfersLimit is the most transfers /v1/transfers returns per request
+const transfersLimit = 50
+
+func GetAllTransfers(since int64) []Transfer {
 	/*
-		This API retrieves (closed) orders history for an account.
+		Get every deposit and withdrawal of the account on or after since, newest first, requesting as many pages as the history needs.
 
-		The API key you use to access this endpoint must have the Trader or Auditor role assigned. See Roles for more information.
-	*/
+		The API key you use to access this endpoint must have the Fund Manager or Auditor role assigned. See Roles for more information.
 
-	util.Info("GetClosedOrdersHistory")
-	var ordersHistory []Order
-	payload, _ := json.Marshal(GetClosedOrdersHistoryRequest{
-		Request: "/v1/orders/history",
-		Nonce:   util.GenerateNonceString(),
-	})
-	err := PostPrivateEndpoint(payload, &ordersHistory)
+		Args:
+		since (int64): Unix time in seconds of the oldest transfer to return; 0 for the whole history
+	*/
+	transfers, err := DefaultClient().GetAllTransfers(since)
 	if err != nil {
-		log.Fatalf("Error fetching orders history: %v", err)
+		log.Fatalf("Error fetching transfers: %v", err)
 		return nil
 	}
-	return ordersHistory
+	return transfers
+}
+
+func (c *Client) GetAllTransfers(since int64) ([]Transfer, error) {
+	/*
+		Get every transfer on or after since, newest first, paging forward from since. See GetAllTransfers
+	*/
+	return pageForward(since, transfersLimit, c.GetTransfers, func(transfer Transfer) (int64, int64) {
+		return transfer.EID, transfer.TimestampMs
+	})
 }
 
 func GetOrderStatus(order_id int) *Order {
@@ -140,20 +310,29 @@ func GetOrderStatus(order_id int) *Order {
 
 		Response: pointer to an Order object
 	*/
-	util.Info("GetOrderStatus")
+	orderStatus, err := DefaultClient().GetOrderStatus(order_id)
+	if err != nil {
+		log.Fatalf("Error fetching order status: %v", err)
+		return nil
+	}
+	return orderStatus
+}
+
+func (c *Client) GetOrderStatus(order_id int) (*Order, error) {
+	/*
+		Get the status of an order. See GetOrderStatus
+	*/
+	c.Logger().Info("GetOrderStatus", "order_id", order_id)
 	var orderStatus Order
 	payload, _ := json.Marshal(GetOrderStatusRequest{
 		OrderID: order_id,
 		Request: "/v1/order/status",
 		Nonce:   util.GenerateNonceString(),
 	})
-	err := PostPrivateEndpoint(payload, &orderStatus)
-
-	if err != nil {
-		log.Fatalf("Error fetching order status: %v", err)
-		return nil
+	if err := c.PostPrivateEndpoint(payload, &orderStatus); err != nil {
+		return nil, err
 	}
-	return &orderStatus
+	return &orderStatus, nil
 }
 
 func StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) *Order {
@@ -177,37 +356,25 @@ func StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice
 		  - The stopPrice must be greater than the limitPrice for sell orders.
 		  - Logs an error and exits if any validation fails or if fetching the current price fails.
 	*/
-
-	util.Info(fmt.Sprintf("StopLimitSell called with symbol: %s, amount: %f, stopPrice: %f, limitPrice: %f", symbol, amount, stopPrice, limitPrice))
-
-	// Validate the stop price and limit price
-	if stopPrice <= limitPrice {
-		log.Fatalf("Invalid stop and limit prices: stopPrice (%f) must be greater than limitPrice (%f)", stopPrice, limitPrice)
-		return nil
-	}
-
-	var newOrder Order
-
-	payload, _ := json.Marshal(StopLimitOrderRequest{
-		Amount:    strconv.FormatFloat(amount, 'f', 8, 64),
-		Price:     strconv.FormatFloat(limitPrice, 'f', 2, 64),
-		Side:      "sell",
-		StopPrice: strconv.FormatFloat(stopPrice, 'f', 2, 64),
-		Symbol:    symbol,
-		Type:      "exchange stop limit",
-		Request:   "/v1/order/new",
-		Nonce:     util.GenerateNonceString(),
-	})
-
-	// Pass the payload to the function
-	err := PostPrivateEndpoint(payload, &newOrder)
-
+	newOrder, err := DefaultClient().StopLimitSell(symbol, amount, stopPrice, limitPrice)
 	if err != nil {
 		log.Fatalf("Error creating new order: %v", err)
 		return nil
 	}
-	return &newOrder
+	return newOrder
+}
 
+func (c *Client) StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) (*Order, error) {
+	/*
+		Place a stop-limit sell order. See StopLimitSell
+	*/
+	c.Logger().Info("StopLimitSell", "symbol", symbol, "amount", amount, "stop_price", stopPrice, "limit_price", limitPrice)
+
+	// Validate the stop price and limit price
+	if stopPrice <= limitPrice {
+		return nil, fmt.Errorf("Invalid stop and limit prices: stopPrice (%f) must be greater than limitPrice (%f)", stopPrice, limitPrice)
+	}
+	return c.newStopLimitOrder("sell", symbol, amount, stopPrice, limitPrice)
 }
 
 func StopLimitBuy(symbol string, amount float64, stopPrice float64, limitPrice float64) *Order {
@@ -231,52 +398,75 @@ func StopLimitBuy(symbol string, amount float64, stopPrice float64, limitPrice f
 	  - The stopPrice must be lower than the limitPrice for buy orders.
 	  - Logs an error and exits if any validation fails or if fetching the current price fails.
 	*/
+	newOrder, err := DefaultClient().StopLimitBuy(symbol, amount, stopPrice, limitPrice)
+	if err != nil {
+		log.Fatalf("Error creating new order: %v", err)
+		return nil
+	}
+	return newOrder
+}
 
-	util.Info(fmt.Sprintf("StopLimitSell called with symbol: %s, amount: %f, stopPrice: %f, limitPrice: %f", symbol, amount, stopPrice, limitPrice))
+func (c *Client) StopLimitBuy(symbol string, amount float64, stopPrice float64, limitPrice float64) (*Order, error) {
+	/*
+		Place a stop-limit buy order. See StopLimitBuy
+	*/
+	c.Logger().Info("StopLimitBuy", "symbol", symbol, "amount", amount, "stop_price", stopPrice, "limit_price", limitPrice)
 
 	// Validate the stop price and limit price
 	if stopPrice >= limitPrice {
-		log.Fatalf("Invalid stop and limit prices: stopPrice (%f) must be less than limitPrice (%f)", stopPrice, limitPrice)
-		return nil
+		return nil, fmt.Errorf("Invalid stop and limit prices: stopPrice (%f) must be less than limitPrice (%f)", stopPrice, limitPrice)
 	}
+	return c.newStopLimitOrder("buy", symbol, amount, stopPrice, limitPrice)
+}
 
+func (c *Client) newStopLimitOrder(side string, symbol string, amount float64, stopPrice float64, limitPrice float64) (*Order, error) {
 	var newOrder Order
-
 	payload, _ := json.Marshal(StopLimitOrderRequest{
 		Amount:    strconv.FormatFloat(amount, 'f', 8, 64),
-		Price:     strconv.FormatFloat(limitPrice, 'f', 2, 64),
-		Side:      "buy",
-		StopPrice: strconv.FormatFloat(stopPrice, 'f', 2, 64),
+		Price:     formatPrice(limitPrice),
+		Side:      side,
+		StopPrice: formatPrice(stopPrice),
 		Symbol:    symbol,
 		Type:      "exchange stop limit",
 		Request:   "/v1/order/new",
 		Nonce:     util.GenerateNonceString(),
 	})
+	if err := c.PostPrivateEndpoint(payload, &newOrder); err != nil {
+		return nil, err
+	}
+	return &newOrder, nil
+}
 
-	// Pass the payload to the function
-	err := PostPrivateEndpoint(payload, &newOrder)
+// formatPrice writes an order price without rounding it to cents, so symbols quoted in fractions of a cent keep their
+// precision; float noise beyond 10 decimals is dropped. Prices must be multiples of the symbol's quote_increment.
+func formatPrice(price float64) string {
+	formatted := strings.TrimRight(strconv.FormatFloat(price, 'f', 10, 64), "0")
+	return strings.TrimSuffix(formatted, ".")
+}
 
+func GetAvailableBalances() []AvailableBalance {
+	availableBalances, err := DefaultClient().GetAvailableBalances()
 	if err != nil {
-		log.Fatalf("Error creating new order: %v", err)
+		log.Fatalf("Error getting open positions: %v", err)
 		return nil
 	}
-	return &newOrder
-
+	return availableBalances
 }
 
-func GetAvailableBalances() []AvailableBalance {
-	util.Info("GetAvailableBalances called")
+func (c *Client) GetAvailableBalances() ([]AvailableBalance, error) {
+	/*
+		Get the available balances of the account, one per currency
+	*/
+	c.Logger().Info("GetAvailableBalances")
 	var availableBalances []AvailableBalance
 	payload, _ := json.Marshal(GetAvailableBalancesRequest{
 		Request: "/v1/balances",
 		Nonce:   util.GenerateNonceString(),
 	})
-	err := PostPrivateEndpoint(payload, &availableBalances)
-	if err != nil {
-		log.Fatalf("Error getting open positions: %v", err)
-		return nil
+	if err := c.PostPrivateEndpoint(payload, &availableBalances); err != nil {
+		return nil, err
 	}
-	return availableBalances
+	return availableBalances, nil
 }
 
 func filterBalances(balances []AvailableBalance, predicate func(AvailableBalance) bool) []AvailableBalance {
@@ -290,9 +480,8 @@ func filterBalances(balances []AvailableBalance, predicate func(AvailableBalance
 }
 
 func GetAvailableCurrencyBalance(currency string) *AvailableBalance {
-	util.Info(fmt.Sprintf("GetAvailableBalances, currency: %s", currency))
+	logger().Info("GetAvailableCurrencyBalance", "currency", currency)
 	availableBalances := GetAvailableBalances()
-	util.Info(fmt.Sprintf("My available balances: %v", availableBalances))
 	predicate := func(balance AvailableBalance) bool {
 		return balance.Currency == currency
 	}
@@ -302,25 +491,33 @@ func GetAvailableCurrencyBalance(currency string) *AvailableBalance {
 		return nil
 	}
 	position := filteredBalances[0]
-	util.Info(fmt.Sprintf("%s balance: %v", currency, position))
 	return &position
 }
 
 func CancelOrder(order_id int) *Order {
-	util.Info(fmt.Sprintf("CancelOrder called with order_id: %v", order_id))
+	canceledOrder, err := DefaultClient().CancelOrder(order_id)
+	if err != nil {
+		log.Fatalf("Error canceling order: %v", err)
+		return nil
+	}
+	return canceledOrder
+}
+
+func (c *Client) CancelOrder(order_id int) (*Order, error) {
+	/*
+		Cancel an order, returning its final state
+	*/
+	c.Logger().Info("CancelOrder", "order_id", order_id)
 	var canceledOrder Order
 	payload, _ := json.Marshal(CancelOrderRequest{
 		Request: "/v1/order/cancel",
 		Nonce:   util.GenerateNonceString(),
 		OrderID: order_id,
 	})
-	// Pass the payload to the function
-	err := PostPrivateEndpoint(payload, &canceledOrder)
-	if err != nil {
-		log.Fatalf("Error canceling order: %v", err)
-		return nil
+	if err := c.PostPrivateEndpoint(payload, &canceledOrder); err != nil {
+		return nil, err
 	}
-	return &canceledOrder
+	return &canceledOrder, nil
 }
 
 func LimitBuy(symbol string, amount float64, limitPrice float64) *Order {
@@ -338,30 +535,22 @@ func LimitBuy(symbol string, amount float64, limitPrice float64) *Order {
 	  Notes:
 	  - Logs an error and exits if any validation fails or if fetching the current price fails.
 	*/
-
-	util.Info(fmt.Sprintf("StopLimitSell called with symbol: %s, amount: %f, limitPrice: %f", symbol, amount, limitPrice))
-
-	var newOrder Order
-
-	payload, _ := json.Marshal(LimitOrderRequest{
-		ClientOrderID: util.GenerateUUID(),
-		Symbol:        symbol,
-		Amount:        strconv.FormatFloat(amount, 'f', 8, 64),
-		Price:         strconv.FormatFloat(limitPrice, 'f', 2, 64),
-		Side:          "buy",
-		Type:          "exchange limit",
-		Request:       "/v1/order/new",
-		Nonce:         util.GenerateNonceString(),
-	})
-
-	// Pass the payload to the function
-	err := PostPrivateEndpoint(payload, &newOrder)
-
+	newOrder, err := DefaultClient().LimitBuy(symbol, amount, limitPrice)
 	if err != nil {
 		log.Fatalf("Error creating new order: %v", err)
 		return nil
 	}
-	return &newOrder
+	return newOrder
+}
+
+func (c *Client) LimitBuy(symbol string, amount float64, limitPrice float64, options ...string) (*Order, error) {
+	/*
+		Place an exchange limit buy order. See LimitBuy
+
+		options are Gemini order execution options: "maker-or-cancel", "immediate-or-cancel" or "fill-or-kill"
+	*/
+	c.Logger().Info("LimitBuy", "symbol", symbol, "amount", amount, "limit_price", limitPrice, "options", options)
+	return c.newLimitOrder("buy", symbol, amount, limitPrice, "", options)
 }
 
 func LimitSell(symbol string, amount float64, limitPrice float64) *Order {
@@ -379,28 +568,60 @@ func LimitSell(symbol string, amount float64, limitPrice float64) *Order {
 	  Notes:
 	  - Logs an error and exits if any validation fails or if fetching the current price fails.
 	*/
+	newOrder, err := DefaultClient().LimitSell(symbol, amount, limitPrice)
+	if err != nil {
+		log.Fatalf("Error creating new order: %v", err)
+		return nil
+	}
+	return newOrder
+}
 
-	util.Info(fmt.Sprintf("StopLimitSell called with symbol: %s, amount: %f, limitPrice: %f", symbol, amount, limitPrice))
+func (c *Client) LimitSell(symbol string, amount float64, limitPrice float64, options ...string) (*Order, error) {
+	/*
+		Place an exchange limit sell order. See LimitSell
 
-	var newOrder Order
+		options are Gemini order execution options: "maker-or-cancel", "immediate-or-cancel" or "fill-or-kill"
+	*/
+	c.Logger().Info("LimitSell", "symbol", symbol, "amount", amount, "limit_price", limitPrice, "options", options)
+	return c.newLimitOrder("sell", symbol, amount, limitPrice, "", options)
+}
 
+func (c *Client) LimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options ...string) (*Order, error) {
+	/*
+		Place an exchange limit order under the given client order id instead of a random one, so it can be found again
+		with GetActiveOrders or GetClosedOrdersHistory if the response is lost, e.g.
+		client.LimitOrder("buy", "btcusd", 0.1, 30000, "dca-btcusd-1", "maker-or-cancel")
+
+		side is "buy" or "sell"; an empty clientOrderID gets a random one. See LimitBuy for the options
+	*/
+	c.Logger().Info("LimitOrder", "side", side, "symbol", symbol, "amount", amount, "limit_price", limitPrice, "client_order_id", clientOrderID, "options", options)
+	return c.newLimitOrder(side, symbol, amount, limitPrice, clientOrderID, options)
+}
+
+func (c *Client) newLimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options []string) (*Order, error) {
+	if side != "buy" && side != "sell" {
+		return nil, errors.New("Error: order side must be buy or sell, got " + side)
+	}
+	if len(options) > 1 {
+		return nil, errors.New("Error: at most one order execution option may be given")
+	}
+	if clientOrderID == "" {
+		clientOrderID = util.GenerateUUID()
+	}
+	var newOrder Order
 	payload, _ := json.Marshal(LimitOrderRequest{
-		ClientOrderID: util.GenerateUUID(),
+		ClientOrderID: clientOrderID,
 		Symbol:        symbol,
 		Amount:        strconv.FormatFloat(amount, 'f', 8, 64),
-		Price:         strconv.FormatFloat(limitPrice, 'f', 2, 64),
-		Side:          "sell",
+		Price:         formatPrice(limitPrice),
+		Side:          side,
 		Type:          "exchange limit",
+		Options:       options,
 		Request:       "/v1/order/new",
 		Nonce:         util.GenerateNonceString(),
 	})
-
-	// Pass the payload to the function
-	err := PostPrivateEndpoint(payload, &newOrder)
-
-	if err != nil {
-		log.Fatalf("Error creating new order: %v", err)
-		return nil
+	if err := c.PostPrivateEndpoint(payload, &newOrder); err != nil {
+		return nil, err
 	}
-	return &newOrder
+	return &newOrder, nil
 }
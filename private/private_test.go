@@ -1,14 +1,37 @@
 package private
 
 import (
-	"fmt"
+	"os"
 	"strconv"
 	"testing"
 
+	"github.com/austinjhunt/go-gemini/geminitest"
 	"github.com/austinjhunt/go-gemini/public"
-	"github.com/austinjhunt/go-gemini/util"
 )
 
+var liveTests = os.Getenv("GEMINI_EXCHANGE_LIVE_TESTS") == "true"
+
+// TestMain runs the tests against a geminitest mock exchange unless GEMINI_EXCHANGE_LIVE_TESTS is "true"
+func TestMain(m *testing.M) {
+	if liveTests {
+		os.Exit(m.Run())
+	}
+	server := geminitest.NewServer()
+	for key, value := range server.Env() {
+		os.Setenv(key, value)
+	}
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
+// skipIfLive skips tests that place orders or move funds when running against the live API
+func skipIfLive(t *testing.T, reason string) {
+	if liveTests {
+		t.Skip(reason)
+	}
+}
+
 func TestGetClosedOrdersHistory(t *testing.T) {
 	t.Log("Getting closed orders history")
 
@@ -34,7 +57,7 @@ func TestGetOrderStatus(t *testing.T) {
 }
 
 func TestStopLimitSell(t *testing.T) {
-	t.Skip("Skipping to avoid fee accrual")
+	skipIfLive(t, "Skipping to avoid fee accrual")
 
 	// Do not run these to avoid flooding account with trade activity and fees.
 
@@ -67,15 +90,15 @@ func TestStopLimitSell(t *testing.T) {
 
 	// cancel that order
 	sellOrderId, _ := strconv.Atoi(order.OrderID)
-	util.Info("Canceling sell order by ID " + order.OrderID)
+	t.Log("Canceling sell order by ID " + order.OrderID)
 	canceledOrder := CancelOrder(sellOrderId)
-	util.Info(fmt.Sprintf("Canceled order: %v", canceledOrder))
+	t.Logf("Canceled order: %v", canceledOrder)
 }
 
 func TestStopLimitBuy(t *testing.T) {
 
 	// Do not run these to avoid flooding account with trade activity and fees.
-	t.Skip("Skipping to avoid fee accrual")
+	skipIfLive(t, "Skipping to avoid fee accrual")
 	// point of a stop limit buy order is to trigger a buy when you see a spike but to limit your expense with a limit price, i.e. buy if it reaches $100K but if it's already $103K (limit) then do not buy
 	tradingPair := "btcusd"
 
@@ -97,13 +120,13 @@ func TestStopLimitBuy(t *testing.T) {
 
 	// cancel that order
 	buyOrderId, _ := strconv.Atoi(order.OrderID)
-	util.Info("Canceling buy order by ID " + order.OrderID)
+	t.Log("Canceling buy order by ID " + order.OrderID)
 	canceledOrder := CancelOrder(buyOrderId)
-	util.Info(fmt.Sprintf("Canceled order: %v", canceledOrder))
+	t.Logf("Canceled order: %v", canceledOrder)
 }
 
 func TestLimitSell(t *testing.T) {
-	t.Skip("Skipping to avoid fee accrual")
+	skipIfLive(t, "Skipping to avoid fee accrual")
 	// goal: sell N% of available coin balance when coin price increases X%
 	coin := "BTC"
 	tradingPair := "btcusd"
@@ -129,13 +152,13 @@ func TestLimitSell(t *testing.T) {
 
 	// cancel that order
 	sellOrderId, _ := strconv.Atoi(order.OrderID)
-	util.Info("Canceling sell order by ID " + order.OrderID)
+	t.Log("Canceling sell order by ID " + order.OrderID)
 	canceledOrder := CancelOrder(sellOrderId)
-	util.Info(fmt.Sprintf("Canceled order: %v", canceledOrder))
+	t.Logf("Canceled order: %v", canceledOrder)
 }
 
 func TestLimitBuy(t *testing.T) {
-	t.Skip("Skipping to avoid fee accrual")
+	skipIfLive(t, "Skipping to avoid fee accrual")
 	// goal: buy N USD worth of coin when coin price decreases X% (you must have that USD balance in account for this to succeed without InsufficientFunds error)
 	coin := "BTC"
 	tradingPair := "btcusd"
@@ -160,7 +183,253 @@ func TestLimitBuy(t *testing.T) {
 
 	// cancel that order
 	sellOrderId, _ := strconv.Atoi(order.OrderID)
-	util.Info("Canceling buy order by ID " + order.OrderID)
+	t.Log("Canceling buy order by ID " + order.OrderID)
 	canceledOrder := CancelOrder(sellOrderId)
-	util.Info(fmt.Sprintf("Canceled order: %v", canceledOrder))
+	t.Logf("Canceled order: %v", canceledOrder)
+}
+
+func TestSubCentOrderPrices(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.SetBalance("AMP", 10000)
+	client, err := NewClient(StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	// batusd and ampusd are quoted in 0.00001 USD
+	order, err := client.LimitOrder("buy", "batusd", 100, 0.24567, "sub-cent-1")
+	if err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	if order.Price != "0.24567" || order.ClientOrderID != "sub-cent-1" {
+		t.Errorf("limit order %+v", order)
+	}
+	order, err = client.StopLimitSell("ampusd", 1000, 0.00412, 0.00411)
+	if err != nil {
+		t.Fatalf("StopLimitSell failed: %v", err)
+	}
+	if order.Price != "0.00411" || order.StopPrice != "0.00412" {
+		t.Errorf("stop-limit order %+v", order)
+	}
+	if got := formatPrice(0.1 + 0.2); got != "0.3" {
+		t.Errorf("formatPrice(0.1 + 0.2) = %s", got)
+	}
+}
+
+func TestCancelAllOrders(t *testing.T) {
+	skipIfLive(t, "Skipping to avoid cancelling live orders")
+	limitPrice, _ := strconv.ParseFloat(public.GetTickerV2("btcusd").Bid, 64)
+	order := LimitBuy("btcusd", 0.001, limitPrice*0.7)
+
+	active := GetActiveOrders()
+	found := false
+	for _, o := range active {
+		found = found || o.OrderID == order.OrderID
+	}
+	if !found {
+		t.Errorf("GetActiveOrders did not return order %s: %+v", order.OrderID, active)
+	}
+
+	response := CancelAllOrders()
+	t.Logf("Cancelled: %+v", response)
+	if response == nil || response.Result != "ok" || len(response.Details.CancelledOrders) == 0 {
+		t.Errorf("CancelAllOrders failed: %+v", response)
+	}
+	if active := GetActiveOrders(); len(active) != 0 {
+		t.Errorf("orders still active after CancelAllOrders: %+v", active)
+	}
+}
+
+func TestGetPastTrades(t *testing.T) {
+	response := GetPastTrades("btcusd", 0)
+	t.Log(response)
+	if len(response) == 0 || response[0].Price == "" || response[0].TID == 0 {
+		t.Errorf("GetPastTrades failed: %+v", response)
+	}
+}
+
+func TestGetTransfers(t *testing.T) {
+	response := GetTransfers(0)
+	t.Log(response)
+	if response == nil {
+		t.Errorf("GetTransfers failed")
+	}
+}
+
+func TestGetOpenPositions(t *testing.T) {
+	t.Log("Getting open positions")
+	response := GetOpenPositions()
+	t.Log(response)
+}
+
+func TestGetAccountMargin(t *testing.T) {
+	t.Log("Getting account margin")
+	response := GetAccountMargin("BTCGUSDPERP")
+	t.Log(response)
+	if response == nil {
+		t.Errorf("GetAccountMargin failed")
+	}
+}
+
+func TestGetFundingPayments(t *testing.T) {
+	t.Log("Getting funding payments")
+	response := GetFundingPayments(0, 0)
+	t.Log(response)
+}
+
+func TestClientGetPositionsWithFunding(t *testing.T) {
+	positions, err := DefaultClient().GetPositionsWithFunding()
+	if err != nil {
+		t.Fatalf("GetPositionsWithFunding failed: %v", err)
+	}
+	t.Log(positions)
+}
+
+func TestEstimatedNextPayment(t *testing.T) {
+	cases := []struct {
+		quantity string
+		funding  float64
+		expected float64
+	}{
+		{"2", 0.5, -1},   // long pays positive funding
+		{"-2", 0.5, 1},   // short receives positive funding
+		{"2", -0.25, .5}, // long receives negative funding
+		{"0", 0.5, 0},
+	}
+	for _, c := range cases {
+		pf := PositionFunding{
+			Position: Position{Symbol: "btcgusdperp", Quantity: c.quantity},
+			Funding:  public.FundingAmount{Symbol: "btcgusdperp", EstimatedFundingAmount: c.funding},
+		}
+		estimate, err := pf.EstimatedNextPayment()
+		if err != nil {
+			t.Fatalf("EstimatedNextPayment failed: %v", err)
+		}
+		if estimate != c.expected {
+			t.Errorf("EstimatedNextPayment(%s @ %f) = %f, expected %f", c.quantity, c.funding, estimate, c.expected)
+		}
+	}
+
+	invalid := PositionFunding{Position: Position{Quantity: "not-a-number"}}
+	if _, err := invalid.EstimatedNextPayment(); err == nil {
+		t.Errorf("EstimatedNextPayment should fail on an invalid quantity")
+	}
+}
+
+func TestGetStakingBalances(t *testing.T) {
+	t.Log("Getting staking balances")
+	response := GetStakingBalances()
+	t.Log(response)
+}
+
+func TestGetStakingHistory(t *testing.T) {
+	t.Log("Getting staking history")
+	response := GetStakingHistory("", "", 10)
+	t.Log(response)
+}
+
+func TestStake(t *testing.T) {
+	skipIfLive(t, "Skipping to avoid moving funds")
+	rates := public.GetStakingRates()
+	for providerID := range rates {
+		transaction := Stake(providerID, "MATIC", 1)
+		if transaction == nil {
+			t.Fatalf("Stake failed: transaction is nil")
+		}
+		t.Logf("Staked: %+v", transaction)
+		unstaked := Unstake(providerID, "MATIC", 1)
+		t.Logf("Unstaked: %+v", unstaked)
+		return
+	}
+}
+
+func TestClientStakeError(t *testing.T) {
+	skipIfLive(t, "Skipping to avoid moving funds")
+	if _, err := DefaultClient().Stake("", "MATIC", 1); err == nil {
+		t.Errorf("staking without a provider should return an error")
+	}
+}
+
+func TestCombineHoldings(t *testing.T) {
+	balances := []AvailableBalance{
+		{Type: "exchange", Currency: "USD", Amount: "100.5", Available: "50.5"},
+		{Type: "exchange", Currency: "MATIC", Amount: "5", Available: "5"},
+	}
+	staking := []StakingBalance{
+		{Type: "Staking", Currency: "MATIC", Balance: 10},
+		{Type: "Staking", Currency: "ETH", Balance: 0.25},
+	}
+	holdings, err := CombineHoldings(balances, staking)
+	if err != nil {
+		t.Fatalf("CombineHoldings failed: %v", err)
+	}
+	expected := []Holding{
+		{Currency: "ETH", Staked: 0.25, Total: 0.25},
+		{Currency: "MATIC", Available: 5, Exchange: 5, Staked: 10, Total: 15},
+		{Currency: "USD", Available: 50.5, Exchange: 100.5, Total: 100.5},
+	}
+	if len(holdings) != len(expected) {
+		t.Fatalf("CombineHoldings returned %d holdings, expected %d", len(holdings), len(expected))
+	}
+	for i := range expected {
+		if holdings[i] != expected[i] {
+			t.Errorf("holding %d = %+v, expected %+v", i, holdings[i], expected[i])
+		}
+	}
+
+	if _, err := CombineHoldings([]AvailableBalance{{Currency: "USD", Amount: "x"}}, nil); err == nil {
+		t.Errorf("CombineHoldings should fail on an invalid amount")
+	}
+}
+
+func TestGetClearingTrades(t *testing.T) {
+	t.Log("Getting clearing trades")
+	response := GetClearingTrades()
+	t.Log(response)
+}
+
+func TestNewClearingOrder(t *testing.T) {
+	skipIfLive(t, "Skipping to avoid settling a block trade")
+	tradingPair := "btcusd"
+	currentCoinAskPrice, _ := strconv.ParseFloat(public.GetTickerV2(tradingPair).Ask, 32)
+	// broker clearing order well above market that we cancel immediately
+	clearingOrder := NewClearingOrder("", tradingPair, "sell", 0.0001, currentCoinAskPrice*2, 1)
+	if clearingOrder == nil || clearingOrder.ClearingID == "" {
+		t.Fatalf("NewClearingOrder failed: %+v", clearingOrder)
+	}
+	status := GetClearingOrderStatus(clearingOrder.ClearingID)
+	t.Logf("Clearing order status: %+v", status)
+	canceled := CancelClearingOrder(clearingOrder.ClearingID)
+	t.Logf("Canceled clearing order: %+v", canceled)
+}
+
+func TestClientClearingOrders(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client, err := NewClient(StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	// clearing prices keep the symbol's sub-cent precision
+	clearingOrder, err := client.NewClearingOrder("", "batusd", "sell", 100, 0.24567, 1)
+	if err != nil {
+		t.Fatalf("NewClearingOrder failed: %v", err)
+	}
+	trades, err := client.GetClearingTrades()
+	if err != nil || len(trades) != 1 || trades[0].Price != "0.24567" {
+		t.Fatalf("clearing trades %+v, %v", trades, err)
+	}
+	if _, err := client.CancelClearingOrder(clearingOrder.ClearingID); err != nil {
+		t.Fatalf("CancelClearingOrder failed: %v", err)
+	}
+
+	// failures are returned instead of exiting
+	if _, err := client.CancelClearingOrder(clearingOrder.ClearingID); err == nil {
+		t.Errorf("cancelling a cancelled clearing order should fail")
+	}
+	if _, err := client.GetClearingOrderStatus("missing"); err == nil {
+		t.Errorf("unknown clearing orders should fail")
+	}
 }
@@ -0,0 +1,271 @@
+package private
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// invoke Gemini exchange REST API staking endpoints
+
+func GetStakingBalances() []StakingBalance {
+	/*
+		Get the staked balances of the account, one per staked currency
+
+		Returns:
+		An array of StakingBalance objects
+	*/
+	balances, err := DefaultClient().GetStakingBalances()
+	if err != nil {
+		log.Fatalf("Error fetching staking balances: %v", err)
+		return nil
+	}
+	return balances
+}
+
+func (c *Client) GetStakingBalances() ([]StakingBalance, error) {
+	/*
+		Get the staked balances of the account. See GetStakingBalances
+	*/
+	c.Logger().Info("GetStakingBalances")
+	var balances []StakingBalance
+	payload, _ := json.Marshal(GetStakingBalancesRequest{
+		Request: "/v1/balances/staking",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &balances); err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+func Stake(providerID string, currency string, amount float64) *StakingTransaction {
+	/*
+		Stake an amount of a currency with a staking provider
+
+		Args:
+		providerID (string): The staking provider id. See public.GetStakingRates
+		currency (string): The currency to stake (e.g., "MATIC")
+		amount (float64): The amount of currency to stake
+
+		Returns:
+		Pointer to the resulting StakingTransaction
+	*/
+	transaction, err := DefaultClient().Stake(providerID, currency, amount)
+	if err != nil {
+		log.Fatalf("Error staking: %v", err)
+		return nil
+	}
+	return transaction
+}
+
+func (c *Client) Stake(providerID string, currency string, amount float64) (*StakingTransaction, error) {
+	/*
+		Stake an amount of a currency with a staking provider. See Stake
+	*/
+	c.Logger().Info("Stake", "provider_id", providerID, "currency", currency, "amount", amount)
+	return c.postStakingRequest("/v1/staking/stake", providerID, currency, amount)
+}
+
+func Unstake(providerID string, currency string, amount float64) *StakingTransaction {
+	/*
+		Withdraw an amount of a staked currency from a staking provider
+
+		Args:
+		providerID (string): The staking provider id the currency is staked with
+		currency (string): The currency to unstake (e.g., "MATIC")
+		amount (float64): The amount of currency to unstake
+
+		Returns:
+		Pointer to the resulting StakingTransaction
+	*/
+	transaction, err := DefaultClient().Unstake(providerID, currency, amount)
+	if err != nil {
+		log.Fatalf("Error unstaking: %v", err)
+		return nil
+	}
+	return transaction
+}
+
+func (c *Client) Unstake(providerID string, currency string, amount float64) (*StakingTransaction, error) {
+	/*
+		Withdraw an amount of a staked currency from a staking provider. See Unstake
+	*/
+	c.Logger().Info("Unstake", "provider_id", providerID, "currency", currency, "amount", amount)
+	return c.postStakingRequest("/v1/staking/unstake", providerID, currency, amount)
+}
+
+func (c *Client) postStakingRequest(request string, providerID string, currency string, amount float64) (*StakingTransaction, error) {
+	var transaction StakingTransaction
+	payload, _ := json.Marshal(StakingRequest{
+		ProviderID: providerID,
+		Currency:   currency,
+		Amount:     strconv.FormatFloat(amount, 'f', -1, 64),
+		Request:    request,
+		Nonce:      util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func GetStakingRewards(since string, until string) map[string]map[string]StakingReward {
+	/*
+		Get the staking rewards accrued, grouped by provider and currency
+
+		Args:
+		since (string): Start of the period in ISO 8601 format (e.g., "2024-01-01T00:00:00.000Z")
+		until (string): Optional. End of the period in ISO 8601 format; empty for now
+
+		Returns:
+		A map of provider id to a map of currency to StakingReward
+	*/
+	rewards, err := DefaultClient().GetStakingRewards(since, until)
+	if err != nil {
+		log.Fatalf("Error fetching staking rewards: %v", err)
+		return nil
+	}
+	return rewards
+}
+
+func (c *Client) GetStakingRewards(since string, until string) (map[string]map[string]StakingReward, error) {
+	/*
+		Get the staking rewards accrued, grouped by provider and currency. See GetStakingRewards
+	*/
+	c.Logger().Info("GetStakingRewards", "since", since, "until", until)
+	var rewards map[string]map[string]StakingReward
+	payload, _ := json.Marshal(GetStakingRewardsRequest{
+		Since:   since,
+		Until:   until,
+		Request: "/v1/staking/rewards",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &rewards); err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
+func GetStakingHistory(since string, until string, limit int) []StakingHistory {
+	/*
+		Get the staking deposits, withdrawals and reward payments of the account
+
+		Args:
+		since (string): Optional. Start of the period in ISO 8601 format; empty to omit
+		until (string): Optional. End of the period in ISO 8601 format; empty to omit
+		limit (int): Optional. Maximum number of transactions to return; 0 for the API default
+
+		Returns:
+		An array of StakingHistory objects, one per provider
+	*/
+	history, err := DefaultClient().GetStakingHistory(since, until, limit)
+	if err != nil {
+		log.Fatalf("Error fetching staking history: %v", err)
+		return nil
+	}
+	return history
+}
+
+func (c *Client) GetStakingHistory(since string, until string, limit int) ([]StakingHistory, error) {
+	/*
+		Get the staking deposits, withdrawals and reward payments of the account. See GetStakingHistory
+	*/
+	c.Logger().Info("GetStakingHistory", "since", since, "until", until, "limit", limit)
+	var history []StakingHistory
+	payload, _ := json.Marshal(GetStakingHistoryRequest{
+		Since:   since,
+		Until:   until,
+		Limit:   limit,
+		Request: "/v1/staking/history",
+		Nonce:   util.GenerateNonceString(),
+	})
+	if err := c.PostPrivateEndpoint(payload, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func GetHoldings() []Holding {
+	/*
+		Get a combined view of exchange and staked balances, one Holding per currency
+
+		Returns:
+		An array of Holding objects sorted by currency
+	*/
+	holdings, err := DefaultClient().GetHoldings()
+	if err != nil {
+		log.Fatalf("Error fetching holdings: %v", err)
+		return nil
+	}
+	return holdings
+}
+
+func (c *Client) GetHoldings() ([]Holding, error) {
+	/*
+		Get a combined view of exchange and staked balances. See GetHoldings
+	*/
+	c.Logger().Info("GetHoldings")
+	balances, err := c.GetAvailableBalances()
+	if err != nil {
+		return nil, err
+	}
+	staking, err := c.GetStakingBalances()
+	if err != nil {
+		return nil, err
+	}
+	return CombineHoldings(balances, staking)
+}
+
+func CombineHoldings(balances []AvailableBalance, staking []StakingBalance) ([]Holding, error) {
+	/*
+		Merge exchange balances and staking balances into one Holding per currency
+
+		Args:
+		balances ([]AvailableBalance): exchange balances, as returned by GetAvailableBalances
+		staking ([]StakingBalance): staked balances, as returned by GetStakingBalances
+
+		Returns:
+		An array of Holding objects sorted by currency, or an error if an exchange balance amount cannot be parsed
+	*/
+	byCurrency := map[string]*Holding{}
+	holding := func(currency string) *Holding {
+		currency = strings.ToUpper(currency)
+		if h, ok := byCurrency[currency]; ok {
+			return h
+		}
+		h := &Holding{Currency: currency}
+		byCurrency[currency] = h
+		return h
+	}
+
+	for _, balance := range balances {
+		amount, err := strconv.ParseFloat(balance.Amount, 64)
+		if err != nil {
+			return nil, errors.New("invalid " + balance.Currency + " amount: " + err.Error())
+		}
+		available, err := strconv.ParseFloat(balance.Available, 64)
+		if err != nil {
+			return nil, errors.New("invalid " + balance.Currency + " available amount: " + err.Error())
+		}
+		h := holding(balance.Currency)
+		h.Exchange += amount
+		h.Available += available
+	}
+	for _, balance := range staking {
+		holding(balance.Currency).Staked += balance.Balance
+	}
+
+	holdings := make([]Holding, 0, len(byCurrency))
+	for _, h := range byCurrency {
+		h.Total = h.Exchange + h.Staked
+		holdings = append(holdings, *h)
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].Currency < holdings[j].Currency })
+	return holdings, nil
+}
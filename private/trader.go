@@ -0,0 +1,19 @@
+package private
+
+// Trader places and inspects spot orders. Client implements it against the live API; paper.Trader simulates it
+// against live market data without risking funds.
+type Trader interface {
+	LimitBuy(symbol string, amount float64, limitPrice float64, options ...string) (*Order, error)
+	LimitSell(symbol string, amount float64, limitPrice float64, options ...string) (*Order, error)
+	// LimitOrder places a limit order under clientOrderID, or a random client order id if it is empty
+	LimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options ...string) (*Order, error)
+	StopLimitBuy(symbol string, amount float64, stopPrice float64, limitPrice float64) (*Order, error)
+	StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) (*Order, error)
+	CancelOrder(order_id int) (*Order, error)
+	GetOrderStatus(order_id int) (*Order, error)
+	GetActiveOrders() ([]Order, error)
+	GetAvailableBalances() ([]AvailableBalance, error)
+	GetClosedOrdersHistory() ([]Order, error)
+}
+
+var _ Trader = (*Client)(nil)
@@ -1,5 +1,7 @@
 package public
 
+import "time"
+
 type TickerV1 struct {  
 	Ask 	string 	`json:"ask"`
 	Bid 	string	`json:"bid"`
@@ -15,4 +17,37 @@ type TickerV2 struct {
 	Changes []string  	`json:"changes"`
 	Bid 	string		`json:"bid"`
 	Ask 	string 		`json:"ask"`
-}
\ No newline at end of file
+}
+type RiskStats struct {
+	ProductType          string `json:"product_type"`
+	MarkPrice            string `json:"mark_price"`
+	IndexPrice           string `json:"index_price"`
+	OpenInterest         string `json:"open_interest"`
+	OpenInterestNotional string `json:"open_interest_notional"`
+}
+
+type FundingAmount struct {
+	Symbol                    string  `json:"symbol"`
+	FundingDateTime           string  `json:"fundingDateTime"`
+	FundingTimestampMilliSecs int64   `json:"fundingTimestampMilliSecs"`
+	NextFundingTimestamp      int64   `json:"nextFundingTimestamp"`
+	Amount                    float64 `json:"amount"`
+	EstimatedFundingAmount    float64 `json:"estimatedFundingAmount"`
+}
+
+type StakingRate struct {
+	ProviderID string  `json:"providerId"`
+	Rate       float64 `json:"rate"`
+	APY        float64 `json:"apy"`
+	Currency   string  `json:"currency"`
+	Minimum    float64 `json:"minimum"`
+}
+
+type Candle struct {
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
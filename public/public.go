@@ -1,6 +1,7 @@
 package public
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,9 +9,12 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/austinjhunt/go-gemini/telemetry"
 	"github.com/austinjhunt/go-gemini/util"
 )
 
@@ -32,31 +36,65 @@ func GetPublicEndpoint(endpoint string, target interface{}) error {
 
 	method := "GET"
 
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return errors.New("error creating request: " + err.Error())
-	}
-
-	req.Header.Add("Content-Type", "application/json")
-
-	res, err := client.Do(req)
-	if err != nil {
-		return errors.New("error making request: " + err.Error())
+	client := util.HTTPClient()
+	logger := util.Logger().With("endpoint", endpoint)
+	metrics := telemetry.DefaultMetrics()
+	_, span := telemetry.DefaultTracer().Start(context.Background(), method+" "+endpoint)
+	defer span.End()
+	span.SetAttributes(telemetry.String("api", "public"), telemetry.String("endpoint", endpoint))
+
+	var res *http.Response
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			span.RecordError(err)
+			return errors.New("error creating request: " + err.Error())
+		}
+
+		req.Header.Add("Content-Type", "application/json")
+
+		start := time.Now()
+		res, err = client.Do(req)
+		if err != nil {
+			logger.Error("public request failed", "latency", time.Since(start), "error", err)
+			metrics.IncError("public", endpoint, "RequestFailed")
+			span.RecordError(err)
+			return errors.New("error making request: " + err.Error())
+		}
+		metrics.ObserveRequest("public", endpoint, res.StatusCode, time.Since(start))
+		logger.Debug("public request", "status", res.StatusCode, "latency", time.Since(start))
+
+		if res.StatusCode != http.StatusTooManyRequests || attempt >= util.MaxRateLimitRetries {
+			break
+		}
+		res.Body.Close()
+		wait := util.RateLimitWait(res, attempt+1)
+		logger.Warn("public request rate limited", "wait", wait, "attempt", attempt+1)
+		metrics.IncRetry("public", endpoint)
+		metrics.ObserveRateLimitWait("public", endpoint, wait)
+		util.Sleep(wait)
 	}
 	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		return errors.New("received non-200 status code: " + res.Status)
-	}
+	span.SetAttributes(telemetry.Int("status", res.StatusCode))
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
+		span.RecordError(err)
 		return errors.New("error reading response body: " + err.Error())
 	}
 
+	if res.StatusCode != http.StatusOK {
+		reason := util.APIErrorReason(res.StatusCode, body)
+		metrics.IncError("public", endpoint, reason)
+		span.SetAttributes(telemetry.String("reason", reason))
+		err := errors.New("received non-200 status code: " + res.Status)
+		span.RecordError(err)
+		return err
+	}
+
 	err = json.Unmarshal(body, target)
 	if err != nil {
+		span.RecordError(err)
 		return errors.New("error unmarshalling response: " + err.Error())
 	}
 
@@ -77,7 +115,7 @@ func DownloadPublicFile(endpoint string, filePath string) error {
 
 	// Perform the HTTP GET request to download the file.
 	url := util.GetBaseAPIUrl() + endpoint
-	response, err := http.Get(url)
+	response, err := util.HTTPClient().Get(url)
 	if err != nil {
 		return errors.New("failed to download funding amount report: " + err.Error())
 	}
@@ -101,7 +139,7 @@ func DownloadPublicFile(endpoint string, filePath string) error {
 		return errors.New("failed to save the report: " + err.Error())
 	}
 
-	log.Printf("Report downloaded successfully and saved to %s\n", filePath)
+	util.Logger().Info("report downloaded", "endpoint", endpoint, "path", filePath)
 	return nil
 }
 
@@ -159,7 +197,7 @@ func GetNetwork(token string) map[string]interface{} {
 }
 
 func GetTicker(symbol string) *TickerV1 {
-	util.Info(fmt.Sprintf("GetTicker, symbol: %s", symbol))
+	util.Logger().Info("GetTicker", "symbol", symbol)
 	var ticker TickerV1
 	url := "/v1/pubticker/" + symbol
 
@@ -173,7 +211,7 @@ func GetTicker(symbol string) *TickerV1 {
 }
 
 func GetTickerV2(symbol string) *TickerV2 {
-	util.Info(fmt.Sprintf("GetTickerV2, symbol: %s", symbol))
+	util.Logger().Info("GetTickerV2", "symbol", symbol)
 	var ticker TickerV2
 	url := "/v2/ticker/" + strings.ToLower(symbol)
 
@@ -370,7 +408,7 @@ func GetCurrentCoinPriceUSD(symbol string) float64 {
 }
 
 func ConvertUSDToCryptoAmount(dollarAmount float64, symbol string) float64 {
-	util.Info(fmt.Sprintf("Converting %f USD to %s", dollarAmount, symbol))
+	util.Logger().Info("ConvertUSDToCryptoAmount", "symbol", symbol, "usd_amount", dollarAmount)
 	ticker := GetTickerV2(symbol)
 	if ticker == nil {
 		log.Fatalf("Ticker data for %s not found", symbol)
@@ -384,6 +422,132 @@ func ConvertUSDToCryptoAmount(dollarAmount float64, symbol string) float64 {
 	}
 
 	cryptoAmount := dollarAmount / askPrice
-	util.Info(fmt.Sprintf("%f USD = %f %s", dollarAmount, cryptoAmount, symbol))
+	util.Logger().Debug("converted USD to crypto amount", "symbol", symbol, "usd_amount", dollarAmount, "crypto_amount", cryptoAmount)
 	return cryptoAmount
 }
+
+func GetRiskStats(symbol string) *RiskStats {
+	/*
+		Get the mark price, index price and open interest for a perpetual symbol
+
+		Args:
+		symbol (string): Trading pair symbol. Available only for perpetual pairs like BTCGUSDPERP
+
+		Returns:
+		Pointer to a RiskStats object
+	*/
+	util.Logger().Info("GetRiskStats", "symbol", symbol)
+	var riskStats RiskStats
+	url := "/v1/riskstats/" + strings.ToUpper(symbol)
+
+	err := GetPublicEndpoint(url, &riskStats)
+	if err != nil {
+		log.Fatalf("Error fetching risk stats: %v", err)
+		return nil
+	}
+
+	return &riskStats
+}
+
+func GetFundingAmountDetails(symbol string) *FundingAmount {
+	/*
+		Typed variant of GetFundingAmount; includes the estimated funding amount and timestamp of the next funding payment
+
+		Args:
+		symbol (string): Trading pair symbol. Available only for perpetual pairs like BTCGUSDPERP
+
+		Returns:
+		Pointer to a FundingAmount object
+	*/
+	var fundingAmount FundingAmount
+	url := "/v1/fundingamount/" + symbol
+
+	err := GetPublicEndpoint(url, &fundingAmount)
+	if err != nil {
+		log.Fatalf("Error fetching funding amount %v", err)
+		return nil
+	}
+	return &fundingAmount
+}
+
+func GetStakingRates() map[string]map[string]StakingRate {
+	/*
+		Get the current staking interest rates for each staking provider and currency
+
+		Args:
+		None
+
+		Returns:
+		A map of provider id to a map of currency to StakingRate
+	*/
+	var rates map[string]map[string]StakingRate
+	url := "/v1/staking/rates"
+
+	err := GetPublicEndpoint(url, &rates)
+	if err != nil {
+		log.Fatalf("Error fetching staking rates %v", err)
+	}
+	return rates
+}
+
+func GetCandleSeries(symbol string, time_frame string) []Candle {
+	/*
+		Typed variant of GetCandles
+
+		Args:
+		symbol (string): Trading pair symbol
+		time_frame (string): Time range for each candle: 1m, 5m, 15m, 30m, 1hr, 6hr or 1day
+
+		Returns:
+		Candles ordered oldest first
+	*/
+	candles, err := ParseCandles(GetCandles(symbol, time_frame))
+	if err != nil {
+		log.Fatalf("Error parsing candles: %v", err)
+		return nil
+	}
+	return candles
+}
+
+func ParseCandles(raw [][]interface{}) ([]Candle, error) {
+	/*
+		Convert candles as returned by GetCandles or GetDerivativesCandles ([time ms, open, high, low, close, volume]) into Candle values, ordered oldest first
+	*/
+	candles := make([]Candle, 0, len(raw))
+	for i, values := range raw {
+		if len(values) < 6 {
+			return nil, errors.New("candle " + strconv.Itoa(i) + " has " + strconv.Itoa(len(values)) + " values, expected 6")
+		}
+		var fields [6]float64
+		for j, value := range values[:6] {
+			number, err := toFloat(value)
+			if err != nil {
+				return nil, errors.New("candle " + strconv.Itoa(i) + ": " + err.Error())
+			}
+			fields[j] = number
+		}
+		candles = append(candles, Candle{
+			Time:   time.UnixMilli(int64(fields[0])).UTC(),
+			Open:   fields[1],
+			High:   fields[2],
+			Low:    fields[3],
+			Close:  fields[4],
+			Volume: fields[5],
+		})
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	return candles, nil
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, errors.New("unexpected value " + fmt.Sprint(value))
+	}
+}
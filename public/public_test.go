@@ -9,10 +9,26 @@ import (
 	"testing"
 	"time"
 
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/telemetry"
 	"github.com/austinjhunt/go-gemini/util"
 	"github.com/xuri/excelize/v2"
 )
 
+// TestMain runs the tests against a geminitest mock exchange unless GEMINI_EXCHANGE_LIVE_TESTS is "true"
+func TestMain(m *testing.M) {
+	if os.Getenv("GEMINI_EXCHANGE_LIVE_TESTS") == "true" {
+		os.Exit(m.Run())
+	}
+	server := geminitest.NewServer()
+	for key, value := range server.Env() {
+		os.Setenv(key, value)
+	}
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
 // TestGetSymbols tests the GetSymbols function
 func TestGetSymbols(t *testing.T) {
 	response := GetSymbols()
@@ -167,8 +183,8 @@ func TestDownloadFundingAmountReport(t *testing.T) {
 		_, _ = w.Write(buf.Bytes())
 	}))
 	defer server.Close()
+	t.Setenv("GEMINI_EXCHANGE_API_BASE_URL", server.URL)
 
-  
 	mockSymbol := "BTCGUSDPERP"
 
 	// Calculate mockFromDate and mockToDate for the past week.
@@ -216,4 +232,96 @@ func TestDownloadFundingAmountReport(t *testing.T) {
 
 	t.Log("TestDownloadFundingAmountReport passed.")
 }
- 
\ No newline at end of file
+ 
+func TestGetRiskStats(t *testing.T) {
+	response := GetRiskStats("BTCGUSDPERP")
+	if response == nil {
+		t.Errorf("GetRiskStats failed")
+		return
+	}
+	log.Println(*response)
+	if response.MarkPrice == "" {
+		t.Errorf("GetRiskStats failed: missing mark price")
+	}
+}
+
+func TestGetFundingAmountDetails(t *testing.T) {
+	response := GetFundingAmountDetails("BTCGUSDPERP")
+	if response == nil {
+		t.Errorf("GetFundingAmountDetails failed")
+		return
+	}
+	log.Println(*response)
+	if response.NextFundingTimestamp == 0 {
+		t.Errorf("GetFundingAmountDetails failed: missing next funding timestamp")
+	}
+}
+
+func TestGetStakingRates(t *testing.T) {
+	response := GetStakingRates()
+	log.Println(response)
+	if len(response) == 0 {
+		t.Errorf("GetStakingRates failed")
+	}
+}
+
+func TestGetPublicEndpointRetriesRateLimitedRequests(t *testing.T) {
+	previousClient := util.HTTPClient()
+	previousSleep := util.Sleep
+	previousMetrics := telemetry.DefaultMetrics()
+	defer func() {
+		util.SetHTTPClient(previousClient)
+		util.Sleep = previousSleep
+		telemetry.SetMetrics(previousMetrics)
+	}()
+
+	var waits []time.Duration
+	util.Sleep = func(d time.Duration) { waits = append(waits, d) }
+	metrics := telemetry.NewInMemoryMetrics()
+	telemetry.SetMetrics(metrics)
+
+	attempts := 0
+	util.SetHTTPClient(&http.Client{Transport: util.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		recorder := httptest.NewRecorder()
+		if attempts < 3 {
+			recorder.WriteHeader(http.StatusTooManyRequests)
+		} else {
+			recorder.WriteString(`["btcusd"]`)
+		}
+		return recorder.Result(), nil
+	})})
+
+	var symbols []string
+	if err := GetPublicEndpoint("/v1/symbols", &symbols); err != nil {
+		t.Fatalf("GetPublicEndpoint failed: %v", err)
+	}
+	if len(symbols) != 1 || attempts != 3 {
+		t.Errorf("got %v after %d attempts", symbols, attempts)
+	}
+	if len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Errorf("unexpected rate limit waits: %v", waits)
+	}
+	if metrics.Retries("public", "/v1/symbols") != 2 || metrics.Requests("public", "/v1/symbols", http.StatusOK) != 1 {
+		t.Errorf("retries or requests were not counted")
+	}
+}
+
+func TestParseCandles(t *testing.T) {
+	candles, err := ParseCandles([][]interface{}{
+		{float64(1700000060000), 101.0, 103.0, 100.0, 102.0, 2.5},
+		{float64(1700000000000), "100", "101.5", "99", "101", "1"},
+	})
+	if err != nil {
+		t.Fatalf("ParseCandles failed: %v", err)
+	}
+	if len(candles) != 2 || candles[0].Close != 101 || candles[1].High != 103 || !candles[0].Time.Before(candles[1].Time) {
+		t.Errorf("ParseCandles returned %+v", candles)
+	}
+	if _, err := ParseCandles([][]interface{}{{1.0, 2.0}}); err == nil {
+		t.Errorf("ParseCandles should reject short candles")
+	}
+	if series := GetCandleSeries("btcusd", "1m"); len(series) == 0 {
+		t.Errorf("GetCandleSeries failed")
+	}
+}
@@ -0,0 +1,67 @@
+// Package risk enforces pre-trade risk checks in front of a private.Trader. Orders breaching a limit are rejected with
+// a *Rejection before they reach the wrapped trader, so nothing is signed or sent.
+package risk
+
+import (
+	"errors"
+	"strings"
+)
+
+// Limits are the risk limits of a symbol. Zero values disable a check.
+type Limits struct {
+	// MaxNotional caps the quote currency value (amount * price) of a single order
+	MaxNotional float64 `json:"max_notional,omitempty"`
+	// MaxPosition caps the base currency balance plus open buy orders, including the new order
+	MaxPosition float64 `json:"max_position,omitempty"`
+	// MaxOpenOrders caps the orders placed through the risk trader that are still live
+	MaxOpenOrders int `json:"max_open_orders,omitempty"`
+	// PriceCollar rejects limit prices further than this fraction from the mid of the current bid and ask (0.05 is 5%).
+	// Stop orders are checked on their stop price, which is where they join the book
+	PriceCollar float64 `json:"price_collar,omitempty"`
+	// DailyLossLimit caps the net realized loss since midnight UTC, in quote currency, of sells placed through the risk
+	// trader, on an average cost basis. Once it is reached only sells are accepted. When the wrapped trader can list
+	// past trades (as *private.Client can), the account's trade history seeds the position and the day's loss, so a
+	// restart doesn't reset it.
+	DailyLossLimit float64 `json:"daily_loss_limit,omitempty"`
+}
+
+// Config holds the limits of each symbol; symbols without an entry use Default
+type Config struct {
+	Default Limits            `json:"default"`
+	Symbols map[string]Limits `json:"symbols,omitempty"`
+}
+
+func (c Config) limits(symbol string) Limits {
+	if limits, ok := c.Symbols[strings.ToLower(symbol)]; ok {
+		return limits
+	}
+	return c.Default
+}
+
+// Errors identifying the check a Rejection failed; match them with errors.Is
+var (
+	ErrMaxNotional    = errors.New("max notional exceeded")
+	ErrMaxPosition    = errors.New("max position exceeded")
+	ErrMaxOpenOrders  = errors.New("max open orders reached")
+	ErrPriceCollar    = errors.New("price outside collar")
+	ErrDailyLossLimit = errors.New("daily loss limit reached")
+)
+
+// Rejection is returned for an order that fails a risk check
+type Rejection struct {
+	// Check is one of the Err* errors above
+	Check  error
+	Symbol string
+	Side   string
+	// Value is the value that breached Limit
+	Value float64
+	Limit float64
+}
+
+func (r *Rejection) Error() string {
+	return "risk: " + r.Side + " " + r.Symbol + " rejected: " + r.Check.Error() + " (" + formatFloat(r.Value) + " > " + formatFloat(r.Limit) + ")"
+}
+
+func (r *Rejection) Unwrap() error {
+	return r.Check
+}
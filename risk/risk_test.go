@@ -0,0 +1,172 @@
+package risk
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/papertest"
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+func expectRejection(t *testing.T, err error, check error) {
+	t.Helper()
+	var rejection *Rejection
+	if !errors.Is(err, check) || !errors.As(err, &rejection) {
+		t.Errorf("expected a %v rejection, got %v", check, err)
+	}
+}
+
+func TestRiskChecks(t *testing.T) {
+	market := papertest.NewMarket(100, 101)
+	inner := paper.NewTrader(market, map[string]float64{"USD": 10000, "BTC": 1}, paper.WithFees(0, 0))
+	trader := NewTrader(inner, Config{
+		Default: Limits{MaxNotional: 100},
+		Symbols: map[string]Limits{"btcusd": {MaxNotional: 1000, MaxPosition: 5, MaxOpenOrders: 2, PriceCollar: 0.1}},
+	}, WithMarketData(market))
+
+	_, err := trader.LimitBuy("ethusd", 2, 60)
+	expectRejection(t, err, ErrMaxNotional)
+	_, err = trader.LimitBuy("BTCUSD", 20, 95)
+	expectRejection(t, err, ErrMaxNotional)
+	_, err = trader.LimitSell("btcusd", 0.1, 1005)
+	expectRejection(t, err, ErrPriceCollar)
+	_, err = trader.StopLimitSell("btcusd", 0.1, 85, 80)
+	expectRejection(t, err, ErrPriceCollar)
+
+	// 1 BTC held plus 3 resting buys reaches the limit of 5, a further buy exceeds it
+	if _, err := trader.LimitBuy("btcusd", 3, 95); err != nil {
+		t.Fatalf("LimitBuy within limits failed: %v", err)
+	}
+	_, err = trader.LimitBuy("btcusd", 1.5, 95)
+	expectRejection(t, err, ErrMaxPosition)
+	if _, err := trader.LimitSell("btcusd", 0.5, 110); err != nil {
+		t.Fatalf("LimitSell within limits failed: %v", err)
+	}
+	_, err = trader.LimitSell("btcusd", 0.1, 110)
+	expectRejection(t, err, ErrMaxOpenOrders)
+
+	// rejected orders never reach the wrapped trader
+	if history, _ := inner.GetClosedOrdersHistory(); len(history) != 0 {
+		t.Errorf("rejected orders were placed: %+v", history)
+	}
+	if _, available := balanceOf(t, inner, "USD"); available != 10000-3*95 {
+		t.Errorf("USD available %v", available)
+	}
+}
+
+func TestDailyLossLimit(t *testing.T) {
+	market := papertest.NewMarket(100, 101)
+	inner := paper.NewTrader(market, map[string]float64{"USD": 10000}, paper.WithFees(0, 0))
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trader := NewTrader(inner, Config{Default: Limits{DailyLossLimit: 50}}, WithMarketData(market), WithClock(func() time.Time { return now }))
+
+	if _, err := trader.LimitBuy("btcusd", 2, 101); err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	market.Set(70, 71)
+	if _, err := trader.LimitSell("btcusd", 2, 70); err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+	if loss := trader.DailyLoss("btcusd"); loss != 62 {
+		t.Errorf("daily loss %v, expected 62", loss)
+	}
+	_, err := trader.LimitBuy("btcusd", 0.1, 71)
+	expectRejection(t, err, ErrDailyLossLimit)
+
+	// the limit resets at midnight UTC
+	now = now.Add(12 * time.Hour)
+	if _, err := trader.LimitBuy("btcusd", 0.1, 71); err != nil {
+		t.Errorf("LimitBuy should be accepted the next day: %v", err)
+	}
+}
+
+func TestStopPriceCollar(t *testing.T) {
+	market := papertest.NewMarket(100, 101)
+	inner := paper.NewTrader(market, map[string]float64{"USD": 10000, "BTC": 1}, paper.WithFees(0, 0))
+	trader := NewTrader(inner, Config{Default: Limits{PriceCollar: 0.1}}, WithMarketData(market))
+
+	// a stop is collared on its stop price, not on a limit price set further away to make sure it fills
+	if _, err := trader.StopLimitSell("btcusd", 0.1, 95, 80); err != nil {
+		t.Errorf("StopLimitSell with its stop inside the collar failed: %v", err)
+	}
+	_, err := trader.StopLimitBuy("btcusd", 0.1, 115, 112)
+	expectRejection(t, err, ErrPriceCollar)
+}
+
+func TestDailyLossSeededFromHistory(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	now := time.Now()
+	server.SetClock(func() time.Time { return now })
+
+	// with the seeded 0.01 BTC buy at 29000 the average cost is 29500; selling both at 20000 loses 190
+	if _, err := client.LimitBuy("btcusd", 0.01, 30000); err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	server.SetPrice("btcusd", 20000)
+	if _, err := client.LimitSell("btcusd", 0.02, 20000); err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+
+	// a trader started after the sale still sees the day's loss
+	trader := NewTrader(client, Config{Default: Limits{DailyLossLimit: 100}}, WithClock(func() time.Time { return now }))
+	_, err = trader.LimitBuy("btcusd", 0.01, 20000)
+	expectRejection(t, err, ErrDailyLossLimit)
+	if loss := trader.DailyLoss("btcusd"); math.Abs(loss-190) > 1e-6 {
+		t.Errorf("daily loss %v, expected 190", loss)
+	}
+
+	// the next day it is reset
+	now = now.Add(24 * time.Hour)
+	if loss := trader.DailyLoss("btcusd"); loss != 0 {
+		t.Errorf("daily loss %v the next day", loss)
+	}
+}
+
+func TestDailyLossSeededAfterFills(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	// without a daily loss limit nothing is seeded until DailyLoss is asked, after the trader booked its own fills;
+	// with the 0.01 BTC buy at 29000 from the history the average cost is 29500, so the sale loses 95
+	trader := NewTrader(client, Config{})
+	if _, err := trader.LimitBuy("btcusd", 0.01, 30000); err != nil {
+		t.Fatalf("LimitBuy failed: %v", err)
+	}
+	server.SetPrice("btcusd", 20000)
+	if _, err := trader.LimitSell("btcusd", 0.01, 20000); err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+	if loss := trader.DailyLoss("btcusd"); math.Abs(loss-95) > 1e-6 {
+		t.Errorf("daily loss %v, expected 95: fills booked before seeding should not be counted twice", loss)
+	}
+}
+
+func balanceOf(t *testing.T, trader *paper.Trader, currency string) (float64, float64) {
+	t.Helper()
+	balances, err := trader.GetAvailableBalances()
+	if err != nil {
+		t.Fatalf("GetAvailableBalances failed: %v", err)
+	}
+	for _, b := range balances {
+		if b.Currency == currency {
+			amount, _ := strconv.ParseFloat(b.Amount, 64)
+			available, _ := strconv.ParseFloat(b.Available, 64)
+			return amount, available
+		}
+	}
+	return 0, 0
+}
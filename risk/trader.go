@@ -0,0 +1,413 @@
+package risk
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// Option configures optional behavior of a Trader
+type Option func(*Trader)
+
+func WithMarketData(market paper.MarketData) Option {
+	/*
+		Read quotes and symbol currencies from market instead of the live API
+	*/
+	return func(t *Trader) {
+		t.market = market
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	/*
+		Use the given clock instead of time.Now to reset the daily loss
+	*/
+	return func(t *Trader) {
+		t.now = now
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	/*
+		Log rejections through the given logger instead of util.Logger()
+	*/
+	return func(t *Trader) {
+		t.logger = logger
+	}
+}
+
+// trackedOrder is an order placed through the risk trader that was live when last seen
+type trackedOrder struct {
+	symbol string
+	side   string
+	amount float64
+}
+
+// position is the average cost position and today's realized profit and loss of a symbol, from fills of tracked orders
+// and, once seeded, the account's trade history
+type position struct {
+	amount float64
+	cost   float64
+	day    time.Time
+	pnl    float64
+	seeded bool
+	// booked holds the fills booked from order status before the position was seeded, by order id
+	booked map[string]execution
+}
+
+// execution is the fill of an order, booked once the order is done
+type execution struct {
+	side   string
+	amount float64
+	price  float64
+}
+
+// historyTrader is implemented by traders that can list the account's past trades, like *private.Client
+type historyTrader interface {
+	GetAllPastTrades(symbol string, since int64) ([]private.PastTrade, error)
+}
+
+// Trader wraps a private.Trader, checking every new order against the configured limits before placing it.
+// It implements private.Trader itself, so it can be used wherever the wrapped trader was.
+type Trader struct {
+	private.Trader
+	config Config
+	market paper.MarketData
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	orders    map[int]trackedOrder
+	positions map[string]*position
+}
+
+var _ private.Trader = (*Trader)(nil)
+
+func NewTrader(trader private.Trader, config Config, opts ...Option) *Trader {
+	/*
+		Wrap trader with pre-trade risk checks
+
+		Args:
+		trader (private.Trader): trader orders are placed with once they pass the checks
+		config (Config): per-symbol limits
+		opts (...Option): optional behavior, e.g. WithMarketData
+
+		Returns a pointer to the Trader
+	*/
+	t := &Trader{
+		Trader: trader, config: config, market: &paper.LiveMarketData{}, now: time.Now,
+		orders: map[int]trackedOrder{}, positions: map[string]*position{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.logger == nil {
+		t.logger = util.Logger()
+	}
+	return t
+}
+
+func (t *Trader) LimitBuy(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	return t.place(symbol, "buy", amount, limitPrice, limitPrice, func() (*private.Order, error) {
+		return t.Trader.LimitBuy(symbol, amount, limitPrice, options...)
+	})
+}
+
+func (t *Trader) LimitSell(symbol string, amount float64, limitPrice float64, options ...string) (*private.Order, error) {
+	return t.place(symbol, "sell", amount, limitPrice, limitPrice, func() (*private.Order, error) {
+		return t.Trader.LimitSell(symbol, amount, limitPrice, options...)
+	})
+}
+
+func (t *Trader) LimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options ...string) (*private.Order, error) {
+	return t.place(symbol, side, amount, limitPrice, limitPrice, func() (*private.Order, error) {
+		return t.Trader.LimitOrder(side, symbol, amount, limitPrice, clientOrderID, options...)
+	})
+}
+
+func (t *Trader) StopLimitBuy(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	return t.place(symbol, "buy", amount, limitPrice, stopPrice, func() (*private.Order, error) {
+		return t.Trader.StopLimitBuy(symbol, amount, stopPrice, limitPrice)
+	})
+}
+
+func (t *Trader) StopLimitSell(symbol string, amount float64, stopPrice float64, limitPrice float64) (*private.Order, error) {
+	return t.place(symbol, "sell", amount, limitPrice, stopPrice, func() (*private.Order, error) {
+		return t.Trader.StopLimitSell(symbol, amount, stopPrice, limitPrice)
+	})
+}
+
+func (t *Trader) CancelOrder(order_id int) (*private.Order, error) {
+	order, err := t.Trader.CancelOrder(order_id)
+	if err == nil {
+		t.mu.Lock()
+		t.observe(order_id, order)
+		t.mu.Unlock()
+	}
+	return order, err
+}
+
+func (t *Trader) GetOrderStatus(order_id int) (*private.Order, error) {
+	order, err := t.Trader.GetOrderStatus(order_id)
+	if err == nil {
+		t.mu.Lock()
+		t.observe(order_id, order)
+		t.mu.Unlock()
+	}
+	return order, err
+}
+
+func (t *Trader) DailyLoss(symbol string) float64 {
+	/*
+		Get the net realized loss of a symbol since midnight UTC, from fills of orders placed through the Trader and, when
+		the wrapped trader can list past trades, the account's trades made before
+	*/
+	symbol = strings.ToLower(symbol)
+	if err := t.seed(symbol); err != nil {
+		t.logger.Warn("unable to seed the daily loss", "symbol", symbol, "error", err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refresh()
+	return t.position(symbol).loss()
+}
+
+// place checks a new order and, if it passes, places it with the wrapped trader and tracks it. price is the limit price
+// and trigger the price the order becomes live at: the limit price, or the stop price of a stop order
+func (t *Trader) place(symbol string, side string, amount float64, price float64, trigger float64, submit func() (*private.Order, error)) (*private.Order, error) {
+	symbol = strings.ToLower(symbol)
+	if t.config.limits(symbol).DailyLossLimit > 0 {
+		if err := t.seed(symbol); err != nil {
+			return nil, err
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.check(symbol, side, amount, price, trigger); err != nil {
+		var rejection *Rejection
+		if errors.As(err, &rejection) {
+			t.logger.Warn("order rejected by risk check", "symbol", symbol, "side", side, "amount", amount, "price", price, "error", err)
+		}
+		return nil, err
+	}
+	order, err := submit()
+	if err != nil {
+		return nil, err
+	}
+	if id, err := strconv.Atoi(order.OrderID); err == nil {
+		t.orders[id] = trackedOrder{symbol: symbol, side: side, amount: amount}
+		t.observe(id, order)
+	}
+	return order, nil
+}
+
+func (t *Trader) check(symbol string, side string, amount float64, price float64, trigger float64) error {
+	limits := t.config.limits(symbol)
+	reject := func(check error, value float64, limit float64) error {
+		return &Rejection{Check: check, Symbol: symbol, Side: side, Value: value, Limit: limit}
+	}
+	if notional := amount * price; limits.MaxNotional > 0 && notional > limits.MaxNotional {
+		return reject(ErrMaxNotional, notional, limits.MaxNotional)
+	}
+	if limits.PriceCollar > 0 {
+		book, err := t.market.Book(symbol)
+		if err != nil {
+			return errors.New("risk: unable to read the " + symbol + " quote: " + err.Error())
+		}
+		if len(book.Bids) == 0 || len(book.Asks) == 0 {
+			return errors.New("risk: no quote for " + symbol)
+		}
+		mid := (book.Bids[0].Price + book.Asks[0].Price) / 2
+		if distance := math.Abs(trigger-mid) / mid; distance > limits.PriceCollar {
+			return reject(ErrPriceCollar, distance, limits.PriceCollar)
+		}
+	}
+	if limits.MaxOpenOrders == 0 && limits.MaxPosition == 0 && limits.DailyLossLimit == 0 {
+		return nil
+	}
+	if err := t.refresh(); err != nil {
+		return err
+	}
+	if limits.MaxOpenOrders > 0 {
+		open := 0
+		for _, order := range t.orders {
+			if order.symbol == symbol {
+				open++
+			}
+		}
+		if open >= limits.MaxOpenOrders {
+			return reject(ErrMaxOpenOrders, float64(open+1), float64(limits.MaxOpenOrders))
+		}
+	}
+	if side == "sell" {
+		return nil
+	}
+	if loss := t.position(symbol).loss(); limits.DailyLossLimit > 0 && loss >= limits.DailyLossLimit {
+		return reject(ErrDailyLossLimit, loss, limits.DailyLossLimit)
+	}
+	if limits.MaxPosition > 0 {
+		base, _, err := t.market.Currencies(symbol)
+		if err != nil {
+			return errors.New("risk: unable to read the " + symbol + " currencies: " + err.Error())
+		}
+		balances, err := t.Trader.GetAvailableBalances()
+		if err != nil {
+			return errors.New("risk: unable to read balances: " + err.Error())
+		}
+		exposure := amount
+		for _, balance := range balances {
+			if strings.EqualFold(balance.Currency, base) {
+				held, _ := strconv.ParseFloat(balance.Amount, 64)
+				exposure += held
+			}
+		}
+		for _, order := range t.orders {
+			if order.symbol == symbol && order.side == "buy" {
+				exposure += order.amount
+			}
+		}
+		if exposure > limits.MaxPosition {
+			return reject(ErrMaxPosition, exposure, limits.MaxPosition)
+		}
+	}
+	return nil
+}
+
+// refresh polls the tracked orders, forgetting those no longer live; must be called with t.mu held
+func (t *Trader) refresh() error {
+	for id := range t.orders {
+		order, err := t.Trader.GetOrderStatus(id)
+		if err != nil {
+			return errors.New("risk: unable to refresh order " + strconv.Itoa(id) + ": " + err.Error())
+		}
+		t.observe(id, order)
+	}
+	return nil
+}
+
+// observe updates a tracked order from its latest status, booking its fills once it is no longer live; must be
+// called with t.mu held
+func (t *Trader) observe(id int, order *private.Order) {
+	tracked, ok := t.orders[id]
+	if !ok || order.IsLive {
+		if ok {
+			original, _ := strconv.ParseFloat(order.OriginalAmount, 64)
+			executed, _ := strconv.ParseFloat(order.ExecutedAmount, 64)
+			tracked.amount = original - executed
+			t.orders[id] = tracked
+		}
+		return
+	}
+	delete(t.orders, id)
+	executed, _ := strconv.ParseFloat(order.ExecutedAmount, 64)
+	price, _ := strconv.ParseFloat(order.AvgExecutionPrice, 64)
+	if executed == 0 {
+		return
+	}
+	p := t.position(tracked.symbol)
+	p.fill(tracked.side, executed, price, true)
+	if !p.seeded {
+		p.booked[order.OrderID] = execution{side: tracked.side, amount: executed, price: price}
+	}
+}
+
+// seed replays the account's trade history of symbol into its position the first time a daily loss is needed, so a
+// restart doesn't reset the day's realized loss. The position is rebuilt from the history in order, so fills booked
+// from order status before are counted once; those missing from the history are booked after it, and trades of orders
+// still tracked are left to be booked once the orders are done. It reads the history without t.mu held, so it must be
+// called without it.
+func (t *Trader) seed(symbol string) error {
+	history, ok := t.Trader.(historyTrader)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	seeded := t.position(symbol).seeded
+	t.mu.Unlock()
+	if seeded {
+		return nil
+	}
+	trades, err := history.GetAllPastTrades(symbol, 0)
+	if err != nil {
+		return errors.New("risk: unable to read the " + symbol + " trade history: " + err.Error())
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.position(symbol)
+	if p.seeded {
+		return nil
+	}
+	p.amount, p.cost, p.pnl = 0, 0, 0
+	// the trades are newest first
+	for i := len(trades) - 1; i >= 0; i-- {
+		trade := trades[i]
+		if id, err := strconv.Atoi(trade.OrderID); err == nil && t.tracked(id) {
+			continue
+		}
+		delete(p.booked, trade.OrderID)
+		amount, _ := strconv.ParseFloat(trade.Amount, 64)
+		price, _ := strconv.ParseFloat(trade.Price, 64)
+		p.fill(strings.ToLower(trade.Type), amount, price, !time.UnixMilli(trade.TimestampMs).Before(p.day))
+	}
+	for _, fill := range p.booked {
+		p.fill(fill.side, fill.amount, fill.price, true)
+	}
+	p.seeded, p.booked = true, nil
+	return nil
+}
+
+// tracked reports whether an order placed through the Trader is still live as far as it knows; must be called with
+// t.mu held
+func (t *Trader) tracked(id int) bool {
+	_, ok := t.orders[id]
+	return ok
+}
+
+// fill books an execution at the average cost, adding the profit and loss of a sell to the day's if it was made today
+func (p *position) fill(side string, amount float64, price float64, today bool) {
+	if side == "buy" {
+		p.amount += amount
+		p.cost += amount * price
+		return
+	}
+	if p.amount > 0 {
+		sold := min(amount, p.amount)
+		average := p.cost / p.amount
+		if today {
+			p.pnl += (price - average) * sold
+		}
+		p.cost -= average * sold
+		p.amount -= sold
+	}
+}
+
+// loss is the net realized loss of the day, or 0 if it is profitable
+func (p *position) loss() float64 {
+	return max(0, -p.pnl)
+}
+
+// position gets the position of a symbol, resetting its realized loss at midnight UTC
+func (t *Trader) position(symbol string) *position {
+	day := t.now().UTC().Truncate(24 * time.Hour)
+	p, ok := t.positions[symbol]
+	if !ok {
+		p = &position{booked: map[string]execution{}}
+		t.positions[symbol] = p
+	}
+	if !p.day.Equal(day) {
+		p.day, p.pnl = day, 0
+	}
+	return p
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
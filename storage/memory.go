@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+// MemoryStore is a Repository kept in memory
+type MemoryStore struct {
+	mu        sync.Mutex
+	orders    map[string]private.Order
+	fills     map[int64]private.PastTrade
+	snapshots map[int64]map[string]private.AvailableBalance
+	candles   map[string]map[int64]public.Candle
+}
+
+var _ Repository = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	/*
+		Create an empty in-memory repository
+	*/
+	return &MemoryStore{
+		orders:    map[string]private.Order{},
+		fills:     map[int64]private.PastTrade{},
+		snapshots: map[int64]map[string]private.AvailableBalance{},
+		candles:   map[string]map[int64]public.Candle{},
+	}
+}
+
+func (s *MemoryStore) UpsertOrders(ctx context.Context, orders []private.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, order := range orders {
+		s.orders[order.OrderID] = order
+	}
+	return nil
+}
+
+func (s *MemoryStore) Orders(ctx context.Context, symbol string) ([]private.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := []private.Order{}
+	for _, order := range s.orders {
+		if symbol == "" || strings.EqualFold(order.Symbol, symbol) {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].TimestampMs != orders[j].TimestampMs {
+			return orders[i].TimestampMs < orders[j].TimestampMs
+		}
+		a, _ := strconv.ParseInt(orders[i].OrderID, 10, 64)
+		b, _ := strconv.ParseInt(orders[j].OrderID, 10, 64)
+		return a < b
+	})
+	return orders, nil
+}
+
+func (s *MemoryStore) UpsertFills(ctx context.Context, fills []private.PastTrade) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, fill := range fills {
+		s.fills[fill.TID] = fill
+	}
+	return nil
+}
+
+func (s *MemoryStore) Fills(ctx context.Context, symbol string) ([]private.PastTrade, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fills := []private.PastTrade{}
+	for _, fill := range s.fills {
+		if symbol == "" || strings.EqualFold(fill.Symbol, symbol) {
+			fills = append(fills, fill)
+		}
+	}
+	sort.Slice(fills, func(i, j int) bool {
+		if fills[i].TimestampMs != fills[j].TimestampMs {
+			return fills[i].TimestampMs < fills[j].TimestampMs
+		}
+		return fills[i].TID < fills[j].TID
+	})
+	return fills, nil
+}
+
+func (s *MemoryStore) SaveBalances(ctx context.Context, snapshot BalanceSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := snapshot.Time.UnixMilli()
+	if s.snapshots[key] == nil {
+		s.snapshots[key] = map[string]private.AvailableBalance{}
+	}
+	for _, balance := range snapshot.Balances {
+		s.snapshots[key][balance.Currency] = balance
+	}
+	return nil
+}
+
+func (s *MemoryStore) BalanceSnapshots(ctx context.Context, from time.Time, to time.Time) ([]BalanceSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshots := []BalanceSnapshot{}
+	for key, balances := range s.snapshots {
+		at := time.UnixMilli(key)
+		if !inRange(at, from, to) {
+			continue
+		}
+		snapshot := BalanceSnapshot{Time: at}
+		for _, balance := range balances {
+			snapshot.Balances = append(snapshot.Balances, balance)
+		}
+		sort.Slice(snapshot.Balances, func(i, j int) bool { return snapshot.Balances[i].Currency < snapshot.Balances[j].Currency })
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+func candleKey(symbol string, timeFrame string) string {
+	return strings.ToLower(symbol) + "/" + timeFrame
+}
+
+func (s *MemoryStore) UpsertCandles(ctx context.Context, symbol string, timeFrame string, candles []public.Candle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := candleKey(symbol, timeFrame)
+	if s.candles[key] == nil {
+		s.candles[key] = map[int64]public.Candle{}
+	}
+	for _, candle := range candles {
+		s.candles[key][candle.Time.UnixMilli()] = candle
+	}
+	return nil
+}
+
+func (s *MemoryStore) Candles(ctx context.Context, symbol string, timeFrame string, from time.Time, to time.Time) ([]public.Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	candles := []public.Candle{}
+	for _, candle := range s.candles[candleKey(symbol, timeFrame)] {
+		if inRange(candle.Time, from, to) {
+			candles = append(candles, candle)
+		}
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	return candles, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+	_ "modernc.org/sqlite" // registers the pure Go "sqlite" driver
+)
+
+// DefaultDriver is the database/sql driver name OpenSQLite uses, registered by modernc.org/sqlite, which needs no cgo
+const DefaultDriver = "sqlite"
+
+// Schema creates the tables of an SQLStore; statements are idempotent
+var Schema = []string{
+	`CREATE TABLE IF NOT EXISTS orders (
+		order_id TEXT PRIMARY KEY,
+		symbol TEXT NOT NULL,
+		timestampms INTEGER NOT NULL,
+		is_live INTEGER NOT NULL,
+		data TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS orders_symbol ON orders (symbol, timestampms)`,
+	`CREATE TABLE IF NOT EXISTS fills (
+		tid INTEGER PRIMARY KEY,
+		symbol TEXT NOT NULL,
+		order_id TEXT NOT NULL,
+		timestampms INTEGER NOT NULL,
+		data TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS fills_symbol ON fills (symbol, timestampms)`,
+	`CREATE TABLE IF NOT EXISTS balances (
+		taken_at INTEGER NOT NULL,
+		currency TEXT NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (taken_at, currency)
+	)`,
+	`CREATE TABLE IF NOT EXISTS candles (
+		symbol TEXT NOT NULL,
+		time_frame TEXT NOT NULL,
+		time INTEGER NOT NULL,
+		open REAL NOT NULL,
+		high REAL NOT NULL,
+		low REAL NOT NULL,
+		close REAL NOT NULL,
+		volume REAL NOT NULL,
+		PRIMARY KEY (symbol, time_frame, time)
+	)`,
+}
+
+// SQLStore is a Repository in an SQL database. Its statements use SQLite syntax: ? placeholders and
+// INSERT ... ON CONFLICT DO UPDATE upserts.
+type SQLStore struct {
+	db *sql.DB
+}
+
+var _ Repository = (*SQLStore)(nil)
+
+func OpenSQLite(path string) (*SQLStore, error) {
+	/*
+		Open (creating if needed) the SQLite database at path with the DefaultDriver and create the schema
+	*/
+	return OpenSQL(DefaultDriver, path)
+}
+
+func OpenSQL(driver string, dsn string) (*SQLStore, error) {
+	/*
+		Open a database with a registered database/sql driver and create the schema
+
+		Args:
+		driver (string): driver name, e.g. "sqlite"
+		dsn (string): data source name, e.g. a file path for SQLite
+
+		Returns the store, or an error if the database can't be opened or migrated
+	*/
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.New("error opening database: " + err.Error())
+	}
+	store, err := NewSQLStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	/*
+		Create a store on an open database, creating the schema. Closing the store closes db
+	*/
+	for _, statement := range Schema {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, errors.New("error creating schema: " + err.Error())
+		}
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// inTx runs fn in a transaction, committing if it succeeds
+func (s *SQLStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) UpsertOrders(ctx context.Context, orders []private.Order) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, order := range orders {
+			data, err := json.Marshal(order)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO orders (order_id, symbol, timestampms, is_live, data) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (order_id) DO UPDATE SET symbol = excluded.symbol, timestampms = excluded.timestampms, is_live = excluded.is_live, data = excluded.data`,
+				order.OrderID, strings.ToLower(order.Symbol), order.TimestampMs, order.IsLive, string(data)); err != nil {
+				return errors.New("error upserting order " + order.OrderID + ": " + err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) Orders(ctx context.Context, symbol string) ([]private.Order, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM orders WHERE ? = '' OR symbol = ? ORDER BY timestampms, CAST(order_id AS INTEGER)`,
+		symbol, strings.ToLower(symbol))
+	if err != nil {
+		return nil, err
+	}
+	orders := []private.Order{}
+	err = scanJSON(rows, func(data []byte) error {
+		var order private.Order
+		if err := json.Unmarshal(data, &order); err != nil {
+			return err
+		}
+		orders = append(orders, order)
+		return nil
+	})
+	return orders, err
+}
+
+func (s *SQLStore) UpsertFills(ctx context.Context, fills []private.PastTrade) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, fill := range fills {
+			data, err := json.Marshal(fill)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO fills (tid, symbol, order_id, timestampms, data) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (tid) DO UPDATE SET symbol = excluded.symbol, order_id = excluded.order_id, timestampms = excluded.timestampms, data = excluded.data`,
+				fill.TID, strings.ToLower(fill.Symbol), fill.OrderID, fill.TimestampMs, string(data)); err != nil {
+				return errors.New("error upserting fill: " + err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) Fills(ctx context.Context, symbol string) ([]private.PastTrade, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM fills WHERE ? = '' OR symbol = ? ORDER BY timestampms, tid`,
+		symbol, strings.ToLower(symbol))
+	if err != nil {
+		return nil, err
+	}
+	fills := []private.PastTrade{}
+	err = scanJSON(rows, func(data []byte) error {
+		var fill private.PastTrade
+		if err := json.Unmarshal(data, &fill); err != nil {
+			return err
+		}
+		fills = append(fills, fill)
+		return nil
+	})
+	return fills, err
+}
+
+func (s *SQLStore) SaveBalances(ctx context.Context, snapshot BalanceSnapshot) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, balance := range snapshot.Balances {
+			data, err := json.Marshal(balance)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO balances (taken_at, currency, data) VALUES (?, ?, ?)
+				ON CONFLICT (taken_at, currency) DO UPDATE SET data = excluded.data`,
+				snapshot.Time.UnixMilli(), balance.Currency, string(data)); err != nil {
+				return errors.New("error saving balance: " + err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) BalanceSnapshots(ctx context.Context, from time.Time, to time.Time) ([]BalanceSnapshot, error) {
+	lower, upper := bounds(from, to)
+	rows, err := s.db.QueryContext(ctx, `SELECT taken_at, data FROM balances WHERE taken_at >= ? AND taken_at < ? ORDER BY taken_at, currency`, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snapshots := []BalanceSnapshot{}
+	for rows.Next() {
+		var takenAt int64
+		var data []byte
+		if err := rows.Scan(&takenAt, &data); err != nil {
+			return nil, err
+		}
+		var balance private.AvailableBalance
+		if err := json.Unmarshal(data, &balance); err != nil {
+			return nil, err
+		}
+		at := time.UnixMilli(takenAt)
+		if len(snapshots) == 0 || !snapshots[len(snapshots)-1].Time.Equal(at) {
+			snapshots = append(snapshots, BalanceSnapshot{Time: at})
+		}
+		last := &snapshots[len(snapshots)-1]
+		last.Balances = append(last.Balances, balance)
+	}
+	return snapshots, rows.Err()
+}
+
+func (s *SQLStore) UpsertCandles(ctx context.Context, symbol string, timeFrame string, candles []public.Candle) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range candles {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO candles (symbol, time_frame, time, open, high, low, close, volume) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (symbol, time_frame, time) DO UPDATE SET open = excluded.open, high = excluded.high, low = excluded.low, close = excluded.close, volume = excluded.volume`,
+				strings.ToLower(symbol), timeFrame, c.Time.UnixMilli(), c.Open, c.High, c.Low, c.Close, c.Volume); err != nil {
+				return errors.New("error upserting candle: " + err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) Candles(ctx context.Context, symbol string, timeFrame string, from time.Time, to time.Time) ([]public.Candle, error) {
+	lower, upper := bounds(from, to)
+	rows, err := s.db.QueryContext(ctx, `SELECT time, open, high, low, close, volume FROM candles
+		WHERE symbol = ? AND time_frame = ? AND time >= ? AND time < ? ORDER BY time`, strings.ToLower(symbol), timeFrame, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	candles := []public.Candle{}
+	for rows.Next() {
+		var c public.Candle
+		var at int64
+		if err := rows.Scan(&at, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume); err != nil {
+			return nil, err
+		}
+		c.Time = time.UnixMilli(at)
+		candles = append(candles, c)
+	}
+	return candles, rows.Err()
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// bounds converts a [from, to) range to milliseconds, with zero times open
+func bounds(from time.Time, to time.Time) (int64, int64) {
+	lower, upper := int64(math.MinInt64), int64(math.MaxInt64)
+	if !from.IsZero() {
+		lower = from.UnixMilli()
+	}
+	if !to.IsZero() {
+		upper = to.UnixMilli()
+	}
+	return lower, upper
+}
+
+// scanJSON calls fn with the single data column of every row, closing rows
+func scanJSON(rows *sql.Rows, fn func(data []byte) error) error {
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,41 @@
+// Package storage persists orders, fills, balance snapshots and candles fetched through the library. Repository is
+// implemented by MemoryStore, for tests, and SQLStore, for SQLite databases opened through database/sql. Every write
+// is an idempotent upsert, so syncing the same data again doesn't duplicate rows.
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+// Repository stores exchange data. Orders are keyed by order id, fills by trade id, balance snapshots by time and
+// currency, and candles by symbol, time frame and time.
+type Repository interface {
+	UpsertOrders(ctx context.Context, orders []private.Order) error
+	// Orders returns the orders of symbol (all symbols when empty), oldest first
+	Orders(ctx context.Context, symbol string) ([]private.Order, error)
+	UpsertFills(ctx context.Context, fills []private.PastTrade) error
+	// Fills returns the fills of symbol (all symbols when empty), oldest first
+	Fills(ctx context.Context, symbol string) ([]private.PastTrade, error)
+	SaveBalances(ctx context.Context, snapshot BalanceSnapshot) error
+	// BalanceSnapshots returns the snapshots taken in [from, to), oldest first
+	BalanceSnapshots(ctx context.Context, from time.Time, to time.Time) ([]BalanceSnapshot, error)
+	UpsertCandles(ctx context.Context, symbol string, timeFrame string, candles []public.Candle) error
+	// Candles returns the candles in [from, to), oldest first; zero times leave the range open
+	Candles(ctx context.Context, symbol string, timeFrame string, from time.Time, to time.Time) ([]public.Candle, error)
+	Close() error
+}
+
+// BalanceSnapshot is the balances of the account at a point in time
+type BalanceSnapshot struct {
+	Time     time.Time
+	Balances []private.AvailableBalance
+}
+
+// inRange reports whether t is in [from, to), treating zero bounds as open
+func inRange(t time.Time, from time.Time, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
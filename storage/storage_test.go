@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+)
+
+// testRepository checks the Repository contract, including that repeated upserts don't duplicate rows
+func testRepository(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	at := time.UnixMilli(1700000000000)
+
+	orders := []private.Order{
+		{OrderID: "2", Symbol: "btcusd", TimestampMs: 2000, IsLive: true, ExecutedAmount: "0"},
+		{OrderID: "1", Symbol: "ethusd", TimestampMs: 1000, ExecutedAmount: "1"},
+	}
+	for i := 0; i < 2; i++ {
+		if err := repo.UpsertOrders(ctx, orders); err != nil {
+			t.Fatalf("UpsertOrders failed: %v", err)
+		}
+	}
+	orders[0].IsLive, orders[0].ExecutedAmount = false, "0.5"
+	repo.UpsertOrders(ctx, orders[:1])
+	all, err := repo.Orders(ctx, "")
+	if err != nil || len(all) != 2 || all[0].OrderID != "1" || all[1].IsLive || all[1].ExecutedAmount != "0.5" {
+		t.Errorf("Orders returned %+v, %v", all, err)
+	}
+	if btc, _ := repo.Orders(ctx, "BTCUSD"); len(btc) != 1 {
+		t.Errorf("Orders of btcusd returned %+v", btc)
+	}
+
+	fills := []private.PastTrade{{TID: 7, Symbol: "BTCUSD", TimestampMs: 3000, Price: "30000"}, {TID: 6, Symbol: "BTCUSD", TimestampMs: 3000}}
+	repo.UpsertFills(ctx, fills)
+	repo.UpsertFills(ctx, fills)
+	if stored, err := repo.Fills(ctx, "btcusd"); err != nil || len(stored) != 2 || stored[0].TID != 6 || stored[1].Price != "30000" {
+		t.Errorf("Fills returned %+v, %v", stored, err)
+	}
+
+	snapshot := BalanceSnapshot{Time: at, Balances: []private.AvailableBalance{{Currency: "USD", Amount: "10"}, {Currency: "BTC", Amount: "1"}}}
+	repo.SaveBalances(ctx, snapshot)
+	repo.SaveBalances(ctx, snapshot)
+	repo.SaveBalances(ctx, BalanceSnapshot{Time: at.Add(time.Hour), Balances: []private.AvailableBalance{{Currency: "USD", Amount: "20"}}})
+	snapshots, err := repo.BalanceSnapshots(ctx, at, at.Add(time.Hour))
+	if err != nil || len(snapshots) != 1 || len(snapshots[0].Balances) != 2 || snapshots[0].Balances[0].Currency != "BTC" {
+		t.Errorf("BalanceSnapshots returned %+v, %v", snapshots, err)
+	}
+
+	candles := []public.Candle{{Time: at.Add(time.Minute), Close: 2}, {Time: at, Close: 1}}
+	repo.UpsertCandles(ctx, "btcusd", "1m", candles)
+	candles[1].Close = 1.5
+	repo.UpsertCandles(ctx, "BTCUSD", "1m", candles)
+	stored, err := repo.Candles(ctx, "btcusd", "1m", time.Time{}, time.Time{})
+	if err != nil || len(stored) != 2 || !stored[0].Time.Equal(at) || stored[0].Close != 1.5 {
+		t.Errorf("Candles returned %+v, %v", stored, err)
+	}
+	if ranged, _ := repo.Candles(ctx, "btcusd", "1m", at.Add(time.Second), time.Time{}); len(ranged) != 1 {
+		t.Errorf("Candles from a time returned %+v", ranged)
+	}
+	if other, _ := repo.Candles(ctx, "btcusd", "5m", time.Time{}, time.Time{}); len(other) != 0 {
+		t.Errorf("Candles of another time frame returned %+v", other)
+	}
+	if err := repo.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testRepository(t, NewMemoryStore())
+}
+
+func TestSQLStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gemini.db")
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	testRepository(t, store)
+
+	// reopening the database keeps the data and re-running the schema is harmless
+	store, err = OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	defer store.Close()
+	orders, err := store.Orders(context.Background(), "")
+	if err != nil || len(orders) == 0 {
+		t.Errorf("reopened store has orders %+v, %v", orders, err)
+	}
+}
+
+func TestOpenSQLWithoutDriver(t *testing.T) {
+	if _, err := OpenSQL("no-such-driver", ""); err == nil {
+		t.Errorf("opening an unregistered driver should fail")
+	}
+}
@@ -0,0 +1,389 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/bot"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// DCAConfig configures a DCA strategy
+type DCAConfig struct {
+	// Schedule is a five field cron expression, evaluated in UTC unless TimeZone is set
+	Schedule string `json:"schedule"`
+	TimeZone string `json:"time_zone"`
+	// Buys are the symbols to buy on every scheduled run and the quote currency amount (e.g. USD) to spend on each
+	Buys []DCABuy `json:"buys"`
+	// HistoryPath is the JSON file execution history is persisted to; without it history is kept in memory only
+	HistoryPath string `json:"history_path"`
+	// BidOffset places orders the given fraction below the bid (default 0, at the bid)
+	BidOffset float64 `json:"bid_offset"`
+	// PriceIncrement and AmountIncrement round order prices and amounts down (defaults 0.01 and 1e-8)
+	PriceIncrement  float64 `json:"price_increment"`
+	AmountIncrement float64 `json:"amount_increment"`
+	// MinOrderSize is the exchange minimum order amount of the symbols; a run ends once its remaining budget buys less,
+	// or less than one PriceIncrement of it remains
+	MinOrderSize float64 `json:"min_order_size"`
+	// RetryAfter is how long an order may rest unfilled before it is cancelled and placed again at the new bid
+	RetryAfter bot.Duration `json:"retry_after"`
+	// MaxAttempts is the number of orders placed for a run before it is marked failed (default 10)
+	MaxAttempts int `json:"max_attempts"`
+}
+
+type DCABuy struct {
+	Symbol string  `json:"symbol"`
+	Amount float64 `json:"amount"`
+}
+
+// DCA execution statuses
+const (
+	DCAPending = "pending"
+	DCAFilled  = "filled"
+	DCAFailed  = "failed"
+)
+
+// DCAExecution is the persisted record of one scheduled buy of one symbol
+type DCAExecution struct {
+	Symbol   string    `json:"symbol"`
+	Slot     time.Time `json:"slot"`
+	Budget   float64   `json:"budget"`
+	Spent    float64   `json:"spent"`
+	Bought   float64   `json:"bought"`
+	Status   string    `json:"status"`
+	Attempts int       `json:"attempts"`
+	OrderIDs []int     `json:"order_ids"`
+	// PlacingClientOrderID is the client order id of an order being placed; it is set and saved before the order is
+	// sent, so a restart can look the order up instead of placing it again
+	PlacingClientOrderID string `json:"placing_client_order_id,omitempty"`
+	// OrderPlacedAt is when the current order was placed; OrderExecuted and OrderSpent are its fills already counted
+	OrderPlacedAt time.Time `json:"order_placed_at,omitempty"`
+	OrderExecuted float64   `json:"order_executed,omitempty"`
+	OrderSpent    float64   `json:"order_spent,omitempty"`
+}
+
+func (e *DCAExecution) currentOrder() (int, bool) {
+	if len(e.OrderIDs) == 0 {
+		return 0, false
+	}
+	return e.OrderIDs[len(e.OrderIDs)-1], true
+}
+
+// DCA buys a fixed quote amount of each configured symbol on a schedule, with maker-or-cancel limit orders at (or
+// just below) the bid. Orders rejected for crossing or left unfilled past RetryAfter are placed again at the new bid,
+// for the remaining budget, until MaxAttempts. Every run is recorded in the history file before its first order is
+// placed, so a restarted strategy neither repeats a run nor catches up on runs missed while it was down, apart from
+// the most recent one. Every order is recorded too, under a client order id, before it is sent, so an order whose
+// response was lost is looked up rather than placed twice.
+type DCA struct {
+	config   DCAConfig
+	schedule *Schedule
+	budgets  map[string]float64
+	options  options
+	started  time.Time
+
+	mu      sync.Mutex
+	history []*DCAExecution
+}
+
+var _ bot.Strategy = (*DCA)(nil)
+
+func init() {
+	bot.Register("dca", func(raw json.RawMessage) (bot.Strategy, error) {
+		var config DCAConfig
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, errors.New("error parsing dca config: " + err.Error())
+		}
+		return NewDCA(config)
+	})
+}
+
+func NewDCA(config DCAConfig, opts ...Option) (*DCA, error) {
+	/*
+		Create a DCA strategy, loading its execution history if the history file exists
+
+		Args:
+		config (DCAConfig): schedule, buys and order settings
+		opts (...Option): optional behavior, e.g. WithClock
+
+		Returns a pointer to the DCA strategy, or an error if the configuration or history file is invalid
+	*/
+	schedule, err := ParseSchedule(config.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	if config.TimeZone != "" {
+		location, err := time.LoadLocation(config.TimeZone)
+		if err != nil {
+			return nil, errors.New("dca config: " + err.Error())
+		}
+		schedule = schedule.In(location)
+	}
+	if len(config.Buys) == 0 {
+		return nil, errors.New("dca config: no buys")
+	}
+	budgets := map[string]float64{}
+	for _, buy := range config.Buys {
+		if buy.Amount <= 0 {
+			return nil, errors.New("dca config: amount of " + buy.Symbol + " must be positive")
+		}
+		// ticks carry lowercase symbols, as the bot config lowercases them
+		budgets[strings.ToLower(buy.Symbol)] = buy.Amount
+	}
+	if config.PriceIncrement == 0 {
+		config.PriceIncrement = 0.01
+	}
+	if config.AmountIncrement == 0 {
+		config.AmountIncrement = 1e-8
+	}
+	if config.RetryAfter.Duration == 0 {
+		config.RetryAfter.Duration = time.Minute
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = 10
+	}
+	d := &DCA{config: config, schedule: schedule, budgets: budgets, options: newOptions("dca", opts)}
+	d.started = d.options.now()
+	if config.HistoryPath != "" {
+		data, err := os.ReadFile(config.HistoryPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.New("error reading dca history: " + err.Error())
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &d.history); err != nil {
+				return nil, errors.New("error parsing dca history: " + err.Error())
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *DCA) History() []DCAExecution {
+	/*
+		Get a copy of the execution history, oldest first
+	*/
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	history := make([]DCAExecution, len(d.history))
+	for i, execution := range d.history {
+		history[i] = *execution
+	}
+	return history
+}
+
+func (d *DCA) OnTick(trader private.Trader, tick bot.Tick) error {
+	/*
+		Start the symbol's run if one is due, and advance its pending run: account for fills, then retry a rejected
+		or stale order at the current bid
+	*/
+	budget, ok := d.budgets[tick.Symbol]
+	if !ok {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.options.now()
+
+	execution := d.last(tick.Symbol)
+	since := d.started
+	if execution != nil {
+		since = execution.Slot
+	}
+	if slot, due := d.schedule.latest(since, now); due {
+		if execution != nil && execution.Status == DCAPending {
+			if err := d.abandon(trader, execution); err != nil {
+				return err
+			}
+		}
+		execution = &DCAExecution{Symbol: tick.Symbol, Slot: slot, Budget: budget, Status: DCAPending}
+		d.history = append(d.history, execution)
+		d.options.logger.Info("dca run due", "symbol", tick.Symbol, "slot", slot, "budget", budget)
+		if err := d.save(); err != nil {
+			return err
+		}
+	}
+	if execution == nil || execution.Status != DCAPending {
+		return nil
+	}
+	err := d.advance(trader, tick, execution, now)
+	if saveErr := d.save(); err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// advance accounts for the current order of a pending run and places a new one when it is no longer working
+func (d *DCA) advance(trader private.Trader, tick bot.Tick, execution *DCAExecution, now time.Time) error {
+	if execution.PlacingClientOrderID != "" {
+		if err := d.recover(trader, execution, now); err != nil {
+			return err
+		}
+	}
+	if orderID, ok := execution.currentOrder(); ok {
+		order, err := trader.GetOrderStatus(orderID)
+		if err != nil {
+			return err
+		}
+		d.account(execution, order)
+		if order.IsLive {
+			if now.Sub(execution.OrderPlacedAt) < d.config.RetryAfter.Duration {
+				return nil
+			}
+			if order, err = trader.CancelOrder(orderID); err != nil {
+				return err
+			}
+			d.account(execution, order)
+			if order.IsLive {
+				return nil
+			}
+		}
+	}
+
+	price := util.FloorTo(tick.Bid*(1-d.config.BidOffset), d.config.PriceIncrement)
+	remaining := execution.Budget - execution.Spent
+	amount := 0.0
+	if price > 0 {
+		amount = util.FloorTo(remaining/price, d.config.AmountIncrement)
+	}
+	if amount <= 0 || amount < d.config.MinOrderSize || remaining < d.config.PriceIncrement {
+		execution.Status = DCAFilled
+		d.options.logger.Info("dca run complete", "symbol", execution.Symbol, "spent", execution.Spent, "bought", execution.Bought)
+		return nil
+	}
+	if execution.Attempts >= d.config.MaxAttempts {
+		execution.Status = DCAFailed
+		d.options.logger.Warn("dca run failed", "symbol", execution.Symbol, "attempts", execution.Attempts, "spent", execution.Spent)
+		return nil
+	}
+
+	// record the attempt before sending it: if the response is lost, the order is found by its client order id
+	execution.Attempts++
+	execution.PlacingClientOrderID = fmt.Sprintf("dca-%s-%d-%d", execution.Symbol, execution.Slot.Unix(), execution.Attempts)
+	if err := d.save(); err != nil {
+		return err
+	}
+	order, err := trader.LimitOrder("buy", execution.Symbol, amount, price, execution.PlacingClientOrderID, "maker-or-cancel")
+	if err != nil {
+		return fmt.Errorf("dca order for %s failed: %w", execution.Symbol, err)
+	}
+	if err := d.track(execution, order, now); err != nil {
+		return err
+	}
+	d.options.logger.Info("dca order placed", "symbol", execution.Symbol, "order_id", order.OrderID, "amount", amount, "price", price, "attempt", execution.Attempts)
+	return nil
+}
+
+// track makes a placed order the current order of the run
+func (d *DCA) track(execution *DCAExecution, order *private.Order, placedAt time.Time) error {
+	orderID, err := strconv.Atoi(order.OrderID)
+	if err != nil {
+		return errors.New("dca order has non-numeric id " + order.OrderID)
+	}
+	execution.PlacingClientOrderID = ""
+	execution.OrderIDs = append(execution.OrderIDs, orderID)
+	execution.OrderPlacedAt = placedAt
+	execution.OrderExecuted = 0
+	execution.OrderSpent = 0
+	d.account(execution, order)
+	return nil
+}
+
+// recover resolves an order whose placement was interrupted: if the exchange accepted it, it becomes the current
+// order of the run; otherwise the attempt is counted and the next one may be placed
+func (d *DCA) recover(trader private.Trader, execution *DCAExecution, now time.Time) error {
+	active, err := trader.GetActiveOrders()
+	if err != nil {
+		return fmt.Errorf("looking up dca order %s failed: %w", execution.PlacingClientOrderID, err)
+	}
+	closed, err := trader.GetClosedOrdersHistory()
+	if err != nil {
+		return fmt.Errorf("looking up dca order %s failed: %w", execution.PlacingClientOrderID, err)
+	}
+	for _, order := range append(active, closed...) {
+		if order.ClientOrderID != execution.PlacingClientOrderID {
+			continue
+		}
+		placedAt := now
+		if order.TimestampMs > 0 {
+			placedAt = time.UnixMilli(order.TimestampMs)
+		}
+		d.options.logger.Info("dca order recovered", "symbol", execution.Symbol, "order_id", order.OrderID, "client_order_id", execution.PlacingClientOrderID)
+		return d.track(execution, &order, placedAt)
+	}
+	d.options.logger.Warn("dca order was not placed", "symbol", execution.Symbol, "client_order_id", execution.PlacingClientOrderID)
+	execution.PlacingClientOrderID = ""
+	return nil
+}
+
+// abandon cancels the current order of a run superseded by the next one and marks the run failed
+func (d *DCA) abandon(trader private.Trader, execution *DCAExecution) error {
+	if execution.PlacingClientOrderID != "" {
+		if err := d.recover(trader, execution, d.options.now()); err != nil {
+			return err
+		}
+	}
+	if orderID, ok := execution.currentOrder(); ok {
+		order, err := trader.CancelOrder(orderID)
+		if err != nil {
+			return err
+		}
+		d.account(execution, order)
+	}
+	execution.Status = DCAFailed
+	d.options.logger.Warn("dca run superseded by the next one", "symbol", execution.Symbol, "slot", execution.Slot, "spent", execution.Spent)
+	return nil
+}
+
+// account adds the newly executed part of the run's current order to the run
+func (d *DCA) account(execution *DCAExecution, order *private.Order) {
+	executed, _ := strconv.ParseFloat(order.ExecutedAmount, 64)
+	price, _ := strconv.ParseFloat(order.AvgExecutionPrice, 64)
+	if executed <= execution.OrderExecuted {
+		return
+	}
+	// the average price covers the whole order, so replace its previous contribution
+	execution.Spent += executed*price - execution.OrderSpent
+	execution.Bought += executed - execution.OrderExecuted
+	execution.OrderExecuted = executed
+	execution.OrderSpent = executed * price
+}
+
+func (d *DCA) OnCandle(trader private.Trader, candle bot.Candle) error {
+	return nil
+}
+
+func (d *DCA) OnFill(trader private.Trader, fill bot.Fill) error {
+	// fills are accounted for from order status on the next tick
+	return nil
+}
+
+func (d *DCA) last(symbol string) *DCAExecution {
+	for i := len(d.history) - 1; i >= 0; i-- {
+		if d.history[i].Symbol == symbol {
+			return d.history[i]
+		}
+	}
+	return nil
+}
+
+// save writes the history file atomically
+func (d *DCA) save() error {
+	if d.config.HistoryPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(d.history, "", "  ")
+	if err != nil {
+		return errors.New("error encoding dca history: " + err.Error())
+	}
+	if err := util.WriteFileAtomic(d.config.HistoryPath, data, 0o600); err != nil {
+		return errors.New("error writing dca history: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/bot"
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/papertest"
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+// quoteTick is the current quote of market as a bot tick
+func quoteTick(market *papertest.Market, symbol string) bot.Tick {
+	bid, ask := market.Quote()
+	return bot.Tick{Symbol: symbol, Bid: bid, Ask: ask, Last: bid}
+}
+
+func TestDCA(t *testing.T) {
+	market := papertest.NewMarket(99, 100)
+	trader := paper.NewTrader(market, map[string]float64{"USD": 1000}, paper.WithFees(0, 0))
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	clock := WithClock(func() time.Time { return now })
+	config := DCAConfig{
+		Schedule:    "0 9 * * *",
+		Buys:        []DCABuy{{Symbol: "BTCUSD", Amount: 100}}, // ticks carry lowercase symbols whatever the case configured
+		HistoryPath: filepath.Join(t.TempDir(), "dca.json"),
+		MaxAttempts: 3,
+	}
+	dca, err := NewDCA(config, clock)
+	if err != nil {
+		t.Fatalf("NewDCA failed: %v", err)
+	}
+	tick := func() {
+		t.Helper()
+		if err := dca.OnTick(trader, quoteTick(market, "btcusd")); err != nil {
+			t.Fatalf("OnTick failed: %v", err)
+		}
+	}
+
+	tick()
+	if len(dca.History()) != 0 {
+		t.Fatalf("no run should be due before 09:00: %+v", dca.History())
+	}
+
+	// the run places a maker-or-cancel order at the bid
+	now = now.Add(time.Hour + 30*time.Second)
+	tick()
+	history := dca.History()
+	if len(history) != 1 || history[0].Status != DCAPending || len(history[0].OrderIDs) != 1 {
+		t.Fatalf("history %+v", history)
+	}
+	order, _ := trader.GetOrderStatus(history[0].OrderIDs[0])
+	if order.Price != "99" || order.OriginalAmount != "1.01010101" || order.Options[0] != "maker-or-cancel" {
+		t.Errorf("order %+v", order)
+	}
+
+	// the market runs away: the stale order is cancelled and replaced at the new bid, where it fills
+	market.Set(101, 102)
+	now = now.Add(2 * time.Minute)
+	tick()
+	market.Set(100, 100.5)
+	tick()
+	history = dca.History()
+	if history[0].Status != DCAFilled || history[0].Attempts != 2 || !papertest.Near(history[0].Bought, 0.99009900) || history[0].Spent > 100 {
+		t.Fatalf("run should fill on the second order: %+v", history[0])
+	}
+
+	// a restarted strategy does not repeat the run
+	restarted, err := NewDCA(config, clock)
+	if err != nil {
+		t.Fatalf("NewDCA with history failed: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if err := restarted.OnTick(trader, quoteTick(market, "btcusd")); err != nil {
+		t.Fatalf("OnTick failed: %v", err)
+	}
+	if len(restarted.History()) != 1 {
+		t.Errorf("restart should not double-buy: %+v", restarted.History())
+	}
+
+	// orders rejected for crossing are retried until MaxAttempts
+	dca = restarted
+	market.Set(100, 100)
+	now = time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		tick()
+	}
+	history = dca.History()
+	if len(history) != 2 || history[1].Status != DCAFailed || history[1].Attempts != 3 || history[1].Spent != 0 {
+		t.Errorf("run should fail after 3 rejected orders: %+v", history[1])
+	}
+	if usd := balance(t, trader, "USD"); !papertest.Near(usd, 1000-history[0].Spent) {
+		t.Errorf("USD balance %v", usd)
+	}
+}
+
+// lostResponseTrader places the next limit order but reports a timeout, as when the response is lost
+type lostResponseTrader struct {
+	*paper.Trader
+	lose bool
+}
+
+func (t *lostResponseTrader) LimitOrder(side string, symbol string, amount float64, limitPrice float64, clientOrderID string, options ...string) (*private.Order, error) {
+	order, err := t.Trader.LimitOrder(side, symbol, amount, limitPrice, clientOrderID, options...)
+	if err == nil && t.lose {
+		t.lose = false
+		return nil, errors.New("context deadline exceeded")
+	}
+	return order, err
+}
+
+func TestDCALostOrderResponse(t *testing.T) {
+	market := papertest.NewMarket(99, 100)
+	trader := &lostResponseTrader{Trader: paper.NewTrader(market, map[string]float64{"USD": 1000}, paper.WithFees(0, 0)), lose: true}
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	clock := WithClock(func() time.Time { return now })
+	config := DCAConfig{
+		Schedule:    "0 9 * * *",
+		Buys:        []DCABuy{{Symbol: "btcusd", Amount: 100}},
+		HistoryPath: filepath.Join(t.TempDir(), "dca.json"),
+	}
+	dca, err := NewDCA(config, clock)
+	if err != nil {
+		t.Fatalf("NewDCA failed: %v", err)
+	}
+	now = now.Add(time.Hour + 30*time.Second)
+	if err := dca.OnTick(trader, quoteTick(market, "btcusd")); err == nil {
+		t.Fatal("expected the lost response to be reported")
+	}
+
+	// a restarted strategy finds the order by its client order id instead of buying again
+	restarted, err := NewDCA(config, clock)
+	if err != nil {
+		t.Fatalf("NewDCA with history failed: %v", err)
+	}
+	history := restarted.History()
+	if len(history) != 1 || history[0].PlacingClientOrderID != "dca-btcusd-1704099600-1" || len(history[0].OrderIDs) != 0 {
+		t.Fatalf("persisted history %+v", history)
+	}
+	if err := restarted.OnTick(trader, quoteTick(market, "btcusd")); err != nil {
+		t.Fatalf("OnTick failed: %v", err)
+	}
+	active, _ := trader.GetActiveOrders()
+	history = restarted.History()
+	if len(active) != 1 || len(history[0].OrderIDs) != 1 || history[0].OrderIDs[0] != mustAtoi(t, active[0].OrderID) ||
+		history[0].Attempts != 1 || history[0].PlacingClientOrderID != "" {
+		t.Errorf("recovered history %+v, active orders %+v", history, active)
+	}
+}
+
+func mustAtoi(t *testing.T, value string) int {
+	t.Helper()
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		t.Fatalf("Atoi(%q) failed: %v", value, err)
+	}
+	return number
+}
+
+func TestNewFromBotConfig(t *testing.T) {
+	strategy, err := bot.NewStrategy("dca", []byte(`{"schedule": "0 9 * * 1", "buys": [{"symbol": "ethusd", "amount": 25}]}`))
+	if err != nil {
+		t.Fatalf("NewStrategy failed: %v", err)
+	}
+	if _, ok := strategy.(*DCA); !ok {
+		t.Errorf("dca registered %T", strategy)
+	}
+	if _, err := bot.NewStrategy("dca", []byte(`{"schedule": "0 9 * *", "buys": [{"symbol": "ethusd", "amount": 25}]}`)); err == nil {
+		t.Errorf("invalid schedules should be rejected")
+	}
+}
+
+func balance(t *testing.T, trader *paper.Trader, currency string) float64 {
+	t.Helper()
+	balances, err := trader.GetAvailableBalances()
+	if err != nil {
+		t.Fatalf("GetAvailableBalances failed: %v", err)
+	}
+	for _, b := range balances {
+		if b.Currency == currency {
+			var amount float64
+			fmt.Sscan(b.Amount, &amount)
+			return amount
+		}
+	}
+	return 0
+}
@@ -0,0 +1,320 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/austinjhunt/go-gemini/bot"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/austinjhunt/go-gemini/public"
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// GridConfig configures a grid strategy
+type GridConfig struct {
+	Symbol string `json:"symbol"`
+	// Lower and Upper bound the ladder of Levels evenly spaced prices (at least 2)
+	Lower  float64 `json:"lower"`
+	Upper  float64 `json:"upper"`
+	Levels int     `json:"levels"`
+	// Amount is the base currency amount of every grid order
+	Amount float64 `json:"amount"`
+	// FeeRate is deducted from both legs of every round trip when computing realized profit
+	FeeRate float64 `json:"fee_rate"`
+	// TickSize (amount increment), QuoteIncrement (price increment) and MinOrderSize are read from the symbol details
+	// unless set
+	TickSize       float64 `json:"tick_size"`
+	QuoteIncrement float64 `json:"quote_increment"`
+	MinOrderSize   float64 `json:"min_order_size"`
+}
+
+// GridReport summarizes a grid's trading. RealizedProfit is in the quote currency, net of FeeRate.
+type GridReport struct {
+	RealizedProfit float64
+	Fees           float64
+	RoundTrips     int
+	OpenOrders     int
+}
+
+type gridOrder struct {
+	level  int
+	side   string
+	amount float64
+	// entry is the fill price of the order this one closes, or 0 for the initial ladder
+	entry float64
+}
+
+// Grid lays a ladder of limit orders between two prices: buys below the market and sells above it. When a buy fills,
+// a sell is placed one level up; when a sell fills, a buy is placed one level down, and each such pair completes a
+// round trip earning the level spacing. It works with any private.Trader, paper or live.
+type Grid struct {
+	config  GridConfig
+	options options
+
+	mu      sync.Mutex
+	prices  []float64
+	orders  map[int]*gridOrder
+	report  GridReport
+	started bool
+}
+
+var _ bot.Strategy = (*Grid)(nil)
+
+func init() {
+	bot.Register("grid", func(raw json.RawMessage) (bot.Strategy, error) {
+		var config GridConfig
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, errors.New("error parsing grid config: " + err.Error())
+		}
+		return NewGrid(config)
+	})
+}
+
+func NewGrid(config GridConfig, opts ...Option) (*Grid, error) {
+	/*
+		Create a grid strategy. The ladder is laid out on the first tick of its symbol, after reading the symbol's
+		tick size, quote increment and minimum order size if they are not configured.
+	*/
+	if config.Symbol == "" {
+		return nil, errors.New("grid config: no symbol")
+	}
+	if config.Levels < 2 {
+		return nil, errors.New("grid config: at least 2 levels are required")
+	}
+	if config.Lower <= 0 || config.Upper <= config.Lower {
+		return nil, errors.New("grid config: bounds must satisfy 0 < lower < upper")
+	}
+	if config.Amount <= 0 {
+		return nil, errors.New("grid config: amount must be positive")
+	}
+	return &Grid{config: config, options: newOptions("grid", opts), orders: map[int]*gridOrder{}}, nil
+}
+
+func (g *Grid) Report() GridReport {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	report := g.report
+	report.OpenOrders = len(g.orders)
+	return report
+}
+
+func (g *Grid) Prices() []float64 {
+	/*
+		Get the grid's price levels, lowest first, once the ladder has been laid out
+	*/
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]float64(nil), g.prices...)
+}
+
+func (g *Grid) OnTick(trader private.Trader, tick bot.Tick) error {
+	/*
+		Lay out the ladder on the first tick, afterwards re-place the opposite side of filled orders
+	*/
+	if tick.Symbol != g.config.Symbol {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.started {
+		if err := g.layout(); err != nil {
+			return err
+		}
+		g.started = true
+		return g.placeLadder(trader, tick)
+	}
+	return g.sync(trader)
+}
+
+func (g *Grid) OnCandle(trader private.Trader, candle bot.Candle) error {
+	return nil
+}
+
+func (g *Grid) OnFill(trader private.Trader, fill bot.Fill) error {
+	if fill.Symbol != g.config.Symbol {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.sync(trader)
+}
+
+// layout reads missing symbol details and computes the level prices, rounded to the quote increment
+func (g *Grid) layout() error {
+	if g.config.TickSize == 0 || g.config.QuoteIncrement == 0 || g.config.MinOrderSize == 0 {
+		var details map[string]interface{}
+		if err := public.GetPublicEndpoint("/v1/symbols/details/"+g.config.Symbol, &details); err != nil {
+			return errors.New("error reading grid symbol details: " + err.Error())
+		}
+		for key, target := range map[string]*float64{
+			"tick_size": &g.config.TickSize, "quote_increment": &g.config.QuoteIncrement, "min_order_size": &g.config.MinOrderSize,
+		} {
+			if *target != 0 {
+				continue
+			}
+			value, err := detailFloat(details[key])
+			if err != nil {
+				return errors.New("error reading grid symbol " + key + ": " + err.Error())
+			}
+			*target = value
+		}
+	}
+	g.config.Amount = util.FloorTo(g.config.Amount, g.config.TickSize)
+	if g.config.Amount < g.config.MinOrderSize {
+		return fmt.Errorf("grid amount %v is below the minimum order size %v of %s", g.config.Amount, g.config.MinOrderSize, g.config.Symbol)
+	}
+	step := (g.config.Upper - g.config.Lower) / float64(g.config.Levels-1)
+	g.prices = make([]float64, g.config.Levels)
+	for i := range g.prices {
+		g.prices[i] = util.FloorTo(g.config.Lower+float64(i)*step, g.config.QuoteIncrement)
+	}
+	for i := 1; i < len(g.prices); i++ {
+		if g.prices[i] <= g.prices[i-1] {
+			return errors.New("grid levels are closer than the quote increment")
+		}
+	}
+	return nil
+}
+
+func detailFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unexpected value %v", value)
+	}
+}
+
+// placeLadder places buys at the levels below the bid and sells at the levels above the ask. The level nearest the
+// market is left empty even if it is outside the spread, so the first fill has a free level for its opposite order.
+func (g *Grid) placeLadder(trader private.Trader, tick bot.Tick) error {
+	mid := (tick.Bid + tick.Ask) / 2
+	empty := 0
+	for level, price := range g.prices {
+		if math.Abs(price-mid) < math.Abs(g.prices[empty]-mid) {
+			empty = level
+		}
+	}
+	var errs []error
+	for level, price := range g.prices {
+		side := ""
+		switch {
+		case level == empty:
+			continue
+		case price < tick.Bid:
+			side = "buy"
+		case price > tick.Ask:
+			side = "sell"
+		default:
+			continue
+		}
+		if err := g.place(trader, level, side, 0); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	g.options.logger.Info("grid laid out", "symbol", g.config.Symbol, "levels", g.prices, "open_orders", len(g.orders))
+	return errors.Join(errs...)
+}
+
+func (g *Grid) place(trader private.Trader, level int, side string, entry float64) error {
+	price := g.prices[level]
+	var order *private.Order
+	var err error
+	if side == "buy" {
+		order, err = trader.LimitBuy(g.config.Symbol, g.config.Amount, price)
+	} else {
+		order, err = trader.LimitSell(g.config.Symbol, g.config.Amount, price)
+	}
+	if err != nil {
+		return fmt.Errorf("grid %s at %v failed: %w", side, price, err)
+	}
+	id, err := strconv.Atoi(order.OrderID)
+	if err != nil {
+		return errors.New("grid order has non-numeric id " + order.OrderID)
+	}
+	g.orders[id] = &gridOrder{level: level, side: side, amount: g.config.Amount, entry: entry}
+	return nil
+}
+
+// sync re-places the opposite side of every filled order and forgets cancelled ones, booking what they executed
+func (g *Grid) sync(trader private.Trader) error {
+	var errs []error
+	// forget every closed order before re-placing any, so the levels they free count as free
+	closed := map[int]*private.Order{}
+	for id := range g.orders {
+		order, err := trader.GetOrderStatus(id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !order.IsLive {
+			closed[id] = order
+		}
+	}
+	forgotten := map[int]*gridOrder{}
+	for id := range closed {
+		forgotten[id] = g.orders[id]
+		delete(g.orders, id)
+	}
+	for id, order := range closed {
+		tracked := forgotten[id]
+		price, _ := strconv.ParseFloat(order.AvgExecutionPrice, 64)
+		if order.IsCancelled {
+			executed, _ := strconv.ParseFloat(order.ExecutedAmount, 64)
+			if executed > 0 {
+				g.book(tracked, executed, price)
+			}
+			g.options.logger.Warn("grid order cancelled outside the grid", "order_id", id, "level", tracked.level, "executed", executed)
+			continue
+		}
+		g.book(tracked, tracked.amount, price)
+		if tracked.entry > 0 {
+			g.report.RoundTrips++
+		}
+		g.options.logger.Info("grid order filled", "order_id", id, "side", tracked.side, "price", price, "realized_profit", g.report.RealizedProfit)
+
+		next, side := tracked.level+1, "sell"
+		if tracked.side == "sell" {
+			next, side = tracked.level-1, "buy"
+		}
+		if next < 0 || next >= len(g.prices) {
+			continue
+		}
+		if g.occupied(next) {
+			g.options.logger.Warn("grid level already has an order, skipping", "level", next, "price", g.prices[next], "side", side)
+			continue
+		}
+		if err := g.place(trader, next, side, price); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// book adds the fees of amount executed at price and, if the order closes a round trip, its realized profit
+func (g *Grid) book(tracked *gridOrder, amount float64, price float64) {
+	g.report.Fees += g.config.FeeRate * amount * price
+	if tracked.entry > 0 {
+		gross := amount * (price - tracked.entry)
+		if tracked.side == "buy" {
+			gross = -gross
+		}
+		g.report.RealizedProfit += gross - g.config.FeeRate*amount*(price+tracked.entry)
+	}
+}
+
+// occupied reports whether the grid has an open order at level
+func (g *Grid) occupied(level int) bool {
+	for _, tracked := range g.orders {
+		if tracked.level == level {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package strategy
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/austinjhunt/go-gemini/bot"
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/paper"
+	"github.com/austinjhunt/go-gemini/papertest"
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+// runGrid drives a 5 level grid through one buy-then-sell round trip
+func runGrid(t *testing.T, trader private.Trader, setQuote func(bid float64, ask float64)) {
+	grid, err := NewGrid(GridConfig{Symbol: "btcusd", Lower: 29000, Upper: 31000, Levels: 5, Amount: 0.0100000001, FeeRate: 0.001})
+	if err != nil {
+		t.Fatalf("NewGrid failed: %v", err)
+	}
+	tick := func(bid float64, ask float64) {
+		t.Helper()
+		setQuote(bid, ask)
+		if err := grid.OnTick(trader, bot.Tick{Symbol: "btcusd", Bid: bid, Ask: ask}); err != nil {
+			t.Fatalf("OnTick failed: %v", err)
+		}
+	}
+
+	tick(29990, 30010)
+	if prices := grid.Prices(); len(prices) != 5 || prices[1] != 29500 || prices[3] != 30500 {
+		t.Fatalf("grid prices %v", prices)
+	}
+	if report := grid.Report(); report.OpenOrders != 4 {
+		t.Fatalf("ladder should have 2 buys and 2 sells around the market: %+v", report)
+	}
+
+	// the buy at 29500 fills and is replaced by a sell at 30000
+	tick(29400, 29450)
+	tick(29400, 29450)
+	if report := grid.Report(); report.OpenOrders != 4 || report.RoundTrips != 0 {
+		t.Fatalf("report after buy fill %+v", report)
+	}
+
+	// the sell at 30000 fills, completing a round trip, and the buy at 29500 is placed again
+	tick(30050, 30100)
+	tick(30050, 30100)
+	report := grid.Report()
+	want := 0.01*500 - 0.001*0.01*(30000+29500)
+	if report.RoundTrips != 1 || math.Abs(report.RealizedProfit-want) > 1e-9 || report.OpenOrders != 4 {
+		t.Errorf("report after round trip %+v, want profit %v", report, want)
+	}
+}
+
+func TestGridPaper(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	t.Setenv("GEMINI_EXCHANGE_API_BASE_URL", server.URL)
+	trader := paper.NewTrader(&paper.LiveMarketData{}, map[string]float64{"USD": 100000, "BTC": 1})
+	runGrid(t, trader, func(bid float64, ask float64) { server.SetQuote("btcusd", bid, ask) })
+}
+
+func TestGridLive(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	t.Setenv("GEMINI_EXCHANGE_API_BASE_URL", server.URL)
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	runGrid(t, client, func(bid float64, ask float64) { server.SetQuote("btcusd", bid, ask) })
+}
+
+// cancelledFillTrader reports the executed amount of orders cancelled outside the grid
+type cancelledFillTrader struct {
+	*paper.Trader
+	executed map[int]string
+}
+
+func (c *cancelledFillTrader) GetOrderStatus(orderID int) (*private.Order, error) {
+	order, err := c.Trader.GetOrderStatus(orderID)
+	if err == nil && order.IsCancelled && c.executed[orderID] != "" {
+		order.ExecutedAmount, order.AvgExecutionPrice = c.executed[orderID], order.Price
+	}
+	return order, err
+}
+
+func TestGridLevels(t *testing.T) {
+	market := papertest.NewMarket(29900, 29950)
+	trader := &cancelledFillTrader{Trader: paper.NewTrader(market, map[string]float64{"USD": 100000, "BTC": 1}, paper.WithFees(0, 0)), executed: map[int]string{}}
+	grid, err := NewGrid(GridConfig{Symbol: "btcusd", Lower: 29000, Upper: 31000, Levels: 5, Amount: 0.01, FeeRate: 0.001, TickSize: 1e-8, QuoteIncrement: 0.01, MinOrderSize: 1e-5})
+	if err != nil {
+		t.Fatalf("NewGrid failed: %v", err)
+	}
+	tick := func() {
+		t.Helper()
+		if err := grid.OnTick(trader, quoteTick(market, "btcusd")); err != nil {
+			t.Fatalf("OnTick failed: %v", err)
+		}
+	}
+
+	// no level lies within the spread, so the one nearest the market is left empty for the first fill
+	tick()
+	active, _ := trader.GetActiveOrders()
+	if len(active) != 4 {
+		t.Fatalf("ladder should leave one level empty: %+v", active)
+	}
+	for _, order := range active {
+		if order.Price == "30000" {
+			t.Errorf("level 30000 nearest the market should be empty: %+v", order)
+		}
+	}
+
+	// the buy at 29500 fills and its sell takes the empty level
+	market.Set(29400, 29450)
+	tick()
+	tick()
+	if report := grid.Report(); report.OpenOrders != 4 {
+		t.Fatalf("report after buy fill %+v", report)
+	}
+
+	// an order cancelled elsewhere after partially filling has its fills booked
+	for _, order := range active {
+		if order.Price == "29000" {
+			id, _ := strconv.Atoi(order.OrderID)
+			trader.Trader.CancelOrder(id)
+			trader.executed[id] = "0.004"
+		}
+	}
+	tick()
+	want := 0.001*0.01*29500 + 0.001*0.004*29000
+	if report := grid.Report(); report.OpenOrders != 3 || math.Abs(report.Fees-want) > 1e-9 {
+		t.Errorf("report after partially filled cancel %+v, want fees %v", report, want)
+	}
+}
+
+func TestGridConfig(t *testing.T) {
+	for _, config := range []GridConfig{
+		{Lower: 1, Upper: 2, Levels: 2, Amount: 1},
+		{Symbol: "btcusd", Lower: 2, Upper: 1, Levels: 2, Amount: 1},
+		{Symbol: "btcusd", Lower: 1, Upper: 2, Levels: 1, Amount: 1},
+		{Symbol: "btcusd", Lower: 1, Upper: 2, Levels: 2},
+	} {
+		if _, err := NewGrid(config); err == nil {
+			t.Errorf("NewGrid(%+v) should fail", config)
+		}
+	}
+	grid, _ := NewGrid(GridConfig{Symbol: "btcusd", Lower: 1, Upper: 2, Levels: 2, Amount: 0.5, TickSize: 1e-8, QuoteIncrement: 0.01, MinOrderSize: 1})
+	if err := grid.OnTick(nil, bot.Tick{Symbol: "btcusd", Bid: 1.5, Ask: 1.5}); err == nil {
+		t.Errorf("amounts below the minimum order size should be rejected")
+	}
+}
@@ -0,0 +1,131 @@
+package strategy
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron-like schedule with the five standard fields: minute, hour, day of month, month and day of week
+// (0 or 7 is Sunday). Fields accept *, numbers, ranges (1-5), lists (1,15) and steps (*/15, 0-30/10). As in cron,
+// when both day of month and day of week are restricted a day matching either is scheduled.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+	location                      *time.Location
+}
+
+func ParseSchedule(spec string) (*Schedule, error) {
+	/*
+		Parse a five field cron expression, e.g. "0 9 * * 1" for 09:00 UTC every Monday. Times are in UTC; see In.
+	*/
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.New("schedule " + strconv.Quote(spec) + " must have 5 fields")
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, errors.New("schedule " + strconv.Quote(spec) + ": " + err.Error())
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1 // 7 is also Sunday
+	}
+	return &Schedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domStar: fields[2] == "*", dowStar: fields[4] == "*",
+		location: time.UTC,
+	}, nil
+}
+
+func parseField(field string, min int, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, errors.New("invalid step in " + strconv.Quote(part))
+			}
+			rangePart = part[:i]
+		}
+		low, high := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, errors.New("invalid value in " + strconv.Quote(part))
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, errors.New("invalid range in " + strconv.Quote(part))
+				}
+			} else if strings.Contains(part, "/") {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, errors.New(strconv.Quote(part) + " is out of range " + strconv.Itoa(min) + "-" + strconv.Itoa(max))
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (s *Schedule) In(location *time.Location) *Schedule {
+	/*
+		Get a copy of the schedule evaluated in the given time zone
+	*/
+	copy := *s
+	copy.location = location
+	return &copy
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (s *Schedule) Next(after time.Time) time.Time {
+	/*
+		Get the first scheduled time strictly after the given time, or the zero time if there is none within five years
+	*/
+	t := after.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// latest returns the last scheduled time after since and not after now, if any
+func (s *Schedule) latest(since time.Time, now time.Time) (time.Time, bool) {
+	var due time.Time
+	for next := s.Next(since); !next.IsZero() && !next.After(now); next = s.Next(next) {
+		due = next
+	}
+	return due, !due.IsZero()
+}
@@ -0,0 +1,45 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	cases := []struct {
+		spec  string
+		after string
+		want  string
+	}{
+		{"*/15 * * * *", "2024-01-01T10:07:30Z", "2024-01-01T10:15:00Z"},
+		{"0 9 * * *", "2024-01-01T09:00:00Z", "2024-01-02T09:00:00Z"},
+		{"0 9 * * 1", "2024-01-02T00:00:00Z", "2024-01-08T09:00:00Z"},     // next Monday
+		{"30 8 1,15 * *", "2024-01-15T09:00:00Z", "2024-02-01T08:30:00Z"}, // day of month list
+		{"0 0 13 * 5", "2024-09-01T00:00:00Z", "2024-09-06T00:00:00Z"},    // day of month or Friday
+		{"0 12 29 2 *", "2024-03-01T00:00:00Z", "2028-02-29T12:00:00Z"},   // leap day
+		{"0 0 * * 7", "2024-01-01T00:00:00Z", "2024-01-07T00:00:00Z"},     // 7 is Sunday
+		{"0-10/5 23 * 12 *", "2024-12-31T23:11:00Z", "2025-12-01T23:00:00Z"},
+	}
+	for _, c := range cases {
+		schedule, err := ParseSchedule(c.spec)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q) failed: %v", c.spec, err)
+		}
+		if got := schedule.Next(at(c.after)); !got.Equal(at(c.want)) {
+			t.Errorf("%q after %s: got %s, want %s", c.spec, c.after, got, c.want)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) should fail", spec)
+		}
+	}
+}
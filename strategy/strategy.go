@@ -0,0 +1,48 @@
+// Package strategy contains built-in trading strategies for the bot runtime. Importing it registers them with
+// bot.Register under the names "dca" and "grid".
+package strategy
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/util"
+)
+
+// Option configures optional behavior of a built-in strategy
+type Option func(*options)
+
+type options struct {
+	now    func() time.Time
+	logger *slog.Logger
+}
+
+func WithClock(now func() time.Time) Option {
+	/*
+		Use the given clock instead of time.Now
+	*/
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	/*
+		Log through the given logger instead of util.Logger()
+	*/
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func newOptions(name string, opts []Option) options {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = util.Logger()
+	}
+	o.logger = o.logger.With("strategy", name)
+	return o
+}
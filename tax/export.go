@@ -0,0 +1,98 @@
+package tax
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Columns are the header of the CSV and XLSX exports
+var Columns = []string{"Currency", "Amount", "Date Acquired", "Date Sold", "Proceeds", "Cost Basis", "Gain", "Term", "Unknown Basis", "Trade ID"}
+
+func (d Disposal) row() []interface{} {
+	return []interface{}{
+		d.Currency, d.Amount, d.Acquired.UTC().Format(time.DateOnly), d.Disposed.UTC().Format(time.DateOnly),
+		round(d.Proceeds), round(d.CostBasis), round(d.Gain), d.Term, d.UnknownBasis, d.TradeID,
+	}
+}
+
+// round rounds to cents
+func round(value float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'f', 2, 64), 64)
+	return rounded
+}
+
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return ""
+}
+
+func (r *Report) WriteCSV(w io.Writer) error {
+	/*
+		Write the disposals as CSV, one row per lot disposed of, with a header row
+	*/
+	writer := csv.NewWriter(w)
+	writer.Write(Columns)
+	for _, d := range r.Disposals {
+		row := d.row()
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = format(value)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *Report) WriteXLSX(path string) error {
+	/*
+		Write the disposals to an XLSX workbook at path: a "Disposals" sheet like the CSV export, and a "Summary"
+		sheet with the method and totals
+	*/
+	file := excelize.NewFile()
+	defer file.Close()
+	file.SetSheetName(file.GetSheetName(0), "Disposals")
+	if err := file.SetSheetRow("Disposals", "A1", &Columns); err != nil {
+		return err
+	}
+	for i, d := range r.Disposals {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		row := d.row()
+		if err := file.SetSheetRow("Disposals", cell, &row); err != nil {
+			return err
+		}
+	}
+
+	if _, err := file.NewSheet("Summary"); err != nil {
+		return err
+	}
+	totals := r.Totals()
+	for i, row := range [][]interface{}{
+		{"Method", r.Method},
+		{"Currency", r.Currency},
+		{"Disposals", len(r.Disposals)},
+		{"Proceeds", round(totals.Proceeds)},
+		{"Cost Basis", round(totals.CostBasis)},
+		{"Short Term Gain", round(totals.ShortTermGain)},
+		{"Long Term Gain", round(totals.LongTermGain)},
+	} {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow("Summary", cell, &row); err != nil {
+			return err
+		}
+	}
+	return file.SaveAs(path)
+}
@@ -0,0 +1,307 @@
+// Package tax builds capital gains reports from past trades and transfers, matching disposals to acquisition lots
+// with the FIFO, LIFO or HIFO method, and writes them as CSV or XLSX.
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/private"
+)
+
+// Lot matching methods
+const (
+	// FIFO disposes of the oldest lot first
+	FIFO = "fifo"
+	// LIFO disposes of the newest lot first
+	LIFO = "lifo"
+	// HIFO disposes of the lot with the highest cost per unit first
+	HIFO = "hifo"
+)
+
+// Holding periods; long term means held for more than a year
+const (
+	ShortTerm = "short"
+	LongTerm  = "long"
+)
+
+// Config configures a report
+type Config struct {
+	// Method is FIFO, LIFO or HIFO (default FIFO)
+	Method string
+	// Currency is the fiat currency gains are reported in; trades quoted in other currencies are skipped (default "USD")
+	Currency string
+	// DepositCost gives the cost per unit of deposited crypto, whose basis the exchange doesn't know. When nil, or when
+	// it returns false, deposits get a zero basis and their disposals are flagged UnknownBasis
+	DepositCost func(transfer private.Transfer) (float64, bool)
+	// Year limits the disposals reported to a calendar year (UTC); 0 reports all. Earlier trades still build the lots
+	Year int
+}
+
+// Disposal is the sale of (part of) one lot
+type Disposal struct {
+	Currency     string
+	Amount       float64
+	Acquired     time.Time
+	Disposed     time.Time
+	Proceeds     float64
+	CostBasis    float64
+	Gain         float64
+	Term         string
+	UnknownBasis bool
+	// TradeID is the trade id of the sale
+	TradeID int64
+}
+
+// Lot is an acquisition that has not been (fully) disposed of
+type Lot struct {
+	Currency     string
+	Amount       float64
+	Acquired     time.Time
+	CostPerUnit  float64
+	UnknownBasis bool
+}
+
+// Report is a capital gains report
+type Report struct {
+	Method    string
+	Currency  string
+	Disposals []Disposal
+	// OpenLots are the lots left at the end of the history
+	OpenLots []Lot
+	// Skipped explains trades and transfers the report ignores
+	Skipped []string
+}
+
+// Totals sums the disposals of a report by holding period
+type Totals struct {
+	Proceeds      float64
+	CostBasis     float64
+	ShortTermGain float64
+	LongTermGain  float64
+}
+
+func (r *Report) Totals() Totals {
+	/*
+		Sum the proceeds, cost basis and short and long term gains of the disposals
+	*/
+	var totals Totals
+	for _, d := range r.Disposals {
+		totals.Proceeds += d.Proceeds
+		totals.CostBasis += d.CostBasis
+		if d.Term == LongTerm {
+			totals.LongTermGain += d.Gain
+		} else {
+			totals.ShortTermGain += d.Gain
+		}
+	}
+	return totals
+}
+
+// event is a trade or crypto transfer in the history
+type event struct {
+	time     time.Time
+	kind     string
+	currency string
+	amount   float64
+	// price is per unit in the report currency, fee in the report currency
+	price    float64
+	fee      float64
+	tradeID  int64
+	transfer *private.Transfer
+}
+
+func FromClient(client *private.Client, config Config) (*Report, error) {
+	/*
+		Build a capital gains report from the whole trade and transfer history of the account of client, paging through
+		both with GetAllPastTrades and GetAllTransfers. See Build
+	*/
+	trades, err := client.GetAllPastTrades("", 0)
+	if err != nil {
+		return nil, fmt.Errorf("tax: fetching trades: %w", err)
+	}
+	transfers, err := client.GetAllTransfers(0)
+	if err != nil {
+		return nil, fmt.Errorf("tax: fetching transfers: %w", err)
+	}
+	return Build(trades, transfers, config)
+}
+
+func Build(trades []private.PastTrade, transfers []private.Transfer, config Config) (*Report, error) {
+	/*
+		Build a capital gains report from the complete trade and transfer history of an account
+
+		Args:
+		trades ([]private.PastTrade): trades from GetAllPastTrades, in any order
+		transfers ([]private.Transfer): transfers from GetAllTransfers, in any order; crypto deposits add lots and
+			withdrawals remove lots without a gain
+		config (Config): lot method, report currency and deposit cost basis
+
+		Returns the report, or an error for an unknown method or malformed trade
+	*/
+	if config.Method == "" {
+		config.Method = FIFO
+	}
+	config.Method = strings.ToLower(config.Method)
+	if config.Method != FIFO && config.Method != LIFO && config.Method != HIFO {
+		return nil, errors.New("tax: unknown lot method " + config.Method)
+	}
+	if config.Currency == "" {
+		config.Currency = "USD"
+	}
+	config.Currency = strings.ToUpper(config.Currency)
+	report := &Report{Method: config.Method, Currency: config.Currency}
+
+	var events []event
+	for _, trade := range trades {
+		symbol := strings.ToUpper(trade.Symbol)
+		if !strings.HasSuffix(symbol, config.Currency) || len(symbol) == len(config.Currency) {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("trade %d on %s is not quoted in %s", trade.TID, symbol, config.Currency))
+			continue
+		}
+		e := event{time: tradeTime(trade), kind: strings.ToLower(trade.Type), currency: strings.TrimSuffix(symbol, config.Currency), tradeID: trade.TID}
+		var err error
+		if e.amount, err = strconv.ParseFloat(trade.Amount, 64); err != nil {
+			return nil, fmt.Errorf("tax: trade %d has invalid amount %q", trade.TID, trade.Amount)
+		}
+		if e.price, err = strconv.ParseFloat(trade.Price, 64); err != nil {
+			return nil, fmt.Errorf("tax: trade %d has invalid price %q", trade.TID, trade.Price)
+		}
+		if trade.FeeAmount != "" {
+			if e.fee, err = strconv.ParseFloat(trade.FeeAmount, 64); err != nil {
+				return nil, fmt.Errorf("tax: trade %d has invalid fee %q", trade.TID, trade.FeeAmount)
+			}
+			if trade.FeeCurrency != "" && !strings.EqualFold(trade.FeeCurrency, config.Currency) {
+				report.Skipped = append(report.Skipped, fmt.Sprintf("fee of trade %d is in %s, not included", trade.TID, trade.FeeCurrency))
+				e.fee = 0
+			}
+		}
+		if e.kind != "buy" && e.kind != "sell" {
+			return nil, fmt.Errorf("tax: trade %d has unknown type %q", trade.TID, trade.Type)
+		}
+		events = append(events, e)
+	}
+	for i := range transfers {
+		transfer := &transfers[i]
+		currency := strings.ToUpper(transfer.Currency)
+		if currency == config.Currency || (transfer.Status != "" && transfer.Status != "Complete" && transfer.Status != "Advanced") {
+			continue
+		}
+		amount, err := strconv.ParseFloat(transfer.Amount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("tax: transfer %d has invalid amount %q", transfer.EID, transfer.Amount)
+		}
+		kind := strings.ToLower(transfer.Type)
+		if kind != "deposit" && kind != "withdrawal" {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("transfer %d of type %s", transfer.EID, transfer.Type))
+			continue
+		}
+		events = append(events, event{time: time.UnixMilli(transfer.TimestampMs), kind: kind, currency: currency, amount: amount, transfer: transfer})
+	}
+	// at the same time, acquisitions come before disposals
+	acquisition := map[string]bool{"buy": true, "deposit": true}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].time.Equal(events[j].time) {
+			return events[i].time.Before(events[j].time)
+		}
+		return acquisition[events[i].kind] && !acquisition[events[j].kind]
+	})
+
+	lots := map[string][]*Lot{}
+	for _, e := range events {
+		switch e.kind {
+		case "buy":
+			lots[e.currency] = append(lots[e.currency], &Lot{Currency: e.currency, Amount: e.amount, Acquired: e.time, CostPerUnit: e.price + e.fee/e.amount})
+		case "deposit":
+			lot := &Lot{Currency: e.currency, Amount: e.amount, Acquired: e.time, UnknownBasis: true}
+			if config.DepositCost != nil {
+				if cost, ok := config.DepositCost(*e.transfer); ok {
+					lot.CostPerUnit, lot.UnknownBasis = cost, false
+				}
+			}
+			lots[e.currency] = append(lots[e.currency], lot)
+		case "sell", "withdrawal":
+			remaining := e.amount
+			for remaining > 1e-12 {
+				lot := pick(lots[e.currency], config.Method)
+				if lot == nil {
+					report.Skipped = append(report.Skipped, fmt.Sprintf("%s of %v %s on %s exceeds the known lots by %v",
+						e.kind, e.amount, e.currency, e.time.UTC().Format(time.DateOnly), remaining))
+					break
+				}
+				used := min(remaining, lot.Amount)
+				lot.Amount -= used
+				remaining -= used
+				if e.kind == "sell" && (config.Year == 0 || e.time.UTC().Year() == config.Year) {
+					report.Disposals = append(report.Disposals, dispose(e, lot, used))
+				}
+			}
+			lots[e.currency] = compact(lots[e.currency])
+		}
+	}
+
+	currencies := make([]string, 0, len(lots))
+	for currency := range lots {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		for _, lot := range lots[currency] {
+			report.OpenLots = append(report.OpenLots, *lot)
+		}
+	}
+	return report, nil
+}
+
+func tradeTime(trade private.PastTrade) time.Time {
+	if trade.TimestampMs != 0 {
+		return time.UnixMilli(trade.TimestampMs)
+	}
+	return time.Unix(trade.Timestamp, 0)
+}
+
+// pick selects the next lot to dispose of with the given method
+func pick(lots []*Lot, method string) *Lot {
+	var picked *Lot
+	for _, lot := range lots {
+		if lot.Amount <= 1e-12 {
+			continue
+		}
+		if method == FIFO {
+			return lot
+		}
+		if picked == nil || method == LIFO || lot.CostPerUnit > picked.CostPerUnit {
+			picked = lot
+		}
+	}
+	return picked
+}
+
+// dispose records the sale of amount of lot by a sell event
+func dispose(e event, lot *Lot, amount float64) Disposal {
+	d := Disposal{
+		Currency: e.currency, Amount: amount, Acquired: lot.Acquired, Disposed: e.time, TradeID: e.tradeID,
+		Proceeds:     amount * (e.price - e.fee/e.amount),
+		CostBasis:    amount * lot.CostPerUnit,
+		UnknownBasis: lot.UnknownBasis, Term: ShortTerm,
+	}
+	d.Gain = d.Proceeds - d.CostBasis
+	if e.time.After(lot.Acquired.AddDate(1, 0, 0)) {
+		d.Term = LongTerm
+	}
+	return d
+}
+
+func compact(lots []*Lot) []*Lot {
+	open := lots[:0]
+	for _, lot := range lots {
+		if lot.Amount > 1e-12 {
+			open = append(open, lot)
+		}
+	}
+	return open
+}
@@ -0,0 +1,157 @@
+package tax
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/austinjhunt/go-gemini/geminitest"
+	"github.com/austinjhunt/go-gemini/private"
+	"github.com/xuri/excelize/v2"
+)
+
+func trade(id int64, date string, side string, amount string, price string, fee string) private.PastTrade {
+	at, _ := time.Parse(time.DateOnly, date)
+	return private.PastTrade{TID: id, TimestampMs: at.UnixMilli(), Symbol: "BTCUSD", Type: side, Amount: amount, Price: price, FeeAmount: fee, FeeCurrency: "USD"}
+}
+
+var history = []private.PastTrade{
+	trade(4, "2023-08-01", "Sell", "1.5", "400", "0"),
+	trade(1, "2022-01-01", "Buy", "1", "100", "0"),
+	trade(2, "2023-06-01", "Buy", "1", "300", "0"),
+	trade(3, "2023-07-01", "Buy", "1", "200", "0"),
+	{TID: 5, Symbol: "ETHBTC", Type: "Buy", Amount: "1", Price: "0.06"},
+}
+
+func TestLotMethods(t *testing.T) {
+	for method, expected := range map[string][][3]float64{
+		// amount, cost basis, gain of each disposal
+		FIFO: {{1, 100, 300}, {0.5, 150, 50}},
+		LIFO: {{1, 200, 200}, {0.5, 150, 50}},
+		HIFO: {{1, 300, 100}, {0.5, 100, 100}},
+	} {
+		report, err := Build(history, nil, Config{Method: method})
+		if err != nil {
+			t.Fatalf("%s: Build failed: %v", method, err)
+		}
+		if len(report.Disposals) != len(expected) || len(report.Skipped) != 1 {
+			t.Fatalf("%s: report %+v", method, report)
+		}
+		for i, d := range report.Disposals {
+			if d.Amount != expected[i][0] || d.CostBasis != expected[i][1] || d.Gain != expected[i][2] {
+				t.Errorf("%s: disposal %d is %+v, expected %v", method, i, d, expected[i])
+			}
+		}
+		if totals := report.Totals(); totals.Proceeds != 600 || totals.ShortTermGain+totals.LongTermGain != expected[0][2]+expected[1][2] {
+			t.Errorf("%s: totals %+v", method, totals)
+		}
+	}
+	report, _ := Build(history, nil, Config{})
+	if report.Disposals[0].Term != LongTerm || report.Disposals[1].Term != ShortTerm || report.Totals().LongTermGain != 300 {
+		t.Errorf("holding periods %+v", report.Disposals)
+	}
+	if _, err := Build(history, nil, Config{Method: "lifo-ish"}); err == nil {
+		t.Errorf("an unknown method should be rejected")
+	}
+}
+
+func TestTransfersAndExport(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	now := time.Now()
+	server.SetClock(func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	})
+	// the history starts with a 0.01 BTC buy at 29000; 0.5 BTC is deposited and 0.2 BTC withdrawn
+	server.Deposit("BTC", 0.5)
+	server.Withdraw("BTC", 0.2)
+	server.SetFeeRate(0.001)
+	if _, err := client.LimitSell("btcusd", 0.1, 30000); err != nil {
+		t.Fatalf("LimitSell failed: %v", err)
+	}
+	trades, _ := client.GetPastTrades("", 0)
+	transfers, _ := client.GetTransfers(0)
+	if len(trades) != 2 || len(transfers) != 2 {
+		t.Fatalf("history %+v %+v", trades, transfers)
+	}
+
+	report, err := Build(trades, transfers, Config{DepositCost: func(transfer private.Transfer) (float64, bool) { return 20000, true }})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	// the withdrawal takes the bought lot and 0.19 of the deposit, the sale 0.1 of the rest
+	if len(report.Disposals) != 1 || report.Disposals[0].CostBasis != 2000 || math.Abs(report.Disposals[0].Gain-997) > 1e-6 {
+		t.Errorf("disposals %+v", report.Disposals)
+	}
+	if len(report.OpenLots) != 1 || math.Abs(report.OpenLots[0].Amount-0.21) > 1e-9 {
+		t.Errorf("open lots %+v", report.OpenLots)
+	}
+
+	var csv bytes.Buffer
+	if err := report.WriteCSV(&csv); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 2 || lines[0] != strings.Join(Columns, ",") || !strings.HasPrefix(lines[1], "BTC,0.1,") || !strings.Contains(lines[1], ",2997,2000,997,short,false,") {
+		t.Errorf("CSV:\n%s", csv.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "gains.xlsx")
+	if err := report.WriteXLSX(path); err != nil {
+		t.Fatalf("WriteXLSX failed: %v", err)
+	}
+	file, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	defer file.Close()
+	if gain, _ := file.GetCellValue("Disposals", "G2"); gain != "997" {
+		t.Errorf("XLSX gain %q", gain)
+	}
+	if method, _ := file.GetCellValue("Summary", "B1"); method != FIFO {
+		t.Errorf("XLSX method %q", method)
+	}
+}
+
+func TestFromClientPages(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	client, err := private.NewClient(private.StaticCredentials{APIKey: server.APIKey, APISecret: server.APISecret}, private.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	now := time.Now()
+	server.SetClock(func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	})
+	// 60 deposits are more than one page of 50 transfers
+	for i := 0; i < 60; i++ {
+		server.Deposit("BTC", 0.1)
+	}
+	transfers, err := client.GetAllTransfers(0)
+	if err != nil || len(transfers) != 60 || transfers[0].TimestampMs <= transfers[59].TimestampMs {
+		t.Fatalf("expected 60 transfers, newest first, got %d, %v", len(transfers), err)
+	}
+
+	report, err := FromClient(client, Config{})
+	if err != nil {
+		t.Fatalf("FromClient failed: %v", err)
+	}
+	total := 0.0
+	for _, lot := range report.OpenLots {
+		total += lot.Amount
+	}
+	// the seeded 0.01 BTC buy and every deposit
+	if math.Abs(total-6.01) > 1e-9 {
+		t.Errorf("open lots total %v: %+v", total, report.OpenLots)
+	}
+}
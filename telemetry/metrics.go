@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics records counters and histograms for API calls. api is "public" or "private"; endpoint is the request path.
+type Metrics interface {
+	ObserveRequest(api string, endpoint string, status int, latency time.Duration)
+	IncError(api string, endpoint string, reason string)
+	IncRetry(api string, endpoint string)
+	ObserveRateLimitWait(api string, endpoint string, wait time.Duration)
+}
+
+// NoopMetrics discards all measurements; it is the default metrics implementation
+type NoopMetrics struct{}
+
+func (NoopMetrics) ObserveRequest(api string, endpoint string, status int, latency time.Duration) {}
+func (NoopMetrics) IncError(api string, endpoint string, reason string)                           {}
+func (NoopMetrics) IncRetry(api string, endpoint string)                                          {}
+func (NoopMetrics) ObserveRateLimitWait(api string, endpoint string, wait time.Duration)          {}
+
+// DefaultBuckets are the histogram upper bounds, in seconds, used for latencies and rate limit waits
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram is a cumulative histogram of observations in seconds
+type Histogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+func newHistogram() *Histogram {
+	return &Histogram{Buckets: DefaultBuckets, Counts: make([]uint64, len(DefaultBuckets))}
+}
+
+func (h *Histogram) observe(seconds float64) {
+	for i, bound := range h.Buckets {
+		if seconds <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += seconds
+}
+
+func (h *Histogram) copy() Histogram {
+	return Histogram{Buckets: h.Buckets, Counts: append([]uint64(nil), h.Counts...), Count: h.Count, Sum: h.Sum}
+}
+
+type endpointKey struct {
+	API      string
+	Endpoint string
+}
+
+type requestKey struct {
+	endpointKey
+	Status int
+}
+
+type errorKey struct {
+	endpointKey
+	Reason string
+}
+
+// InMemoryMetrics keeps all measurements in memory and exposes them through accessor methods
+type InMemoryMetrics struct {
+	mu             sync.Mutex
+	requests       map[requestKey]uint64
+	errors         map[errorKey]uint64
+	retries        map[endpointKey]uint64
+	latencies      map[endpointKey]*Histogram
+	rateLimitWaits map[endpointKey]*Histogram
+}
+
+func NewInMemoryMetrics() *InMemoryMetrics {
+	return &InMemoryMetrics{
+		requests:       map[requestKey]uint64{},
+		errors:         map[errorKey]uint64{},
+		retries:        map[endpointKey]uint64{},
+		latencies:      map[endpointKey]*Histogram{},
+		rateLimitWaits: map[endpointKey]*Histogram{},
+	}
+}
+
+func (m *InMemoryMetrics) ObserveRequest(api string, endpoint string, status int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := endpointKey{api, endpoint}
+	m.requests[requestKey{key, status}]++
+	if m.latencies[key] == nil {
+		m.latencies[key] = newHistogram()
+	}
+	m.latencies[key].observe(latency.Seconds())
+}
+
+func (m *InMemoryMetrics) IncError(api string, endpoint string, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[errorKey{endpointKey{api, endpoint}, reason}]++
+}
+
+func (m *InMemoryMetrics) IncRetry(api string, endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[endpointKey{api, endpoint}]++
+}
+
+func (m *InMemoryMetrics) ObserveRateLimitWait(api string, endpoint string, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := endpointKey{api, endpoint}
+	if m.rateLimitWaits[key] == nil {
+		m.rateLimitWaits[key] = newHistogram()
+	}
+	m.rateLimitWaits[key].observe(wait.Seconds())
+}
+
+func (m *InMemoryMetrics) Requests(api string, endpoint string, status int) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests[requestKey{endpointKey{api, endpoint}, status}]
+}
+
+func (m *InMemoryMetrics) Errors(api string, endpoint string, reason string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.errors[errorKey{endpointKey{api, endpoint}, reason}]
+}
+
+func (m *InMemoryMetrics) Retries(api string, endpoint string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.retries[endpointKey{api, endpoint}]
+}
+
+func (m *InMemoryMetrics) Latency(api string, endpoint string) Histogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h := m.latencies[endpointKey{api, endpoint}]; h != nil {
+		return h.copy()
+	}
+	return newHistogram().copy()
+}
+
+func (m *InMemoryMetrics) RateLimitWaits(api string, endpoint string) Histogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h := m.rateLimitWaits[endpointKey{api, endpoint}]; h != nil {
+		return h.copy()
+	}
+	return newHistogram().copy()
+}
@@ -0,0 +1,124 @@
+package telemetry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusMetrics keeps measurements in memory and writes them in the Prometheus text exposition format
+type PrometheusMetrics struct {
+	*InMemoryMetrics
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{InMemoryMetrics: NewInMemoryMetrics()}
+}
+
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	/*
+		Serve the metrics for scraping, e.g. http.Handle("/metrics", metrics)
+	*/
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	p.WriteTo(w)
+}
+
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	/*
+		Write all metrics in the Prometheus text exposition format
+	*/
+	p.mu.Lock()
+	var buf bytes.Buffer
+
+	writeHeader(&buf, "gemini_api_requests_total", "counter", "API requests by api, endpoint and HTTP status.")
+	var requestLines []string
+	for key, count := range p.requests {
+		requestLines = append(requestLines, series("gemini_api_requests_total", labels(key.endpointKey, "status", strconv.Itoa(key.Status)), formatUint(count)))
+	}
+	writeSorted(&buf, requestLines)
+
+	writeHeader(&buf, "gemini_api_errors_total", "counter", "API errors by api, endpoint and Gemini error reason.")
+	var errorLines []string
+	for key, count := range p.errors {
+		errorLines = append(errorLines, series("gemini_api_errors_total", labels(key.endpointKey, "reason", key.Reason), formatUint(count)))
+	}
+	writeSorted(&buf, errorLines)
+
+	writeHeader(&buf, "gemini_api_retries_total", "counter", "API requests retried after being rate limited.")
+	var retryLines []string
+	for key, count := range p.retries {
+		retryLines = append(retryLines, series("gemini_api_retries_total", labels(key), formatUint(count)))
+	}
+	writeSorted(&buf, retryLines)
+
+	writeHistograms(&buf, "gemini_api_request_duration_seconds", "API request latency.", p.latencies)
+	writeHistograms(&buf, "gemini_api_rate_limit_wait_seconds", "Time spent waiting after being rate limited.", p.rateLimitWaits)
+	p.mu.Unlock()
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func writeHeader(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeSorted(w io.Writer, lines []string) {
+	sort.Strings(lines)
+	for _, line := range lines {
+		io.WriteString(w, line)
+	}
+}
+
+func writeHistograms(w io.Writer, name string, help string, histograms map[endpointKey]*Histogram) {
+	writeHeader(w, name, "histogram", help)
+	keys := make([]endpointKey, 0, len(histograms))
+	for key := range histograms {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].API != keys[j].API {
+			return keys[i].API < keys[j].API
+		}
+		return keys[i].Endpoint < keys[j].Endpoint
+	})
+	for _, key := range keys {
+		h := histograms[key]
+		for i, bound := range h.Buckets {
+			io.WriteString(w, series(name+"_bucket", labels(key, "le", formatFloat(bound)), formatUint(h.Counts[i])))
+		}
+		io.WriteString(w, series(name+"_bucket", labels(key, "le", "+Inf"), formatUint(h.Count)))
+		io.WriteString(w, series(name+"_sum", labels(key), formatFloat(h.Sum)))
+		io.WriteString(w, series(name+"_count", labels(key), formatUint(h.Count)))
+	}
+}
+
+func labels(key endpointKey, extra ...string) string {
+	pairs := []string{"api", key.API, "endpoint", key.Endpoint}
+	pairs = append(pairs, extra...)
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+escapeLabel(pairs[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func series(name string, labels string, value string) string {
+	return name + labels + " " + value + "\n"
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatUint(value uint64) string {
+	return strconv.FormatUint(value, 10)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
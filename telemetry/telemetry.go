@@ -0,0 +1,35 @@
+package telemetry
+
+import "sync"
+
+// process-wide metrics and tracer used by the public endpoint functions and by private clients without their own
+
+var (
+	defaultMetrics Metrics = NoopMetrics{}
+	defaultTracer  Tracer  = NoopTracer{}
+	defaultsLock   sync.RWMutex
+)
+
+func DefaultMetrics() Metrics {
+	defaultsLock.RLock()
+	defer defaultsLock.RUnlock()
+	return defaultMetrics
+}
+
+func SetMetrics(metrics Metrics) {
+	defaultsLock.Lock()
+	defer defaultsLock.Unlock()
+	defaultMetrics = metrics
+}
+
+func DefaultTracer() Tracer {
+	defaultsLock.RLock()
+	defer defaultsLock.RUnlock()
+	return defaultTracer
+}
+
+func SetTracer(tracer Tracer) {
+	defaultsLock.Lock()
+	defer defaultsLock.Unlock()
+	defaultTracer = tracer
+}
@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInMemoryMetrics(t *testing.T) {
+	metrics := NewInMemoryMetrics()
+	metrics.ObserveRequest("private", "/v1/order/new", 200, 30*time.Millisecond)
+	metrics.ObserveRequest("private", "/v1/order/new", 200, 2*time.Second)
+	metrics.ObserveRequest("private", "/v1/order/new", 400, 10*time.Millisecond)
+	metrics.IncError("private", "/v1/order/new", "InsufficientFunds")
+	metrics.IncRetry("public", "/v1/symbols")
+	metrics.ObserveRateLimitWait("public", "/v1/symbols", time.Second)
+
+	if got := metrics.Requests("private", "/v1/order/new", 200); got != 2 {
+		t.Errorf("Requests = %d, expected 2", got)
+	}
+	if got := metrics.Errors("private", "/v1/order/new", "InsufficientFunds"); got != 1 {
+		t.Errorf("Errors = %d, expected 1", got)
+	}
+	if got := metrics.Retries("public", "/v1/symbols"); got != 1 {
+		t.Errorf("Retries = %d, expected 1", got)
+	}
+	latency := metrics.Latency("private", "/v1/order/new")
+	if latency.Count != 3 || latency.Sum < 2.039 || latency.Sum > 2.041 {
+		t.Errorf("Latency = %+v", latency)
+	}
+	// the 30ms observation falls in the .05 bucket and above, the 2s one in the 2.5 bucket and above
+	if latency.Counts[3] != 2 || latency.Counts[len(latency.Counts)-1] != 3 {
+		t.Errorf("Latency buckets = %v", latency.Counts)
+	}
+	if waits := metrics.RateLimitWaits("public", "/v1/symbols"); waits.Count != 1 || waits.Sum != 1 {
+		t.Errorf("RateLimitWaits = %+v", waits)
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	metrics.ObserveRequest("public", "/v1/symbols", 200, 20*time.Millisecond)
+	metrics.IncError("private", "/v1/order/new", `Invalid"Reason`)
+
+	var buf bytes.Buffer
+	if _, err := metrics.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	output := buf.String()
+	expected := []string{
+		"# TYPE gemini_api_requests_total counter",
+		`gemini_api_requests_total{api="public",endpoint="/v1/symbols",status="200"} 1`,
+		`gemini_api_errors_total{api="private",endpoint="/v1/order/new",reason="Invalid\"Reason"} 1`,
+		"# TYPE gemini_api_request_duration_seconds histogram",
+		`gemini_api_request_duration_seconds_bucket{api="public",endpoint="/v1/symbols",le="0.025"} 1`,
+		`gemini_api_request_duration_seconds_bucket{api="public",endpoint="/v1/symbols",le="+Inf"} 1`,
+		`gemini_api_request_duration_seconds_count{api="public",endpoint="/v1/symbols"} 1`,
+		"# TYPE gemini_api_rate_limit_wait_seconds histogram",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("exposition is missing %q:\n%s", line, output)
+		}
+	}
+}
+
+func TestInMemoryTracer(t *testing.T) {
+	tracer := NewInMemoryTracer()
+	_, span := tracer.Start(context.Background(), "POST /v1/order/new")
+	span.SetAttributes(String("endpoint", "/v1/order/new"), Int("status", 400))
+	span.RecordError(errors.New("rejected"))
+	span.End()
+
+	spans := tracer.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "POST /v1/order/new" || spans[0].Attributes["status"] != 400 || len(spans[0].Errors) != 1 {
+		t.Errorf("recorded span = %+v", spans[0])
+	}
+}
@@ -0,0 +1,98 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracer starts spans around API calls. It mirrors the shape of an OpenTelemetry tracer so that an adapter
+// over go.opentelemetry.io/otel/trace only needs to translate Attribute values.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced API call
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key/value pair recorded on a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// NoopTracer discards all spans; it is the default tracer
+type NoopTracer struct{}
+
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Attribute) {}
+func (noopSpan) RecordError(err error)            {}
+func (noopSpan) End()                             {}
+
+// InMemoryTracer keeps every ended span, for tests and debugging
+type InMemoryTracer struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// RecordedSpan is a span ended on an InMemoryTracer
+type RecordedSpan struct {
+	Name       string
+	Attributes map[string]interface{}
+	Errors     []error
+	Start      time.Time
+	End        time.Time
+}
+
+func NewInMemoryTracer() *InMemoryTracer {
+	return &InMemoryTracer{}
+}
+
+func (t *InMemoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, &inMemorySpan{tracer: t, span: RecordedSpan{Name: name, Attributes: map[string]interface{}{}, Start: time.Now()}}
+}
+
+func (t *InMemoryTracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]RecordedSpan(nil), t.spans...)
+}
+
+type inMemorySpan struct {
+	tracer *InMemoryTracer
+	span   RecordedSpan
+}
+
+func (s *inMemorySpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.span.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *inMemorySpan) RecordError(err error) {
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+func (s *inMemorySpan) End() {
+	s.span.End = time.Now()
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s.span)
+}
@@ -0,0 +1,39 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	for _, data := range []string{`{"a":1}`, `{"a":2}`} {
+		if err := WriteFileAtomic(path, []byte(data), 0o600); err != nil {
+			t.Fatalf("WriteFileAtomic failed: %v", err)
+		}
+		if read, err := os.ReadFile(path); err != nil || string(read) != data {
+			t.Errorf("read %q, %v; expected %q", read, err, data)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "state.json"), nil, 0o600); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestFloorTo(t *testing.T) {
+	for _, c := range []struct{ value, increment, want float64 }{
+		{0.123456789, 1e-8, 0.12345678},
+		{0.99, 0.01, 0.99},
+		{100.57, 0.5, 100.5},
+		{1.5, 0, 1.5},
+	} {
+		if got := FloorTo(c.value, c.increment); got != c.want {
+			t.Errorf("FloorTo(%v, %v) = %v, expected %v", c.value, c.increment, got, c.want)
+		}
+	}
+}
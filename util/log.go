@@ -0,0 +1,114 @@
+package util
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	logger     *slog.Logger
+	loggerLock sync.Mutex
+)
+
+func Logger() *slog.Logger {
+	/*
+		Get the process-wide logger used by the public endpoint functions and by clients without their own logger.
+
+		Unless replaced with SetLogger, it is created on first use and writes text records to stderr at the level named
+		by the LOGLEVEL environment variable (debug, info, warn or error; default info), with sensitive attributes redacted.
+	*/
+	loggerLock.Lock()
+	defer loggerLock.Unlock()
+	if logger == nil {
+		logger = NewLogger(os.Stderr, GetEnvOrDefault("LOGLEVEL", "INFO"))
+	}
+	return logger
+}
+
+func SetLogger(l *slog.Logger) {
+	/*
+		Replace the process-wide logger
+	*/
+	loggerLock.Lock()
+	defer loggerLock.Unlock()
+	logger = l
+}
+
+func NewLogger(w io.Writer, level string) *slog.Logger {
+	/*
+		Create a text logger writing to w at the named level, with attributes in DefaultSensitiveFields redacted
+	*/
+	handler := slog.NewTextHandler(w, &slog.HandlerOptions{Level: ParseLevel(level)})
+	return slog.New(NewRedactingHandler(handler, NewRedactor()))
+}
+
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "err", "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// RedactingHandler is a slog.Handler that masks the values of sensitive attributes before passing records on
+type RedactingHandler struct {
+	handler  slog.Handler
+	redactor *Redactor
+}
+
+func NewRedactingHandler(handler slog.Handler, redactor *Redactor) *RedactingHandler {
+	return &RedactingHandler{handler: handler, redactor: redactor}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.RedactString(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+	return &RedactingHandler{handler: h.handler.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{handler: h.handler.WithGroup(name), redactor: h.redactor}
+}
+
+func (h *RedactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	if h.redactor.IsSensitive(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, member := range group {
+			redacted[i] = h.redactAttr(member)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindString:
+		return slog.String(attr.Key, h.redactor.RedactString(value.String()))
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, "warn")
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Errorf("warn level logger wrote %q", buf.String())
+	}
+}
+
+func TestRedactingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	redactor := NewRedactor().WithSecrets("account-key-123")
+	logger := slog.New(NewRedactingHandler(slog.NewTextHandler(&buf, nil), redactor))
+	logger.With("signature", "deadbeef").Info("private request",
+		"endpoint", "/v1/balances",
+		"note", "sent with account-key-123",
+		slog.Group("headers", "X-GEMINI-APIKEY", "account-key-123"),
+	)
+	output := buf.String()
+	for _, leaked := range []string{"deadbeef", "account-key-123"} {
+		if strings.Contains(output, leaked) {
+			t.Errorf("log output leaked %q: %s", leaked, output)
+		}
+	}
+	if !strings.Contains(output, "endpoint=/v1/balances") {
+		t.Errorf("log output lost a non-sensitive attribute: %s", output)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]slog.Level{
+		"DEBUG": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+		"":      slog.LevelInfo,
+	}
+	for level, expected := range cases {
+		if ParseLevel(level) != expected {
+			t.Errorf("ParseLevel(%q) = %v, expected %v", level, ParseLevel(level), expected)
+		}
+	}
+}
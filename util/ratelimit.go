@@ -0,0 +1,39 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxRateLimitRetries is how many times a request rejected with HTTP 429 is retried before giving up
+var MaxRateLimitRetries = 3
+
+// Sleep waits between rate limited retries; replaceable in tests
+var Sleep = time.Sleep
+
+func RateLimitWait(res *http.Response, attempt int) time.Duration {
+	/*
+		Get how long to wait before retrying a rate limited request: the Retry-After header if present, otherwise one second per attempt
+	*/
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(attempt) * time.Second
+}
+
+func APIErrorReason(status int, body []byte) string {
+	/*
+		Get the reason of a Gemini error response ({"result":"error","reason":"...","message":"..."}).
+		Falls back to the HTTP status text when the body is not a Gemini error.
+	*/
+	var apiError struct {
+		Result string `json:"result"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(body, &apiError); err == nil && apiError.Reason != "" {
+		return apiError.Reason
+	}
+	return http.StatusText(status)
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const Redacted = "[REDACTED]"
+
+// DefaultSensitiveFields are the JSON keys and HTTP headers redacted by NewRedactor in addition to the ones it is given
+var DefaultSensitiveFields = []string{
+	"apikey",
+	"api_key",
+	"api_secret",
+	"secret",
+	"signature",
+	"x-gemini-apikey",
+	"x-gemini-signature",
+	"x-gemini-payload",
+	"authorization",
+}
+
+// Redactor masks sensitive values in JSON documents, HTTP headers and free text before they are logged
+type Redactor struct {
+	fields  map[string]bool
+	secrets []string
+}
+
+func NewRedactor(fields ...string) *Redactor {
+	/*
+		Create a redactor for DefaultSensitiveFields plus the given fields. Field names are matched case-insensitively.
+	*/
+	r := &Redactor{fields: map[string]bool{}}
+	for _, field := range append(append([]string{}, DefaultSensitiveFields...), fields...) {
+		r.fields[strings.ToLower(field)] = true
+	}
+	return r
+}
+
+func (r *Redactor) WithSecrets(secrets ...string) *Redactor {
+	/*
+		Return a copy of the redactor that also masks every literal occurrence of the given secret values (e.g. an API key) in text
+	*/
+	copied := &Redactor{fields: r.fields}
+	for _, secret := range append(append([]string{}, r.secrets...), secrets...) {
+		if secret != "" {
+			copied.secrets = append(copied.secrets, secret)
+		}
+	}
+	return copied
+}
+
+func (r *Redactor) IsSensitive(field string) bool {
+	return r.fields[strings.ToLower(field)]
+}
+
+func (r *Redactor) RedactJSON(data []byte) string {
+	/*
+		Mask the values of sensitive keys at any depth of a JSON document. Text that is not valid JSON is passed to RedactString.
+	*/
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return r.RedactString(string(data))
+	}
+	redacted, err := json.Marshal(r.redactValue(document))
+	if err != nil {
+		return Redacted
+	}
+	return r.RedactString(string(redacted))
+}
+
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if r.IsSensitive(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = r.redactValue(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redactValue(item)
+		}
+	}
+	return value
+}
+
+func (r *Redactor) RedactHeader(header http.Header) http.Header {
+	/*
+		Return a copy of the header with the values of sensitive headers masked
+	*/
+	redacted := header.Clone()
+	for key := range redacted {
+		if r.IsSensitive(key) {
+			redacted[key] = []string{Redacted}
+		}
+	}
+	return redacted
+}
+
+func (r *Redactor) RedactString(text string) string {
+	/*
+		Mask every literal occurrence of the redactor's secret values in text
+	*/
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, Redacted)
+	}
+	return text
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactJSON(t *testing.T) {
+	redactor := NewRedactor("address")
+	document := `{"request":"/v1/withdraw/btc","address":"bc1qxyz","nested":[{"Signature":"abc","amount":"1"}]}`
+	redacted := redactor.RedactJSON([]byte(document))
+	for _, leaked := range []string{"bc1qxyz", "abc"} {
+		if strings.Contains(redacted, leaked) {
+			t.Errorf("RedactJSON leaked %q: %s", leaked, redacted)
+		}
+	}
+	for _, kept := range []string{"/v1/withdraw/btc", `"amount":"1"`} {
+		if !strings.Contains(redacted, kept) {
+			t.Errorf("RedactJSON removed %q: %s", kept, redacted)
+		}
+	}
+}
+
+func TestRedactString(t *testing.T) {
+	redactor := NewRedactor().WithSecrets("account-key-123", "")
+	redacted := redactor.RedactJSON([]byte("not json: account-key-123"))
+	if redacted != "not json: "+Redacted {
+		t.Errorf("RedactJSON on text returned %q", redacted)
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-GEMINI-APIKEY", "account-key-123")
+	header.Set("X-GEMINI-SIGNATURE", "deadbeef")
+	header.Set("Content-Type", "text/plain")
+	redacted := NewRedactor().RedactHeader(header)
+	if redacted.Get("X-GEMINI-APIKEY") != Redacted || redacted.Get("X-GEMINI-SIGNATURE") != Redacted {
+		t.Errorf("RedactHeader did not mask credentials: %v", redacted)
+	}
+	if redacted.Get("Content-Type") != "text/plain" {
+		t.Errorf("RedactHeader masked a non-sensitive header: %v", redacted)
+	}
+	if header.Get("X-GEMINI-APIKEY") != "account-key-123" {
+		t.Errorf("RedactHeader modified the original header")
+	}
+}
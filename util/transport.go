@@ -0,0 +1,188 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Middleware wraps the RoundTripper that sends every public and private API request
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to the http.RoundTripper interface
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var (
+	httpClient     *http.Client
+	httpClientLock sync.Mutex
+)
+
+func HTTPClient() *http.Client {
+	/*
+		Get the process-wide HTTP client used by the public endpoint functions and by private clients without their own.
+		Unless replaced with SetHTTPClient, it is a plain http.Client using http.DefaultTransport.
+	*/
+	httpClientLock.Lock()
+	defer httpClientLock.Unlock()
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return httpClient
+}
+
+func SetHTTPClient(client *http.Client) {
+	/*
+		Replace the process-wide HTTP client, e.g. to use a proxy or custom TLS configuration
+	*/
+	httpClientLock.Lock()
+	defer httpClientLock.Unlock()
+	httpClient = client
+}
+
+func Use(middleware ...Middleware) {
+	/*
+		Add middleware to the process-wide HTTP client. The first middleware given is the outermost.
+	*/
+	client := HTTPClient()
+	wrapped := *client
+	wrapped.Transport = Chain(client.Transport, middleware...)
+	SetHTTPClient(&wrapped)
+}
+
+func Chain(base http.RoundTripper, middleware ...Middleware) http.RoundTripper {
+	/*
+		Wrap base (http.DefaultTransport if nil) in middleware, the first middleware given being the outermost
+	*/
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		base = middleware[i](base)
+	}
+	return base
+}
+
+func BeforeRequest(hook func(*http.Request)) Middleware {
+	/*
+		Call hook with every request before it is sent, e.g. to add tracing headers
+	*/
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			hook(req)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func AfterResponse(hook func(*http.Request, *http.Response, error)) Middleware {
+	/*
+		Call hook with every request and its response or error after it is sent
+	*/
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			res, err := next.RoundTrip(req)
+			hook(req, res, err)
+			return res, err
+		})
+	}
+}
+
+func TimingMiddleware(logger *slog.Logger) Middleware {
+	/*
+		Log the method, path, status and latency of every request; uses Logger() if logger is nil
+	*/
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			l := logger
+			if l == nil {
+				l = Logger()
+			}
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			attrs := []any{"method", req.Method, "endpoint", req.URL.Path, "latency", time.Since(start)}
+			if err != nil {
+				l.Error("http request failed", append(attrs, "error", err)...)
+			} else {
+				l.Info("http request", append(attrs, "status", res.StatusCode)...)
+			}
+			return res, err
+		})
+	}
+}
+
+const RequestIDHeader = "X-Request-ID"
+
+func RequestIDMiddleware() Middleware {
+	/*
+		Set the X-Request-ID header of every request that does not already have one to a new UUID
+	*/
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(RequestIDHeader) == "" {
+				req = req.Clone(req.Context())
+				req.Header.Set(RequestIDHeader, GenerateUUID())
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func DumpMiddleware(w io.Writer, redactor *Redactor) Middleware {
+	/*
+		Write every request and response, with headers and bodies redacted, to w; uses NewRedactor() if redactor is nil
+	*/
+	if redactor == nil {
+		redactor = NewRedactor()
+	}
+	var lock sync.Mutex
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var dump bytes.Buffer
+			fmt.Fprintf(&dump, "> %s %s\n", req.Method, req.URL)
+			writeDumpHeader(&dump, ">", redactor.RedactHeader(req.Header))
+
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				fmt.Fprintf(&dump, "< error: %s\n", redactor.RedactString(err.Error()))
+			} else {
+				body, readErr := io.ReadAll(res.Body)
+				res.Body.Close()
+				res.Body = io.NopCloser(bytes.NewReader(body))
+				fmt.Fprintf(&dump, "< %s\n", res.Status)
+				writeDumpHeader(&dump, "<", redactor.RedactHeader(res.Header))
+				if readErr != nil {
+					fmt.Fprintf(&dump, "< error reading body: %s\n", readErr)
+				} else if len(body) > 0 {
+					fmt.Fprintf(&dump, "%s\n", redactor.RedactJSON(body))
+				}
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			w.Write(dump.Bytes())
+			return res, err
+		})
+	}
+}
+
+func writeDumpHeader(w io.Writer, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, key, value)
+		}
+	}
+}
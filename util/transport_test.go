@@ -0,0 +1,115 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newEchoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"request_id":"` + r.Header.Get(RequestIDHeader) + `","signature":"server-secret"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestChainOrder(t *testing.T) {
+	server := newEchoServer(t)
+	var order []string
+	tag := func(name string) Middleware {
+		return BeforeRequest(func(*http.Request) { order = append(order, name) })
+	}
+	client := &http.Client{Transport: Chain(nil, tag("outer"), tag("inner"))}
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	res.Body.Close()
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("middleware ran in order %v", order)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	server := newEchoServer(t)
+	var seen string
+	client := &http.Client{Transport: Chain(nil,
+		RequestIDMiddleware(),
+		AfterResponse(func(req *http.Request, res *http.Response, err error) { seen = req.Header.Get(RequestIDHeader) }),
+	)}
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if seen == "" || !strings.Contains(string(body), seen) {
+		t.Errorf("request id %q not sent: %s", seen, body)
+	}
+}
+
+func TestDumpMiddleware(t *testing.T) {
+	server := newEchoServer(t)
+	var dump bytes.Buffer
+	client := &http.Client{Transport: Chain(nil, DumpMiddleware(&dump, nil))}
+	req, _ := http.NewRequest("POST", server.URL+"/v1/balances", nil)
+	req.Header.Set("X-GEMINI-APIKEY", "account-key-123")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(body), "server-secret") {
+		t.Errorf("DumpMiddleware consumed the response body: %s", body)
+	}
+	output := dump.String()
+	if !strings.Contains(output, "> POST "+server.URL+"/v1/balances") || !strings.Contains(output, "< 200 OK") {
+		t.Errorf("dump is missing the request or response line: %s", output)
+	}
+	for _, leaked := range []string{"account-key-123", "server-secret"} {
+		if strings.Contains(output, leaked) {
+			t.Errorf("dump leaked %q: %s", leaked, output)
+		}
+	}
+}
+
+func TestTimingMiddleware(t *testing.T) {
+	server := newEchoServer(t)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	client := &http.Client{Transport: Chain(nil, TimingMiddleware(logger))}
+	res, err := client.Get(server.URL + "/v1/symbols")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	res.Body.Close()
+	for _, attr := range []string{"endpoint=/v1/symbols", "status=200", "latency="} {
+		if !strings.Contains(buf.String(), attr) {
+			t.Errorf("timing log is missing %s: %s", attr, buf.String())
+		}
+	}
+}
+
+func TestUse(t *testing.T) {
+	previous := HTTPClient()
+	defer SetHTTPClient(previous)
+
+	server := newEchoServer(t)
+	called := false
+	Use(BeforeRequest(func(*http.Request) { called = true }))
+	res, err := HTTPClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	res.Body.Close()
+	if !called {
+		t.Errorf("middleware added with Use was not called")
+	}
+}
@@ -1,13 +1,11 @@
 package util
 
 import (
-
-	// This package provides sha384.New
-
-	"log"
+	"math"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,42 +16,36 @@ func GenerateUUID() string {
 	return id.String()
 }
 
-func GenerateNonceString() string {
-	return strconv.FormatInt(time.Now().UTC().Unix(), 10)
-}
+var (
+	lastNonce int64
+	nonceLock sync.Mutex
+)
 
-// Function to check if a slice contains a string
-func contains(slice []string, target string) bool {
-	for _, item := range slice {
-		if item == target {
-			return true
-		}
+// GenerateNonceString returns the current time in milliseconds, bumped if needed so that every nonce is strictly greater than the last
+func GenerateNonceString() string {
+	nonceLock.Lock()
+	defer nonceLock.Unlock()
+	nonce := time.Now().UTC().UnixMilli()
+	if nonce <= lastNonce {
+		nonce = lastNonce + 1
 	}
-	return false
+	lastNonce = nonce
+	return strconv.FormatInt(nonce, 10)
 }
 
-func Debug(msg string) {
-	LOGLEVEL := strings.ToLower(GetEnvOrDefault("LOGLEVEL", "INFO"))
-	if LOGLEVEL == "debug" {
-		log.Printf("[DEBUG] %s", msg)
+// FloorTo rounds value down to a multiple of increment, e.g. a tick size or quote increment; an increment of 0 or
+// less leaves value unchanged
+func FloorTo(value float64, increment float64) float64 {
+	if increment <= 0 {
+		return value
 	}
-}
-func Info(msg string) {
-	LOGLEVEL := strings.ToLower(GetEnvOrDefault("LOGLEVEL", "INFO"))
-	levels := []string{"debug", "info"}
-	if contains(levels, LOGLEVEL) {
-		log.Printf("[INFO] %s", msg)
+	// the epsilon keeps exact multiples from being floored a whole increment down by representation error
+	steps := math.Floor(value/increment + 1e-9)
+	// dividing by the inverse of decimal increments avoids results like 0.9900990000000001
+	if inverse := math.Round(1 / increment); math.Abs(inverse-1/increment) < 1e-9 {
+		return steps / inverse
 	}
-}
-func Warn(msg string) {
-	LOGLEVEL := strings.ToLower(GetEnvOrDefault("LOGLEVEL", "INFO"))
-	levels := []string{"debug", "info", "warn"}
-	if contains(levels, LOGLEVEL) {
-		log.Printf("[INFO] %s", msg)
-	}
-}
-func Err(msg string) {
-	log.Printf("[INFO] %s", msg)
+	return steps * increment
 }
 
 func StringContainsSubstring(str string, substr string) bool {
@@ -77,7 +69,11 @@ func GetEnvOrDefault(key, defaultValue string) string {
 	return value
 }
 
+// GetBaseAPIUrl returns GEMINI_EXCHANGE_API_BASE_URL if set (e.g. a geminitest server), otherwise the URL of GEMINI_EXCHANGE_API_ENVIRONMENT
 func GetBaseAPIUrl() string {
+	if baseUrl := os.Getenv("GEMINI_EXCHANGE_API_BASE_URL"); baseUrl != "" {
+		return strings.TrimSuffix(baseUrl, "/")
+	}
 	apiEnvironment := GetEnvOrDefault("GEMINI_EXCHANGE_API_ENVIRONMENT", "production")
 	envUrls := map[string]string{
 		"sandbox":    "https://api.sandbox.gemini.com",